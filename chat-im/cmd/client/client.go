@@ -2,6 +2,7 @@ package main
 
 import (
 	"chat-im/req_rsp"
+	"hutool/logx"
 	"server/pkg/codec"
 	"server/pkg/net/tcp"
 	"time"
@@ -13,6 +14,9 @@ type Client struct {
 
 func (cl *Client) Init() {
 	cl.rawClient = tcp.NewClient(codec.JsonSerializer{}, 3*time.Second)
+	cl.rawClient.OnKick(func(reason codec.KickReason, msg string) {
+		logx.Warnf("kicked reason %d %s", reason, msg)
+	})
 	_ = cl.rawClient.Dial("127.0.0.1", 8080)
 }
 
@@ -20,6 +24,8 @@ func (cl *Client) SendLoginReq(uid uint32) {
 	tcp.Ask[*req_rsp.LoginReq, *req_rsp.LoginRsp](cl.rawClient, 0, req_rsp.Login, &req_rsp.LoginReq{
 		Uid: uid,
 	}, func(rsp *req_rsp.LoginRsp) {
-
+		if rsp.Code != req_rsp.LoginOk {
+			logx.Warnf("login %d failed code %d %s", uid, rsp.Code, rsp.Msg)
+		}
 	})
 }
@@ -1,7 +1,18 @@
 package req_rsp
 
+type LoginCode int32
+
+const (
+	LoginOk LoginCode = iota
+	// LoginDuplicate 已在其他连接登录且服务拒绝新登录
+	LoginDuplicate
+	LoginFailed
+)
+
 type LoginReq struct {
 	Uid uint32 `json:"uid"`
 }
 type LoginRsp struct {
+	Code LoginCode `json:"code"`
+	Msg  string    `json:"msg,omitempty"`
 }
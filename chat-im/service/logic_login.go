@@ -2,13 +2,22 @@ package service
 
 import (
 	"chat-im/req_rsp"
+	"errors"
+	"hutool/logx"
 	"server/pkg/codec"
+	session2 "server/pkg/session"
 )
 
 func (s *Service) LoginReq(ctx codec.ReqCtx, req *req_rsp.LoginReq) *req_rsp.LoginRsp {
-	uid := req.Uid
-	s.uidToSessionId.Store(uid, ctx.GetSession().GetConnId())
-	ctx.GetSession().Set("uid", uid)
+	err := s.NetService.BindUser(ctx.GetSession(), req.Uid)
+	if errors.Is(err, session2.DuplicateLoginErr) {
+		logx.Infof("reject duplicate login %d", req.Uid)
+		return &req_rsp.LoginRsp{Code: req_rsp.LoginDuplicate, Msg: err.Error()}
+	}
+	if err != nil {
+		logx.Warnf("bind user %d err %+v", req.Uid, err)
+		return &req_rsp.LoginRsp{Code: req_rsp.LoginFailed, Msg: err.Error()}
+	}
 	return &req_rsp.LoginRsp{}
 }
 
@@ -0,0 +1,8 @@
+package service
+
+import (
+	session2 "server/pkg/session"
+)
+
+// UidKey 登录后绑定的用户身份
+var UidKey = session2.NewKey[uint32]("uid")
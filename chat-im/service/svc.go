@@ -1,7 +1,6 @@
 package service
 
 import (
-	"hutool/container"
 	"hutool/logx"
 	"hutool/reflectx"
 	router2 "server/pkg/router"
@@ -12,15 +11,12 @@ import (
 type Service struct {
 	NetService *service.Service
 	Registry   *router2.Registry
-
-	uidToSessionId container.IMap[uint32, uint32]
 }
 
 func NewService() *Service {
 	svc := &Service{
-		NetService:     nil,
-		Registry:       router2.NewRouter(),
-		uidToSessionId: container.NewSyncMap[uint32, uint32](),
+		NetService: nil,
+		Registry:   router2.NewRouter(),
 	}
 
 	svc.InitRouter()
@@ -33,10 +29,6 @@ func (s *Service) PostReadRequest(session *session2.Session, req any) {
 	logx.Debugf("conn %d req type %+v req body %+v", connId, rType, req)
 }
 
-func (s *Service) OnSessionEnd(session *session2.Session) {
-	uid, ok := session.Get("uid")
-	if !ok {
-		return
-	}
-	s.uidToSessionId.Delete(uid.(uint32))
+func (s *Service) UserSessions(uid uint32) []*session2.Session {
+	return s.NetService.SessionManager().FindByIdentity(uid)
 }
@@ -3,7 +3,9 @@ package actor
 import (
 	"context"
 	"hutool/chanx"
+	"hutool/logx"
 	"hutool/taskx"
+	"hutool/timewheel"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -21,6 +23,7 @@ type Runner[T IActor] struct {
 	dt         time.Duration
 	closed     atomic.Bool
 	canDropMsg bool
+	wheel      *timewheel.TimingWheel
 	ctx        context.Context
 	cancel     context.CancelFunc
 	wg         sync.WaitGroup
@@ -39,11 +42,16 @@ func NewRunner[T IActor](actor T, opts ...Option) *Runner[T] {
 		dt:         cfg.dt,
 		closed:     atomic.Bool{},
 		canDropMsg: cfg.canDropMsg,
+		wheel:      cfg.wheel,
 		ctx:        ctx,
 		cancel:     cancel,
 		wg:         sync.WaitGroup{},
 	}
+	if r.wheel == nil {
+		r.wheel = sharedWheel()
+	}
 	actor.Start()
+	r.wg.Add(1)
 	go r.work()
 	return r
 }
@@ -56,6 +64,21 @@ func (r *Runner[T]) Stop() {
 	}
 }
 
+// sharedWheel 未指定时间轮的actor共用
+var sharedWheel = sync.OnceValue(func() *timewheel.TimingWheel {
+	return timewheel.NewTimingWheel()
+})
+
+// AfterFunc 定时回调投递到actor协程执行
+func (r *Runner[T]) AfterFunc(d time.Duration, fn func(T)) *timewheel.Timer {
+	return r.wheel.AfterFunc(d, func() {
+		err := r.tell(fn)
+		if err != nil {
+			logx.Errorf("actor timer tell err %+v", err)
+		}
+	})
+}
+
 func (r *Runner[T]) tell(msg func(T)) error {
 	if r.closed.Load() {
 		return ErrMsgActorClosed
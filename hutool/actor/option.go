@@ -1,11 +1,15 @@
 package actor
 
-import "time"
+import (
+	"hutool/timewheel"
+	"time"
+)
 
 type Config struct {
 	dt         time.Duration
 	canDropMsg bool
 	msgChanLen int
+	wheel      *timewheel.TimingWheel
 }
 
 type Option func(*Config)
@@ -28,6 +32,13 @@ func WithMsgChanLen(l int) Option {
 	}
 }
 
+// WithTimingWheel 驱动AfterFunc的时间轮，默认共用一个
+func WithTimingWheel(wheel *timewheel.TimingWheel) Option {
+	return func(c *Config) {
+		c.wheel = wheel
+	}
+}
+
 func DefaultConfig() *Config {
 	return &Config{
 		dt:         time.Second,
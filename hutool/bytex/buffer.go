@@ -0,0 +1,86 @@
+package bytex
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Buffer 引用计数的池化缓冲，跨协程持有前Retain，用完Release，最后一次Release时归还
+type Buffer struct {
+	b      []byte
+	refs   atomic.Int32
+	pooled bool
+}
+
+var bufferPool = sync.Pool{
+	New: func() any {
+		return &Buffer{}
+	},
+}
+
+// bufferPools 按容量分级缓存Buffer，连同切片一起复用，避免切片放回池时的装箱分配
+var bufferPools [MaxIndex + 1]sync.Pool
+
+func AllocateBuffer(c int) *Buffer {
+	idx := index(c)
+	if idx > MaxIndex {
+		return WrapBuffer(make([]byte, c))
+	}
+	buf, ok := bufferPools[idx].Get().(*Buffer)
+	if !ok {
+		buf = &Buffer{b: make([]byte, 1<<idx)}
+	}
+	buf.b = buf.b[:c]
+	buf.pooled = true
+	buf.refs.Store(1)
+	return buf
+}
+
+// WrapBuffer 包装不属于池的切片，Release时不会回收切片
+func WrapBuffer(bytes []byte) *Buffer {
+	buf := bufferPool.Get().(*Buffer)
+	buf.b = bytes
+	buf.pooled = false
+	buf.refs.Store(1)
+	return buf
+}
+
+func (b *Buffer) Bytes() []byte {
+	return b.b
+}
+
+// SetBytes 追加写入后更新内容，扩容后的切片不会回到池中
+func (b *Buffer) SetBytes(bytes []byte) {
+	b.b = bytes
+}
+
+func (b *Buffer) Len() int {
+	return len(b.b)
+}
+
+func (b *Buffer) Retain() *Buffer {
+	if b.refs.Add(1) <= 1 {
+		panic("bytex: retain released buffer")
+	}
+	return b
+}
+
+func (b *Buffer) Release() {
+	refs := b.refs.Add(-1)
+	if refs > 0 {
+		return
+	}
+	if refs < 0 {
+		panic("bytex: buffer released too many times")
+	}
+	if b.pooled {
+		// 扩容过的切片容量不是2的幂，不再复用
+		idx := index(cap(b.b))
+		if idx <= MaxIndex && cap(b.b) == 1<<idx {
+			bufferPools[idx].Put(b)
+			return
+		}
+	}
+	b.b = nil
+	bufferPool.Put(b)
+}
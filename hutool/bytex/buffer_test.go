@@ -0,0 +1,35 @@
+package bytex
+
+import "testing"
+
+func TestBufferRetainRelease(t *testing.T) {
+	buf := AllocateBuffer(100)
+	if buf.Len() != 100 || cap(buf.Bytes()) != 128 {
+		t.Fatalf("unexpected buffer len %d cap %d", buf.Len(), cap(buf.Bytes()))
+	}
+	buf.Retain()
+	buf.Release()
+	if buf.Bytes() == nil {
+		t.Fatal("buffer returned while still retained")
+	}
+	buf.Release()
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("release twice should panic")
+		}
+	}()
+	buf.Release()
+}
+
+func TestReturnGrownSlice(t *testing.T) {
+	bytes := Allocate(8)
+	bytes = append(bytes, make([]byte, 10)...)
+	Return(bytes)
+	for i := 0; i < 10; i++ {
+		b := Allocate(32)
+		if len(b) != 32 {
+			t.Fatalf("unexpected len %d", len(b))
+		}
+	}
+}
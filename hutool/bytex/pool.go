@@ -32,10 +32,11 @@ func Allocate(c int) []byte {
 	return pools[idx].Get().([]byte)[:c]
 }
 
+// Return 只回收池中分配的切片，扩容过的切片容量不是2的幂，直接丢弃
 func Return(bytes []byte) {
 	idx := index(cap(bytes))
-	if idx > MaxIndex {
+	if idx > MaxIndex || cap(bytes) != 1<<idx {
 		return
 	}
-	pools[idx].Put(bytes)
+	pools[idx].Put(bytes[:cap(bytes)])
 }
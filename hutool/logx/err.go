@@ -0,0 +1,5 @@
+package logx
+
+import "errors"
+
+var LevelNotSupportedErr = errors.New("logger not support change level")
@@ -37,3 +37,18 @@ func (l Level) IntValue() int {
 		return 0
 	}
 }
+
+// ILevelLogger 支持运行时调整日志级别的实现
+type ILevelLogger interface {
+	SetLevel(level Level)
+	GetLevel() Level
+}
+
+func ParseLevel(s string) (Level, bool) {
+	switch l := Level(s); l {
+	case LevelDebug, LevelInfo, LevelWarn, LevelError:
+		return l, true
+	default:
+		return "", false
+	}
+}
@@ -46,3 +46,22 @@ func Error(args ...interface{}) {
 func Errorf(format string, args ...interface{}) {
 	globalLog.Errorf(format, args...)
 }
+
+// SetLevel 运行时调整全局日志级别，logger需要实现ILevelLogger
+func SetLevel(level logdef.Level) error {
+	l, ok := globalLog.(logdef.ILevelLogger)
+	if !ok {
+		return LevelNotSupportedErr
+	}
+	l.SetLevel(level)
+	return nil
+}
+
+// GetLevel 不支持级别的logger返回空
+func GetLevel() logdef.Level {
+	l, ok := globalLog.(logdef.ILevelLogger)
+	if !ok {
+		return ""
+	}
+	return l.GetLevel()
+}
@@ -8,6 +8,7 @@ import (
 	"reflect"
 	"slices"
 	"strings"
+	"sync/atomic"
 )
 
 type Field struct {
@@ -20,7 +21,8 @@ type Hook func(level logdef.Level, fields []Field, msg string)
 type Logger struct {
 	skip   int
 	fields []Field
-	level  logdef.Level
+	// 克隆出的logger共享级别，运行时调整对所有派生logger生效
+	level  *atomic.Pointer[logdef.Level]
 	logger *log.Logger
 	hook   Hook
 }
@@ -36,67 +38,76 @@ func NewLogger(opts ...Option) *Logger {
 		logger: lg,
 		skip:   cfg.skip,
 		hook:   cfg.hook,
-		level:  cfg.level,
+		level:  &atomic.Pointer[logdef.Level]{},
 	}
+	l.SetLevel(cfg.level)
 	return l
 }
 
 func (l Logger) Debug(args ...any) {
-	if logdef.LevelDebug.IntValue() < l.level.IntValue() {
+	if logdef.LevelDebug.IntValue() < l.GetLevel().IntValue() {
 		return
 	}
 	l.print(logdef.LevelDebug, fmt.Sprint(args...))
 }
 
 func (l Logger) Info(args ...any) {
-	if logdef.LevelInfo.IntValue() < l.level.IntValue() {
+	if logdef.LevelInfo.IntValue() < l.GetLevel().IntValue() {
 		return
 	}
 	l.print(logdef.LevelInfo, fmt.Sprint(args...))
 }
 
 func (l Logger) Warn(args ...any) {
-	if logdef.LevelWarn.IntValue() < l.level.IntValue() {
+	if logdef.LevelWarn.IntValue() < l.GetLevel().IntValue() {
 		return
 	}
 	l.print(logdef.LevelWarn, fmt.Sprint(args...))
 }
 
 func (l Logger) Error(args ...any) {
-	if logdef.LevelError.IntValue() < l.level.IntValue() {
+	if logdef.LevelError.IntValue() < l.GetLevel().IntValue() {
 		return
 	}
 	l.print(logdef.LevelError, fmt.Sprint(args...))
 }
 
 func (l Logger) Debugf(format string, args ...any) {
-	if logdef.LevelDebug.IntValue() < l.level.IntValue() {
+	if logdef.LevelDebug.IntValue() < l.GetLevel().IntValue() {
 		return
 	}
 	l.print(logdef.LevelDebug, fmt.Sprintf(format, args...))
 }
 
 func (l Logger) Infof(format string, args ...any) {
-	if logdef.LevelInfo.IntValue() < l.level.IntValue() {
+	if logdef.LevelInfo.IntValue() < l.GetLevel().IntValue() {
 		return
 	}
 	l.print(logdef.LevelInfo, fmt.Sprintf(format, args...))
 }
 
 func (l Logger) Warnf(format string, args ...any) {
-	if logdef.LevelWarn.IntValue() < l.level.IntValue() {
+	if logdef.LevelWarn.IntValue() < l.GetLevel().IntValue() {
 		return
 	}
-	l.print(logdef.LevelWarn, fmt.Sprint(format, args))
+	l.print(logdef.LevelWarn, fmt.Sprintf(format, args...))
 }
 
 func (l Logger) Errorf(format string, args ...any) {
-	if logdef.LevelError.IntValue() < l.level.IntValue() {
+	if logdef.LevelError.IntValue() < l.GetLevel().IntValue() {
 		return
 	}
 	l.print(logdef.LevelError, fmt.Sprintf(format, args...))
 }
 
+func (l Logger) SetLevel(level logdef.Level) {
+	l.level.Store(&level)
+}
+
+func (l Logger) GetLevel() logdef.Level {
+	return *l.level.Load()
+}
+
 func (l Logger) WithField(key string, value any) logdef.ILogger {
 	c := l.deepClone()
 	c.fields = slices.DeleteFunc(c.fields, func(f Field) bool {
@@ -124,7 +135,7 @@ func (l Logger) WithSkip(skip int) logdef.ILogger {
 }
 
 func (l Logger) print(level logdef.Level, msg string) {
-	if level.IntValue() < l.level.IntValue() {
+	if level.IntValue() < l.GetLevel().IntValue() {
 		return
 	}
 	l.updatePrefix(level)
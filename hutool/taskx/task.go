@@ -86,6 +86,7 @@ func (t *TaskPool[T]) Add(fn func() T, callback TaskCallback[T], key ...uint32)
 }
 
 func (t *TaskPool[T]) worker(id int) {
+	defer t.wg.Done()
 	targetChan := t.chans[id]
 	for {
 		select {
@@ -110,6 +111,19 @@ func (t *TaskPool[T]) processOneTask(task Task[T]) {
 	}
 }
 
+// QueueLens 每个worker队列中待处理的任务数
+func (t *TaskPool[T]) QueueLens() []int {
+	lens := make([]int, len(t.chans))
+	for i, ch := range t.chans {
+		lens[i] = len(ch)
+	}
+	return lens
+}
+
+func (t *TaskPool[T]) QueueSize() int {
+	return t.queueSize
+}
+
 func (t *TaskPool[T]) Stop() {
 	if t.closed.CompareAndSwap(false, true) {
 		t.cancelF()
@@ -17,3 +17,28 @@ func TestTask(t *testing.T) {
 	res := promise.Wait()
 	logx.Infof("%d", res)
 }
+
+func TestQueueLens(t *testing.T) {
+	pool := NewTaskPool[int](WithWorkerNum(2), WithQueueSize(4))
+	defer pool.Stop()
+	block := make(chan struct{})
+	// 先于Stop执行，失败时不会卡住worker
+	defer close(block)
+	for i := 0; i < 3; i++ {
+		_ = pool.Add(func() int {
+			<-block
+			return 0
+		}, nil, 0)
+	}
+	// 等worker取走第一个任务
+	var lens []int
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		lens = pool.QueueLens()
+		if len(lens) == 2 && lens[0] == 2 {
+			break
+		}
+	}
+	if len(lens) != 2 || lens[0] != 2 || lens[1] != 0 {
+		t.Fatalf("unexpected queue lens %v", lens)
+	}
+}
@@ -0,0 +1,39 @@
+package timewheel
+
+import "time"
+
+type Config struct {
+	tick      time.Duration
+	wheelSize int
+	levels    int
+}
+
+type Option func(*Config)
+
+// WithTick 时间轮精度
+func WithTick(tick time.Duration) Option {
+	return func(c *Config) {
+		c.tick = tick
+	}
+}
+
+func WithWheelSize(wheelSize int) Option {
+	return func(c *Config) {
+		c.wheelSize = wheelSize
+	}
+}
+
+// WithLevels 层数，最大定时为 tick * wheelSize^levels，超出的定时会在最高层轮转
+func WithLevels(levels int) Option {
+	return func(c *Config) {
+		c.levels = levels
+	}
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		tick:      10 * time.Millisecond,
+		wheelSize: 64,
+		levels:    4,
+	}
+}
@@ -0,0 +1,79 @@
+package timewheel
+
+import "time"
+
+type Timer struct {
+	w      *TimingWheel
+	expire int64
+	fn     func()
+	// 所在槽位的链表，不在轮上时为nil
+	bucket     *bucket
+	prev, next *Timer
+}
+
+// Stop 取消定时，返回定时是否还未触发
+func (t *Timer) Stop() bool {
+	t.w.mu.Lock()
+	defer t.w.mu.Unlock()
+	if t.bucket == nil {
+		return false
+	}
+	t.bucket.remove(t)
+	return true
+}
+
+// Reset 重新定时，已经触发或取消的定时也会重新加入，返回之前是否还未触发
+func (t *Timer) Reset(d time.Duration) bool {
+	t.w.mu.Lock()
+	defer t.w.mu.Unlock()
+	active := t.bucket != nil
+	if active {
+		t.bucket.remove(t)
+	}
+	t.expire = t.w.expireOf(d)
+	t.w.add(t)
+	return active
+}
+
+type bucket struct {
+	head Timer
+}
+
+func newBucket() *bucket {
+	b := &bucket{}
+	b.head.prev = &b.head
+	b.head.next = &b.head
+	return b
+}
+
+func (b *bucket) push(t *Timer) {
+	t.bucket = b
+	t.prev = b.head.prev
+	t.next = &b.head
+	b.head.prev.next = t
+	b.head.prev = t
+}
+
+func (b *bucket) remove(t *Timer) {
+	t.prev.next = t.next
+	t.next.prev = t.prev
+	t.prev = nil
+	t.next = nil
+	t.bucket = nil
+}
+
+// takeAll 取出所有定时并清空槽位
+func (b *bucket) takeAll() []*Timer {
+	timers := make([]*Timer, 0)
+	for t := b.head.next; t != &b.head; {
+		next := t.next
+		t.prev = nil
+		t.next = nil
+		t.bucket = nil
+		timers = append(timers, t)
+		t = next
+	}
+	b.head.prev = &b.head
+	b.head.next = &b.head
+	return timers
+}
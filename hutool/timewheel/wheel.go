@@ -0,0 +1,156 @@
+package timewheel
+
+import (
+	"context"
+	"hutool/safe"
+	"sync"
+	"time"
+)
+
+// TimingWheel 分层时间轮，加入、重置和取消都是O(1)，每个tick只处理到期的槽位
+type TimingWheel struct {
+	tick      time.Duration
+	wheelSize int64
+	// spans[i] 第i层一个槽位跨越的tick数
+	spans   []int64
+	buckets [][]*bucket
+	start   time.Time
+	current int64
+	mu      sync.Mutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func NewTimingWheel(opts ...Option) *TimingWheel {
+	cfg := DefaultConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &TimingWheel{
+		tick:      cfg.tick,
+		wheelSize: int64(cfg.wheelSize),
+		spans:     make([]int64, cfg.levels),
+		buckets:   make([][]*bucket, cfg.levels),
+		start:     time.Now(),
+		current:   0,
+		mu:        sync.Mutex{},
+		ctx:       ctx,
+		cancel:    cancel,
+		wg:        sync.WaitGroup{},
+	}
+	span := int64(1)
+	for i := range w.buckets {
+		w.spans[i] = span
+		span *= w.wheelSize
+		w.buckets[i] = make([]*bucket, cfg.wheelSize)
+		for j := range w.buckets[i] {
+			w.buckets[i][j] = newBucket()
+		}
+	}
+	w.wg.Add(1)
+	go w.run()
+	return w
+}
+
+// AfterFunc d之后在时间轮协程中执行fn，fn应当尽快返回
+func (w *TimingWheel) AfterFunc(d time.Duration, fn func()) *Timer {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	t := &Timer{
+		w:      w,
+		expire: w.expireOf(d),
+		fn:     fn,
+	}
+	w.add(t)
+	return t
+}
+
+func (w *TimingWheel) Stop() {
+	w.cancel()
+	w.wg.Wait()
+}
+
+// expireOf 按绝对时间计算到期tick，至少是下一个tick
+func (w *TimingWheel) expireOf(d time.Duration) int64 {
+	elapsed := time.Since(w.start) + d
+	expire := int64((elapsed + w.tick - 1) / w.tick)
+	if expire <= w.current {
+		expire = w.current + 1
+	}
+	return expire
+}
+
+func (w *TimingWheel) add(t *Timer) {
+	expire := t.expire
+	if expire < w.current {
+		expire = w.current
+	}
+	delta := expire - w.current
+	top := len(w.buckets) - 1
+	level := 0
+	for level < top && delta >= w.spans[level]*w.wheelSize {
+		level++
+	}
+	// 超出最大范围的先放在最高层最远的槽位，降级时重新计算
+	if delta >= w.spans[top]*w.wheelSize {
+		expire = w.current + w.spans[top]*w.wheelSize - 1
+	}
+	slot := (expire / w.spans[level]) % w.wheelSize
+	w.buckets[level][slot].push(t)
+}
+
+func (w *TimingWheel) run() {
+	defer w.wg.Done()
+	tk := time.NewTicker(w.tick)
+	defer tk.Stop()
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-tk.C:
+			// ticker可能丢失，按实际经过的时间推进
+			target := int64(time.Since(w.start) / w.tick)
+			for {
+				expired, ok := w.advance(target)
+				if !ok {
+					break
+				}
+				for _, t := range expired {
+					safe.Run(t.fn)
+				}
+			}
+		}
+	}
+}
+
+// advance 推进一个tick，返回到期的定时
+func (w *TimingWheel) advance(target int64) ([]*Timer, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.current >= target {
+		return nil, false
+	}
+	w.current++
+	// 高层到达槽位边界时降级到低层
+	for level := 1; level < len(w.buckets); level++ {
+		if w.current%w.spans[level] != 0 {
+			break
+		}
+		slot := (w.current / w.spans[level]) % w.wheelSize
+		for _, t := range w.buckets[level][slot].takeAll() {
+			w.add(t)
+		}
+	}
+	slot := w.current % w.wheelSize
+	expired := make([]*Timer, 0)
+	for _, t := range w.buckets[0][slot].takeAll() {
+		if t.expire > w.current {
+			w.add(t)
+			continue
+		}
+		expired = append(expired, t)
+	}
+	return expired, true
+}
@@ -0,0 +1,68 @@
+package timewheel
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAfterFunc(t *testing.T) {
+	// 小轮子，覆盖降级和超出范围的情况
+	w := NewTimingWheel(WithTick(time.Millisecond), WithWheelSize(4), WithLevels(2))
+	defer w.Stop()
+
+	delays := []time.Duration{2 * time.Millisecond, 7 * time.Millisecond, 20 * time.Millisecond, 50 * time.Millisecond}
+	start := time.Now()
+	fired := make(chan time.Duration, len(delays))
+	for _, d := range delays {
+		w.AfterFunc(d, func() {
+			fired <- time.Since(start)
+		})
+	}
+	for _, d := range delays {
+		select {
+		case elapsed := <-fired:
+			if elapsed < d-time.Millisecond {
+				t.Fatalf("timer %v fired too early at %v", d, elapsed)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timer %v not fired", d)
+		}
+	}
+}
+
+func TestStopAndReset(t *testing.T) {
+	w := NewTimingWheel(WithTick(time.Millisecond))
+	defer w.Stop()
+
+	var stopped atomic.Bool
+	tm := w.AfterFunc(10*time.Millisecond, func() {
+		stopped.Store(true)
+	})
+	if !tm.Stop() {
+		t.Fatal("stop pending timer should return true")
+	}
+
+	start := time.Now()
+	fired := make(chan time.Duration, 1)
+	tm2 := w.AfterFunc(10*time.Millisecond, func() {
+		fired <- time.Since(start)
+	})
+	time.Sleep(5 * time.Millisecond)
+	tm2.Reset(30 * time.Millisecond)
+
+	select {
+	case elapsed := <-fired:
+		if elapsed < 30*time.Millisecond {
+			t.Fatalf("reset timer fired at %v", elapsed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("reset timer not fired")
+	}
+	if stopped.Load() {
+		t.Fatal("stopped timer fired")
+	}
+	if tm2.Stop() {
+		t.Fatal("stop fired timer should return false")
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"server/app/test"
+	"server/pkg/codec"
+	"server/pkg/net/kcp"
+	"server/pkg/net/tcp"
+	"server/pkg/net/ws"
+	"time"
+)
+
+// benchClient 屏蔽不同传输层客户端的差异
+type benchClient interface {
+	Dial(host string, port int) error
+	Ask(msg string, onRsp func()) error
+	Tell(msg string) error
+	Close()
+}
+
+func newBenchClient(transport string, heartbeat time.Duration) (benchClient, error) {
+	serializer := codec.ProtoSerializer{}
+	switch transport {
+	case "tcp":
+		return &tcpClient{cl: tcp.NewClient(serializer, heartbeat)}, nil
+	case "ws":
+		return &wsClient{cl: ws.NewClient(serializer, heartbeat)}, nil
+	case "kcp":
+		return &kcpClient{cl: kcp.NewClient(serializer, heartbeat)}, nil
+	default:
+		return nil, fmt.Errorf("unknown transport %s", transport)
+	}
+}
+
+type tcpClient struct {
+	cl *tcp.Client
+}
+
+func (c *tcpClient) Dial(host string, port int) error {
+	return c.cl.Dial(host, port)
+}
+
+func (c *tcpClient) Ask(msg string, onRsp func()) error {
+	return tcp.Ask[*test.HelloAsk, *test.HelloRsp](c.cl, svcId, uint32(test.RouterId_Hello), &test.HelloAsk{Msg: msg}, func(rsp *test.HelloRsp) {
+		onRsp()
+	})
+}
+
+func (c *tcpClient) Tell(msg string) error {
+	return tcp.Tell[*test.HiTell](c.cl, svcId, uint32(test.RouterId_Hi), &test.HiTell{Msg: msg})
+}
+
+func (c *tcpClient) Close() {
+	c.cl.Close()
+}
+
+type wsClient struct {
+	cl *ws.Client
+}
+
+func (c *wsClient) Dial(host string, port int) error {
+	return c.cl.Dial(host, port)
+}
+
+func (c *wsClient) Ask(msg string, onRsp func()) error {
+	return ws.Ask[*test.HelloAsk, *test.HelloRsp](c.cl, svcId, uint32(test.RouterId_Hello), &test.HelloAsk{Msg: msg}, func(rsp *test.HelloRsp) {
+		onRsp()
+	})
+}
+
+func (c *wsClient) Tell(msg string) error {
+	return ws.Tell[*test.HiTell](c.cl, svcId, uint32(test.RouterId_Hi), &test.HiTell{Msg: msg})
+}
+
+func (c *wsClient) Close() {
+	c.cl.Close()
+}
+
+type kcpClient struct {
+	cl *kcp.Client
+}
+
+func (c *kcpClient) Dial(host string, port int) error {
+	return c.cl.Dial(host, port)
+}
+
+func (c *kcpClient) Ask(msg string, onRsp func()) error {
+	return kcp.Ask[*test.HelloAsk, *test.HelloRsp](c.cl, svcId, uint32(test.RouterId_Hello), &test.HelloAsk{Msg: msg}, func(rsp *test.HelloRsp) {
+		onRsp()
+	})
+}
+
+func (c *kcpClient) Tell(msg string) error {
+	return kcp.Tell[*test.HiTell](c.cl, svcId, uint32(test.RouterId_Hi), &test.HiTell{Msg: msg})
+}
+
+func (c *kcpClient) Close() {
+	c.cl.Close()
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"hutool/logx"
+	"hutool/logx/logdef"
+	"hutool/logx/stdlog"
+	"math/rand"
+	"os"
+	"server/app/test"
+	"server/pkg/codec"
+	"server/pkg/record"
+	router2 "server/pkg/router"
+	"server/pkg/service"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const svcId = uint32(0)
+
+type benchConfig struct {
+	transport   string
+	host        string
+	port        int
+	clients     int
+	duration    time.Duration
+	askRatio    float64
+	rate        int
+	askTimeout  time.Duration
+	heartbeat   time.Duration
+	local       bool
+	payloadSize int
+	record      string
+}
+
+func main() {
+	cfg := benchConfig{}
+	flag.StringVar(&cfg.transport, "transport", "tcp", "tcp, ws or kcp")
+	flag.StringVar(&cfg.host, "host", "127.0.0.1", "target host")
+	flag.IntVar(&cfg.port, "port", 8080, "target port")
+	flag.IntVar(&cfg.clients, "clients", 100, "number of clients")
+	flag.DurationVar(&cfg.duration, "duration", 10*time.Second, "test duration")
+	flag.Float64Var(&cfg.askRatio, "ask-ratio", 0.8, "ratio of ask in traffic, the rest is tell")
+	flag.IntVar(&cfg.rate, "rate", 0, "requests per second per client, 0 means as fast as possible")
+	flag.DurationVar(&cfg.askTimeout, "ask-timeout", 5*time.Second, "ask timeout")
+	flag.DurationVar(&cfg.heartbeat, "heartbeat", 3*time.Second, "client heartbeat interval")
+	flag.BoolVar(&cfg.local, "local", false, "start a server in process on host:port")
+	flag.IntVar(&cfg.payloadSize, "payload", 32, "message payload size")
+	flag.StringVar(&cfg.record, "record", "", "record local server traffic to file, replay with app/replay")
+	flag.Parse()
+
+	logx.SetLogger(stdlog.NewLogger(stdlog.WithLevel(logdef.LevelWarn)))
+
+	if cfg.local {
+		svc, recorder, err := startLocalServer(cfg)
+		if err != nil {
+			logx.Errorf("start local server err %+v", err)
+			os.Exit(1)
+		}
+		defer func() {
+			svc.Stop()
+			if recorder != nil {
+				_ = recorder.Close()
+			}
+		}()
+	}
+
+	s := &stats{}
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.duration)
+	defer cancel()
+	start := time.Now()
+	wg := sync.WaitGroup{}
+	for i := 0; i < cfg.clients; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runWorker(ctx, cfg, s)
+		}()
+	}
+	wg.Wait()
+	s.report(os.Stdout, time.Since(start))
+}
+
+func startLocalServer(cfg benchConfig) (*service.Service, *record.Recorder, error) {
+	router := router2.NewRouter()
+	router2.RegisterAskRouter[*test.HelloAsk, *test.HelloRsp](router, uint32(test.RouterId_Hello), func(ctx codec.ReqCtx, req *test.HelloAsk) *test.HelloRsp {
+		return &test.HelloRsp{Msg: req.Msg}
+	})
+	router2.RegisterTellRouter[*test.HiTell](router, uint32(test.RouterId_Hi), func(ctx codec.ReqCtx, req *test.HiTell) {
+	})
+	opts := []service.Option{service.WithRouter(router)}
+	var recorder *record.Recorder
+	if cfg.record != "" {
+		var err error
+		recorder, err = record.CreateRecorder(cfg.record, record.WithS2C(true))
+		if err != nil {
+			return nil, nil, err
+		}
+		opts = append(opts, service.WithPlugin(recorder))
+	}
+	svc := service.NewService(svcId, opts...)
+
+	var err error
+	switch cfg.transport {
+	case "tcp":
+		err = svc.StartTCPServer(cfg.host, cfg.port)
+	case "ws":
+		err = svc.StartWsServer(cfg.host, cfg.port, websocket.Upgrader{})
+	case "kcp":
+		err = svc.StartKcpServer(cfg.host, cfg.port)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	// 等待监听就绪
+	time.Sleep(100 * time.Millisecond)
+	return svc, recorder, nil
+}
+
+func runWorker(ctx context.Context, cfg benchConfig, s *stats) {
+	payload := string(make([]byte, cfg.payloadSize))
+	latencies := make([]time.Duration, 0, 1024)
+	defer func() {
+		s.addLatencies(latencies)
+	}()
+
+	var interval time.Duration
+	if cfg.rate > 0 {
+		interval = time.Second / time.Duration(cfg.rate)
+	}
+
+	cl := dial(ctx, cfg, s)
+	if cl == nil {
+		return
+	}
+	defer func() {
+		cl.Close()
+	}()
+
+	rspChan := make(chan struct{}, 1)
+	for ctx.Err() == nil {
+		begin := time.Now()
+		var err error
+		if rand.Float64() < cfg.askRatio {
+			err = cl.Ask(payload, func() {
+				rspChan <- struct{}{}
+			})
+			if err == nil {
+				err = waitRsp(ctx, rspChan, cfg.askTimeout, s)
+				if err == nil {
+					latencies = append(latencies, time.Since(begin))
+					s.asks.Add(1)
+				}
+			}
+		} else {
+			err = cl.Tell(payload)
+			if err == nil {
+				s.tells.Add(1)
+			}
+		}
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			s.errors.Add(1)
+			// 连接异常时重连
+			cl.Close()
+			cl = dial(ctx, cfg, s)
+			if cl == nil {
+				return
+			}
+			s.reconnects.Add(1)
+			rspChan = make(chan struct{}, 1)
+			continue
+		}
+
+		if interval > 0 {
+			wait := interval - time.Since(begin)
+			if wait > 0 {
+				select {
+				case <-ctx.Done():
+				case <-time.After(wait):
+				}
+			}
+		}
+	}
+}
+
+func dial(ctx context.Context, cfg benchConfig, s *stats) benchClient {
+	for ctx.Err() == nil {
+		cl, err := newBenchClient(cfg.transport, cfg.heartbeat)
+		if err != nil {
+			logx.Errorf("%+v", err)
+			return nil
+		}
+		err = cl.Dial(cfg.host, cfg.port)
+		if err == nil {
+			return cl
+		}
+		s.dialFails.Add(1)
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+		}
+	}
+	return nil
+}
+
+var askTimeoutErr = context.DeadlineExceeded
+
+func waitRsp(ctx context.Context, rspChan chan struct{}, timeout time.Duration, s *stats) error {
+	tm := time.NewTimer(timeout)
+	defer tm.Stop()
+	select {
+	case <-rspChan:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-tm.C:
+		s.timeouts.Add(1)
+		return askTimeoutErr
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var histogramBounds = []time.Duration{
+	500 * time.Microsecond,
+	time.Millisecond,
+	2 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	20 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	200 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+}
+
+type stats struct {
+	asks       atomic.Uint64
+	tells      atomic.Uint64
+	errors     atomic.Uint64
+	timeouts   atomic.Uint64
+	reconnects atomic.Uint64
+	dialFails  atomic.Uint64
+
+	mu        sync.Mutex
+	latencies []time.Duration
+}
+
+func (s *stats) addLatencies(latencies []time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latencies = append(s.latencies, latencies...)
+}
+
+func (s *stats) report(w io.Writer, elapsed time.Duration) {
+	s.mu.Lock()
+	latencies := slices.Clone(s.latencies)
+	s.mu.Unlock()
+	slices.Sort(latencies)
+
+	seconds := elapsed.Seconds()
+	asks := s.asks.Load()
+	tells := s.tells.Load()
+	_, _ = fmt.Fprintf(w, "duration    %v\n", elapsed.Round(time.Millisecond))
+	_, _ = fmt.Fprintf(w, "asks        %d (%.0f/s)\n", asks, float64(asks)/seconds)
+	_, _ = fmt.Fprintf(w, "tells       %d (%.0f/s)\n", tells, float64(tells)/seconds)
+	_, _ = fmt.Fprintf(w, "errors      %d\n", s.errors.Load())
+	_, _ = fmt.Fprintf(w, "timeouts    %d\n", s.timeouts.Load())
+	_, _ = fmt.Fprintf(w, "reconnects  %d\n", s.reconnects.Load())
+	_, _ = fmt.Fprintf(w, "dial fails  %d\n", s.dialFails.Load())
+	if len(latencies) == 0 {
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "latency     p50 %v  p90 %v  p99 %v  max %v\n",
+		percentile(latencies, 50), percentile(latencies, 90), percentile(latencies, 99), latencies[len(latencies)-1])
+
+	counts := make([]int, len(histogramBounds)+1)
+	for _, l := range latencies {
+		idx, _ := slices.BinarySearch(histogramBounds, l)
+		counts[idx]++
+	}
+	for i, count := range counts {
+		label := "+inf"
+		if i < len(histogramBounds) {
+			label = "<=" + histogramBounds[i].String()
+		}
+		ratio := float64(count) / float64(len(latencies))
+		_, _ = fmt.Fprintf(w, "  %-8s %8d %6.2f%% %s\n", label, count, ratio*100, strings.Repeat("#", int(ratio*50)))
+	}
+}
+
+func percentile(sorted []time.Duration, p int) time.Duration {
+	idx := (len(sorted)*p+99)/100 - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return sorted[idx]
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"server/pkg/inspect"
+	"server/pkg/record"
+	"strconv"
+	"strings"
+
+	// 示例协议的消息类型，业务协议通过-descriptors加载或在这里引入
+	_ "server/app/test"
+)
+
+// typeFlags -type req:0/0=test.HelloAsk，可以重复
+type typeFlags []string
+
+func (t *typeFlags) String() string {
+	return strings.Join(*t, " ")
+}
+
+func (t *typeFlags) Set(v string) error {
+	*t = append(*t, v)
+	return nil
+}
+
+func main() {
+	pcapFile := flag.String("pcap", "", "classic pcap file of tcp traffic")
+	port := flag.Uint("port", 0, "server port in pcap, packets to this port are c2s")
+	hexFile := flag.String("hex", "", "hex dump of a length prefixed tcp stream, - for stdin")
+	dir := flag.String("dir", "c2s", "direction of hex dump, c2s or s2c")
+	recordFile := flag.String("record", "", "file recorded by record.Recorder")
+	descriptors := flag.String("descriptors", "", "FileDescriptorSet from protoc --descriptor_set_out --include_imports")
+	maxBody := flag.Int("max-body", 512, "truncate decoded body, 0 means no limit")
+	types := typeFlags{}
+	flag.Var(&types, "type", "bind message type, kind:svc/router=full.Name, kind is req, rsp, push, ask or askrsp")
+	flag.Parse()
+
+	decoder := inspect.NewDecoder()
+	if *descriptors != "" {
+		if err := decoder.LoadDescriptors(*descriptors); err != nil {
+			fail(err)
+		}
+	}
+	for _, t := range types {
+		if err := bindType(decoder, t); err != nil {
+			fail(err)
+		}
+	}
+
+	frames, err := readFrames(*pcapFile, uint16(*port), *hexFile, *dir, *recordFile)
+	if errors.Is(err, inspect.PcapTruncatedErr) {
+		// 抓包中断时仍然输出已还原的包
+		fmt.Fprintln(os.Stderr, err)
+	} else if err != nil {
+		fail(err)
+	}
+	for _, frame := range frames {
+		packet := decoder.Decode(frame)
+		if *maxBody > 0 && len(packet.Text) > *maxBody {
+			packet.Text = packet.Text[:*maxBody] + "..."
+		}
+		fmt.Print(packet)
+	}
+}
+
+func readFrames(pcapFile string, port uint16, hexFile string, dir string, recordFile string) ([]inspect.Frame, error) {
+	switch {
+	case pcapFile != "":
+		if port == 0 {
+			return nil, fmt.Errorf("-port is required with -pcap")
+		}
+		f, err := os.Open(pcapFile)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return inspect.ReadPcap(f, port)
+	case hexFile != "":
+		return readHex(hexFile, dir)
+	case recordFile != "":
+		return readRecord(recordFile)
+	default:
+		return nil, fmt.Errorf("one of -pcap, -hex or -record is required")
+	}
+}
+
+func readHex(path string, dir string) ([]inspect.Frame, error) {
+	var text []byte
+	var err error
+	if path == "-" {
+		text, err = io.ReadAll(os.Stdin)
+	} else {
+		text, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	data, err := inspect.ParseHex(text)
+	if err != nil {
+		return nil, err
+	}
+	frameDir := inspect.DirC2S
+	if dir == "s2c" {
+		frameDir = inspect.DirS2C
+	}
+	packets, rest, err := inspect.SplitFrames(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "split frames err %v\n", err)
+	}
+	if len(rest) > 0 {
+		fmt.Fprintf(os.Stderr, "%d trailing bytes not decoded\n", len(rest))
+	}
+	frames := make([]inspect.Frame, 0, len(packets))
+	for _, packet := range packets {
+		frames = append(frames, inspect.Frame{Dir: frameDir, Data: packet})
+	}
+	return frames, nil
+}
+
+// readRecord 录制文件中的包没有长度头，S2C是压缩前的数据
+func readRecord(path string) ([]inspect.Frame, error) {
+	entries, err := record.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	frames := make([]inspect.Frame, 0, len(entries))
+	for _, entry := range entries {
+		frame := inspect.Frame{Time: entry.Time, Stream: "conn " + strconv.Itoa(int(entry.ConnId)), Data: entry.Data}
+		switch entry.Kind {
+		case record.KindC2S:
+			frame.Dir = inspect.DirC2S
+		case record.KindS2C:
+			frame.Dir = inspect.DirS2C
+		default:
+			continue
+		}
+		frames = append(frames, frame)
+	}
+	return frames, nil
+}
+
+func bindType(decoder *inspect.Decoder, v string) error {
+	kindStr, rest, ok1 := strings.Cut(v, ":")
+	routeStr, name, ok2 := strings.Cut(rest, "=")
+	svcStr, routerStr, ok3 := strings.Cut(routeStr, "/")
+	if !ok1 || !ok2 || !ok3 {
+		return fmt.Errorf("invalid -type %q", v)
+	}
+	kind, err := inspect.ParseKind(kindStr)
+	if err != nil {
+		return err
+	}
+	svc, err := strconv.ParseUint(svcStr, 10, 32)
+	if err != nil {
+		return err
+	}
+	router, err := strconv.ParseUint(routerStr, 10, 32)
+	if err != nil {
+		return err
+	}
+	return decoder.Bind(kind, uint32(svc), uint32(router), name)
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"hutool/logx"
+	"hutool/logx/logdef"
+	"hutool/logx/stdlog"
+	"os"
+	"server/app/test"
+	"server/pkg/codec"
+	"server/pkg/record"
+	router2 "server/pkg/router"
+	"server/pkg/service"
+	"time"
+)
+
+const svcId = uint32(0)
+
+// 回放app/bench -local -record录制的文件，业务服务按同样的方式组装自己的路由后调用record.Replayer
+func main() {
+	file := flag.String("file", "", "record file")
+	realtime := flag.Bool("realtime", false, "replay at original speed")
+	settle := flag.Duration("settle", 200*time.Millisecond, "wait for responses after replay")
+	maxDiffs := flag.Int("max-diffs", 20, "max diffs to print")
+	flag.Parse()
+
+	logx.SetLogger(stdlog.NewLogger(stdlog.WithLevel(logdef.LevelWarn)))
+
+	entries, err := record.ReadFile(*file)
+	if err != nil {
+		logx.Errorf("read record err %+v", err)
+		os.Exit(1)
+	}
+
+	router := router2.NewRouter()
+	router2.RegisterAskRouter[*test.HelloAsk, *test.HelloRsp](router, uint32(test.RouterId_Hello), func(ctx codec.ReqCtx, req *test.HelloAsk) *test.HelloRsp {
+		return &test.HelloRsp{Msg: req.Msg}
+	})
+	router2.RegisterTellRouter[*test.HiTell](router, uint32(test.RouterId_Hi), func(ctx codec.ReqCtx, req *test.HiTell) {
+	})
+	svc := service.NewService(svcId, service.WithRouter(router))
+	defer svc.Stop()
+
+	start := time.Now()
+	result := record.NewReplayer(svc, record.WithRealtime(*realtime), record.WithSettle(*settle)).Replay(entries)
+	fmt.Printf("replay %d entries in %v\n", len(entries), time.Since(start))
+	fmt.Printf("conns %d sent %d expected %d actual %d diffs %d\n", result.Conns, result.Sent, result.Expected, result.Actual, len(result.Diffs))
+	for i, diff := range result.Diffs {
+		if i >= *maxDiffs {
+			fmt.Printf("... %d more\n", len(result.Diffs)-i)
+			break
+		}
+		fmt.Printf("conn %d %s\n  expected %x\n  actual   %x\n", diff.ConnId, diff.Key, diff.Expected, diff.Actual)
+	}
+	if len(result.Diffs) > 0 {
+		os.Exit(1)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"hutool/syncx"
 	"net/http"
 	"server/app/test"
+	"server/pkg/admin"
 	"server/pkg/codec"
 	router2 "server/pkg/router"
 	"server/pkg/service"
@@ -58,8 +59,16 @@ func main() {
 		logx.Errorf("start tcp server error: %v", err)
 	}
 
+	// 调试控制台只监听本机
+	adm := admin.NewServer(svc)
+	err = adm.Start("127.0.0.1", 8081)
+	if err != nil {
+		logx.Errorf("start admin server error: %v", err)
+	}
+
 	syncx.WaitUntilSignaled()
 
+	adm.Stop()
 	svc.Stop()
 }
 
@@ -0,0 +1,13 @@
+package admin
+
+import "errors"
+
+var ConnIdErr = errors.New("invalid conn id")
+
+var RouterIdErr = errors.New("invalid router id")
+
+var LevelErr = errors.New("invalid log level")
+
+var UnauthorizedErr = errors.New("unauthorized")
+
+var InsecureListenErr = errors.New("admin server without token must listen on loopback")
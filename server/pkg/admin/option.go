@@ -0,0 +1,29 @@
+package admin
+
+type Config struct {
+	token string
+	pprof bool
+}
+
+type Option func(*Config)
+
+// WithToken 请求需要携带Authorization: Bearer token，为空不校验，此时只能监听回环地址
+func WithToken(token string) Option {
+	return func(c *Config) {
+		c.token = token
+	}
+}
+
+// WithPprof 挂载/debug/pprof，默认关闭
+func WithPprof(enable bool) Option {
+	return func(c *Config) {
+		c.pprof = enable
+	}
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		token: "",
+		pprof: false,
+	}
+}
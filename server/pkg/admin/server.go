@@ -0,0 +1,280 @@
+package admin
+
+import (
+	"cmp"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hutool/logx"
+	"hutool/logx/logdef"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"server/pkg/codec"
+	"server/pkg/net/batch"
+	"server/pkg/service"
+	"server/pkg/session"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Server 运行中服务的调试控制台，只应监听内网地址
+type Server struct {
+	svc        *service.Service
+	cfg        *Config
+	mux        *http.ServeMux
+	httpServer *http.Server
+}
+
+type SessionInfo struct {
+	ConnId     uint32            `json:"connId"`
+	RemoteAddr string            `json:"remoteAddr"`
+	LastActive time.Time         `json:"lastActive"`
+	Attrs      map[string]string `json:"attrs"`
+	Writer     *batch.Stats      `json:"writer,omitempty"`
+}
+
+type WriterPoolInfo struct {
+	QueueSize int   `json:"queueSize"`
+	QueueLens []int `json:"queueLens"`
+	Pending   int   `json:"pending"`
+}
+
+func NewServer(svc *service.Service, opts ...Option) *Server {
+	cfg := DefaultConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	s := &Server{
+		svc: svc,
+		cfg: cfg,
+		mux: http.NewServeMux(),
+	}
+	s.mux.HandleFunc("GET /sessions", s.handleSessions)
+	s.mux.HandleFunc("POST /sessions/{connId}/kick", s.handleKick)
+	s.mux.HandleFunc("GET /routers", s.handleRouters)
+	s.mux.HandleFunc("GET /writer-pool", s.handleWriterPool)
+	s.mux.HandleFunc("GET /features", s.handleFeatures)
+	s.mux.HandleFunc("PUT /routers/{routerId}/disable", s.handleDisableRouter)
+	s.mux.HandleFunc("PUT /routers/{routerId}/enable", s.handleEnableRouter)
+	s.mux.HandleFunc("PUT /maintenance", s.handleEnterMaintenance)
+	s.mux.HandleFunc("DELETE /maintenance", s.handleExitMaintenance)
+	s.mux.HandleFunc("GET /log-level", s.handleGetLevel)
+	s.mux.HandleFunc("PUT /log-level", s.handleSetLevel)
+	if cfg.pprof {
+		s.mux.HandleFunc("/debug/pprof/", pprof.Index)
+		s.mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		s.mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		s.mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		s.mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+	return s
+}
+
+// Handler 挂载到已有的http服务时使用
+func (s *Server) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.authorized(r) {
+			writeErr(w, http.StatusUnauthorized, UnauthorizedErr)
+			return
+		}
+		s.mux.ServeHTTP(w, r)
+	})
+}
+
+// Start 没有设置token时拒绝监听非回环地址
+func (s *Server) Start(host string, port int) error {
+	if s.cfg.token == "" && !isLoopback(host) {
+		return InsecureListenErr
+	}
+	address := fmt.Sprintf("%s:%d", host, port)
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	s.httpServer = &http.Server{
+		Handler: s.Handler(),
+	}
+	go func() {
+		err := s.httpServer.Serve(ln)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logx.Errorf("admin server err %+v", err)
+		}
+	}()
+	logx.Infof("admin server start %s", address)
+	return nil
+}
+
+func (s *Server) Stop() {
+	if s.httpServer == nil {
+		return
+	}
+	err := s.httpServer.Close()
+	if err != nil {
+		logx.Errorf("admin server close err %+v", err)
+	}
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if s.cfg.token == "" {
+		return true
+	}
+	expect := "Bearer " + s.cfg.token
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(expect)) == 1
+}
+
+func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
+	sessions := make([]SessionInfo, 0, s.svc.SessionManager().Count())
+	s.svc.SessionManager().Range(func(session *session.Session) bool {
+		sessions = append(sessions, sessionInfo(session))
+		return true
+	})
+	slices.SortFunc(sessions, func(a, b SessionInfo) int {
+		return cmp.Compare(a.ConnId, b.ConnId)
+	})
+	writeJson(w, sessions)
+}
+
+func sessionInfo(session *session.Session) SessionInfo {
+	info := SessionInfo{
+		ConnId:     session.GetConnId(),
+		RemoteAddr: session.RemoteAddr(),
+		LastActive: session.LastActiveTime(),
+		Attrs:      map[string]string{},
+	}
+	// 属性值可能无法序列化，统一转成字符串
+	for key, value := range session.Attrs() {
+		info.Attrs[key] = fmt.Sprint(value)
+	}
+	if stats, ok := session.WriterStats(); ok {
+		info.Writer = &stats
+	}
+	return info
+}
+
+func (s *Server) handleKick(w http.ResponseWriter, r *http.Request) {
+	connId, err := strconv.ParseUint(r.PathValue("connId"), 10, 32)
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, ConnIdErr)
+		return
+	}
+	if _, ok := s.svc.SessionManager().GetSession(uint32(connId)); !ok {
+		writeErr(w, http.StatusNotFound, session.NotFoundErr)
+		return
+	}
+	msg := r.URL.Query().Get("msg")
+	logx.Infof("admin kick session %d %s", connId, r.RemoteAddr)
+	s.svc.Kick(uint32(connId), codec.KickReasonAdmin, msg)
+	writeJson(w, map[string]any{"connId": connId})
+}
+
+func (s *Server) handleRouters(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, s.svc.Routers())
+}
+
+func (s *Server) handleWriterPool(w http.ResponseWriter, r *http.Request) {
+	queueLens, queueSize := s.svc.WriterPoolStats()
+	info := WriterPoolInfo{
+		QueueSize: queueSize,
+		QueueLens: queueLens,
+	}
+	for _, l := range queueLens {
+		info.Pending += l
+	}
+	writeJson(w, info)
+}
+
+func (s *Server) handleFeatures(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, s.svc.FeatureFlags().Status())
+}
+
+func (s *Server) handleDisableRouter(w http.ResponseWriter, r *http.Request) {
+	routerId, err := strconv.ParseUint(r.PathValue("routerId"), 10, 32)
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, RouterIdErr)
+		return
+	}
+	logx.Infof("admin disable router %d %s", routerId, r.RemoteAddr)
+	s.svc.FeatureFlags().DisableRouter(uint32(routerId))
+	writeJson(w, s.svc.FeatureFlags().Status())
+}
+
+func (s *Server) handleEnableRouter(w http.ResponseWriter, r *http.Request) {
+	routerId, err := strconv.ParseUint(r.PathValue("routerId"), 10, 32)
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, RouterIdErr)
+		return
+	}
+	logx.Infof("admin enable router %d %s", routerId, r.RemoteAddr)
+	s.svc.FeatureFlags().EnableRouter(uint32(routerId))
+	writeJson(w, s.svc.FeatureFlags().Status())
+}
+
+// handleEnterMaintenance whitelist为逗号分隔的路由id
+func (s *Server) handleEnterMaintenance(w http.ResponseWriter, r *http.Request) {
+	var whitelist []uint32
+	for _, v := range strings.Split(r.URL.Query().Get("whitelist"), ",") {
+		if v == "" {
+			continue
+		}
+		routerId, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			writeErr(w, http.StatusBadRequest, RouterIdErr)
+			return
+		}
+		whitelist = append(whitelist, uint32(routerId))
+	}
+	logx.Infof("admin enter maintenance %v %s", whitelist, r.RemoteAddr)
+	s.svc.FeatureFlags().EnterMaintenance(r.URL.Query().Get("msg"), whitelist...)
+	writeJson(w, s.svc.FeatureFlags().Status())
+}
+
+func (s *Server) handleExitMaintenance(w http.ResponseWriter, r *http.Request) {
+	logx.Infof("admin exit maintenance %s", r.RemoteAddr)
+	s.svc.FeatureFlags().ExitMaintenance()
+	writeJson(w, s.svc.FeatureFlags().Status())
+}
+
+func (s *Server) handleGetLevel(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, map[string]any{"level": logx.GetLevel()})
+}
+
+func (s *Server) handleSetLevel(w http.ResponseWriter, r *http.Request) {
+	level, ok := logdef.ParseLevel(r.URL.Query().Get("level"))
+	if !ok {
+		writeErr(w, http.StatusBadRequest, LevelErr)
+		return
+	}
+	err := logx.SetLevel(level)
+	if err != nil {
+		writeErr(w, http.StatusNotImplemented, err)
+		return
+	}
+	logx.Infof("admin set log level %s %s", level, r.RemoteAddr)
+	writeJson(w, map[string]any{"level": level})
+}
+
+func writeJson(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		logx.Errorf("admin write rsp err %+v", err)
+	}
+}
+
+func writeErr(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
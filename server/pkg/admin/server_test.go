@@ -0,0 +1,170 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"server/pkg/codec"
+	"server/pkg/net/tcp"
+	"server/pkg/service"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newTestService(t *testing.T) *service.Service {
+	svc := service.NewService(0)
+	err := svc.StartTCPServer("127.0.0.1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(svc.Stop)
+	return svc
+}
+
+func do(t *testing.T, h http.Handler, method string, url string, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, url, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAuth(t *testing.T) {
+	svc := service.NewService(0)
+	defer svc.Stop()
+	h := NewServer(svc, WithToken("secret")).Handler()
+	if rec := do(t, h, "GET", "/routers", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("no token code %d", rec.Code)
+	}
+	if rec := do(t, h, "GET", "/routers", "wrong"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("wrong token code %d", rec.Code)
+	}
+	if rec := do(t, h, "GET", "/routers", "secret"); rec.Code != http.StatusOK {
+		t.Fatalf("valid token code %d", rec.Code)
+	}
+	// pprof默认关闭
+	if rec := do(t, h, "GET", "/debug/pprof/", "secret"); rec.Code != http.StatusNotFound {
+		t.Fatalf("pprof code %d", rec.Code)
+	}
+	if rec := do(t, NewServer(svc, WithPprof(true)).Handler(), "GET", "/debug/pprof/", ""); rec.Code != http.StatusOK {
+		t.Fatalf("pprof enabled code %d", rec.Code)
+	}
+}
+
+func TestStartRequiresTokenOffLoopback(t *testing.T) {
+	svc := service.NewService(0)
+	defer svc.Stop()
+	err := NewServer(svc).Start("0.0.0.0", 0)
+	if !errors.Is(err, InsecureListenErr) {
+		t.Fatalf("start without token err %v", err)
+	}
+	s := NewServer(svc)
+	err = s.Start("127.0.0.1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Stop()
+	s = NewServer(svc, WithToken("secret"))
+	err = s.Start("0.0.0.0", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Stop()
+}
+
+func TestKick(t *testing.T) {
+	svc := newTestService(t)
+	h := NewServer(svc).Handler()
+
+	cl := tcp.NewClient(codec.ProtoSerializer{}, time.Second)
+	kicked := make(chan codec.KickReason, 1)
+	cl.OnKick(func(reason codec.KickReason, msg string) {
+		kicked <- reason
+	})
+	err := cl.Dial("127.0.0.1", svc.TCPAddr().(*net.TCPAddr).Port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+
+	var sessions []SessionInfo
+	deadline := time.Now().Add(time.Second)
+	for len(sessions) == 0 && time.Now().Before(deadline) {
+		rec := do(t, h, "GET", "/sessions", "")
+		err = json.Unmarshal(rec.Body.Bytes(), &sessions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(sessions) != 1 {
+		t.Fatalf("unexpected sessions %+v", sessions)
+	}
+
+	if rec := do(t, h, "POST", "/sessions/abc/kick", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad conn id code %d", rec.Code)
+	}
+	if rec := do(t, h, "POST", "/sessions/4294967296/kick", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("overflow conn id code %d", rec.Code)
+	}
+	if rec := do(t, h, "POST", "/sessions/999999/kick", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown conn id code %d", rec.Code)
+	}
+	url := "/sessions/" + strconv.FormatUint(uint64(sessions[0].ConnId), 10) + "/kick?msg=bye"
+	if rec := do(t, h, "POST", url, ""); rec.Code != http.StatusOK {
+		t.Fatalf("kick code %d %s", rec.Code, rec.Body)
+	}
+	select {
+	case reason := <-kicked:
+		if reason != codec.KickReasonAdmin {
+			t.Fatalf("kick reason %d", reason)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("client not kicked")
+	}
+}
+
+func TestLogLevelBadValue(t *testing.T) {
+	svc := service.NewService(0)
+	defer svc.Stop()
+	h := NewServer(svc).Handler()
+	if rec := do(t, h, "PUT", "/log-level?level=loud", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad level code %d", rec.Code)
+	}
+}
+
+func TestFeatureRoutes(t *testing.T) {
+	svc := service.NewService(0)
+	defer svc.Stop()
+	h := NewServer(svc).Handler()
+	if rec := do(t, h, "PUT", "/routers/abc/disable", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad router id code %d", rec.Code)
+	}
+	if rec := do(t, h, "PUT", "/maintenance?whitelist=1,x", ""); rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad whitelist code %d", rec.Code)
+	}
+	if svc.FeatureFlags().InMaintenance() {
+		t.Fatal("bad whitelist should not enter maintenance")
+	}
+
+	do(t, h, "PUT", "/routers/7/disable", "")
+	rec := do(t, h, "PUT", "/maintenance?whitelist=1,2&msg=later", "")
+	var status service.FeatureStatus
+	err := json.Unmarshal(rec.Body.Bytes(), &status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Maintenance || len(status.Whitelist) != 2 || len(status.DisabledRouters) != 1 || status.Msg != "later" {
+		t.Fatalf("unexpected status %+v", status)
+	}
+	do(t, h, "PUT", "/routers/7/enable", "")
+	do(t, h, "DELETE", "/maintenance", "")
+	if status = svc.FeatureFlags().Status(); status.Maintenance || len(status.DisabledRouters) != 0 {
+		t.Fatalf("unexpected status after reset %+v", status)
+	}
+}
@@ -0,0 +1,83 @@
+package codec
+
+import (
+	"encoding/binary"
+	"hutool/bitx"
+)
+
+const (
+	S2CRspHeadLen  = 5
+	S2CPushHeadLen = 9
+	S2CAskHeadLen  = 13
+	C2SReqHeadLen  = 13
+	C2SRspHeadLen  = 5
+)
+
+// 以下Append函数只写包头，包体由调用方继续追加，配合池化缓冲避免分配
+
+func AppendS2CRspHead(dst []byte, reqId uint32) []byte {
+	var head byte = 0
+	bitx.SetBit(&head, IsPushPacketBitPost, false)
+	dst = append(dst, head)
+	return binary.BigEndian.AppendUint32(dst, reqId)
+}
+
+func AppendS2CPushHead(dst []byte, serviceId uint32, routerId uint32) []byte {
+	var head byte = 0
+	bitx.SetBit(&head, IsPushPacketBitPost, true)
+	dst = append(dst, head)
+	dst = binary.BigEndian.AppendUint32(dst, serviceId)
+	return binary.BigEndian.AppendUint32(dst, routerId)
+}
+
+func AppendS2CAskHead(dst []byte, serviceId uint32, routerId uint32, reqId uint32) []byte {
+	var head byte = 0
+	bitx.SetBit(&head, IsAskPacketBitPos, true)
+	dst = append(dst, head)
+	dst = binary.BigEndian.AppendUint32(dst, serviceId)
+	dst = binary.BigEndian.AppendUint32(dst, routerId)
+	return binary.BigEndian.AppendUint32(dst, reqId)
+}
+
+func AppendC2SReqHead(dst []byte, serviceId uint32, routerId uint32, reqId uint32, isOneWay bool) []byte {
+	var head byte = 0
+	bitx.SetBit(&head, IsOneWayBitPos, isOneWay)
+	bitx.SetBit(&head, HeartbeatBitPos, false)
+	dst = append(dst, head)
+	dst = binary.BigEndian.AppendUint32(dst, serviceId)
+	dst = binary.BigEndian.AppendUint32(dst, routerId)
+	return binary.BigEndian.AppendUint32(dst, reqId)
+}
+
+func AppendC2SRspHead(dst []byte, reqId uint32) []byte {
+	var head byte = 0
+	bitx.SetBit(&head, IsRspPacketBitPos, true)
+	dst = append(dst, head)
+	return binary.BigEndian.AppendUint32(dst, reqId)
+}
+
+// AppendC2SMetadata packet只能包含包头，元数据为空时不改变包
+func AppendC2SMetadata(packet []byte, md Metadata) ([]byte, error) {
+	if len(md) == 0 {
+		return packet, nil
+	}
+	packet, err := appendMetadata(packet, md)
+	if err != nil {
+		return packet, err
+	}
+	bitx.SetBit(&packet[0], C2SHasMetaBitPos, true)
+	return packet, nil
+}
+
+// AppendS2CMetadata packet只能包含包头，元数据为空时不改变包
+func AppendS2CMetadata(packet []byte, md Metadata) ([]byte, error) {
+	if len(md) == 0 {
+		return packet, nil
+	}
+	packet, err := appendMetadata(packet, md)
+	if err != nil {
+		return packet, err
+	}
+	bitx.SetBit(&packet[0], S2CHasMetaBitPos, true)
+	return packet, nil
+}
@@ -1,19 +1,53 @@
 package codec
 
-import "server/pkg/session"
+import (
+	"context"
+	"server/pkg/session"
+)
 
+var _ context.Context = ReqCtx{}
+
+// ReqCtx 实现context.Context，连接断开时取消，请求带超时的时候按超时设置截止时间
 type ReqCtx struct {
+	context.Context
 	reqId   uint32
 	session *session.Session
+	packet  C2SPacket
+	meta    Metadata
+	state   *reqState
+}
+
+// reqState ReqCtx按值传递，处理期间需要修改的部分放在指针里共享
+// ReqCtx可能被带到其他协程，不能池化复用
+type reqState struct {
+	rspMeta Metadata
+	cancel  context.CancelFunc
 }
 
+// NewReqCtx 处理结束后需要调用Release，格式错误的元数据直接忽略
 func NewReqCtx(packet C2SPacket, session *session.Session) ReqCtx {
+	meta, _ := packet.Metadata()
+	state := &reqState{}
+	ctx := session.Context()
+	if timeout, ok := meta.Timeout(); ok {
+		ctx, state.cancel = context.WithTimeout(ctx, timeout)
+	}
 	return ReqCtx{
+		Context: ctx,
 		session: session,
 		reqId:   packet.ReqId(),
+		packet:  packet,
+		meta:    meta,
+		state:   state,
 	}
 }
 
+// WithContext 替换内部的上下文，用于附加追踪等信息，ctx需要从原来的上下文派生
+func (c ReqCtx) WithContext(ctx context.Context) ReqCtx {
+	c.Context = ctx
+	return c
+}
+
 func (c ReqCtx) GetReqId() uint32 {
 	return c.reqId
 }
@@ -21,3 +55,36 @@ func (c ReqCtx) GetReqId() uint32 {
 func (c ReqCtx) GetSession() *session.Session {
 	return c.session
 }
+
+// Packet 原始请求包，只在处理期间有效，异步使用前需要Retain
+func (c ReqCtx) Packet() C2SPacket {
+	return c.packet
+}
+
+// Metadata 请求附带的元数据，只读
+func (c ReqCtx) Metadata() Metadata {
+	return c.meta
+}
+
+func (c ReqCtx) TraceId() string {
+	return c.meta[MetaKeyTraceId]
+}
+
+// SetRspMeta 设置响应的元数据，只对ask有效，需要在处理函数返回前调用
+func (c ReqCtx) SetRspMeta(key string, value string) {
+	if c.state.rspMeta == nil {
+		c.state.rspMeta = make(Metadata)
+	}
+	c.state.rspMeta.Set(key, value)
+}
+
+func (c ReqCtx) RspMetadata() Metadata {
+	return c.state.rspMeta
+}
+
+// Release 释放超时定时器，带超时的请求之后会被取消，异步任务需要自己派生上下文
+func (c ReqCtx) Release() {
+	if c.state.cancel != nil {
+		c.state.cancel()
+	}
+}
@@ -5,4 +5,8 @@ import "errors"
 var (
 	TypeNotSupportErr error = errors.New("type not support")
 	PacketBytesErr    error = errors.New("packet bytes error")
+	// PacketReservedBitsErr 对端使用了当前版本不认识的标记
+	PacketReservedBitsErr error = errors.New("packet reserved bits set")
+	PacketTooLargeErr     error = errors.New("packet too large")
+	MetadataTooLargeErr   error = errors.New("packet metadata too large")
 )
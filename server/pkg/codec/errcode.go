@@ -0,0 +1,59 @@
+package codec
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// 框架拒绝请求时写入响应元数据，消息体为空
+const (
+	MetaKeyErrCode = "err-code"
+	MetaKeyErrMsg  = "err-msg"
+)
+
+type ErrCode uint32
+
+const (
+	ErrCodeOk ErrCode = iota
+	// ErrCodeRouterDisabled 路由被临时关闭
+	ErrCodeRouterDisabled
+	// ErrCodeMaintenance 服务维护中，只放行白名单路由
+	ErrCodeMaintenance
+)
+
+// RspErr 服务端返回的错误响应
+type RspErr struct {
+	Code ErrCode
+	Msg  string
+}
+
+func (e *RspErr) Error() string {
+	return fmt.Sprintf("rsp err code %d %s", e.Code, e.Msg)
+}
+
+// NewS2CErrRspPacket 错误响应，只有元数据没有消息体
+func NewS2CErrRspPacket(reqId uint32, code ErrCode, msg string) (S2CPacket, error) {
+	md := Metadata{}
+	md.SetErr(code, msg)
+	return NewS2CRspPacketWithMeta(reqId, md, nil)
+}
+
+// Err 响应不是错误时返回nil
+func (md Metadata) Err() *RspErr {
+	v, ok := md[MetaKeyErrCode]
+	if !ok {
+		return nil
+	}
+	code, err := strconv.ParseUint(v, 10, 32)
+	if err != nil || code == uint64(ErrCodeOk) {
+		return nil
+	}
+	return &RspErr{Code: ErrCode(code), Msg: md[MetaKeyErrMsg]}
+}
+
+func (md Metadata) SetErr(code ErrCode, msg string) {
+	md[MetaKeyErrCode] = strconv.FormatUint(uint64(code), 10)
+	if msg != "" {
+		md[MetaKeyErrMsg] = msg
+	}
+}
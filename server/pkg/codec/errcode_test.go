@@ -0,0 +1,37 @@
+package codec
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestErrRspPacket(t *testing.T) {
+	packet, err := NewS2CErrRspPacket(7, ErrCodeMaintenance, "maintaining")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := BytesToS2CPacket(packet.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	md, err := parsed.Metadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rspErr := md.Err()
+	if parsed.ReqId() != 7 || len(parsed.Body()) != 0 || rspErr == nil || rspErr.Code != ErrCodeMaintenance || rspErr.Msg != "maintaining" {
+		t.Fatalf("unexpected err rsp %v %v", parsed.ReqId(), rspErr)
+	}
+
+	text, err := S2CPacketToJson(parsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(text), `"body":null`) || !strings.Contains(string(text), `"err-code":"2"`) {
+		t.Fatalf("unexpected json %s", text)
+	}
+
+	if (Metadata{}).Err() != nil {
+		t.Fatal("metadata without err code should not be err")
+	}
+}
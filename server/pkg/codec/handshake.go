@@ -0,0 +1,116 @@
+package codec
+
+import "encoding/binary"
+
+const (
+	// ProtocolVersion 当前协议版本，包格式变化时递增
+	ProtocolVersion = uint16(1)
+	// MinProtocolVersion 服务端还能兼容的最低版本
+	MinProtocolVersion = uint16(1)
+	// MaxPacketLen 协议默认的最大包长
+	MaxPacketLen = 4 * 1024
+)
+
+const SysRouterHandshake = uint32(3)
+
+// 能力标记，握手时取双方交集
+const (
+	CapServerAsk uint32 = 1 << iota
+	CapKick
+	CapUdp
+
+	CapAll = CapServerAsk | CapKick | CapUdp
+)
+
+const (
+	SerializerIdUnknown uint8 = 0
+	SerializerIdProto   uint8 = 1
+	SerializerIdJson    uint8 = 2
+	SerializerIdMsgpack uint8 = 3
+)
+
+// SerializerIdOf 自定义序列化返回Unknown，不参与协商
+func SerializerIdOf(serializer ISerializer) uint8 {
+	switch serializer.(type) {
+	case ProtoSerializer, *ProtoSerializer:
+		return SerializerIdProto
+	case JsonSerializer, *JsonSerializer:
+		return SerializerIdJson
+	case MsgpackSerializer, *MsgpackSerializer:
+		return SerializerIdMsgpack
+	default:
+		return SerializerIdUnknown
+	}
+}
+
+type HandshakeCode uint8
+
+const (
+	HandshakeOk HandshakeCode = iota
+	HandshakeVersionUnsupported
+	HandshakeSerializerUnsupported
+)
+
+const handshakeLen = 12
+
+// Handshake 连接建立后客户端发送的第一个请求
+// version(2) serializer(1) compression(1) maxPacketLen(4) caps(4)
+type Handshake struct {
+	Version    uint16
+	Serializer uint8
+	// Compression 客户端是支持的压缩算法掩码，响应中是选中的算法
+	Compression  uint8
+	MaxPacketLen uint32
+	Caps         uint32
+}
+
+func (h Handshake) Encode() []byte {
+	bytes := make([]byte, handshakeLen)
+	h.encodeTo(bytes)
+	return bytes
+}
+
+func (h Handshake) encodeTo(bytes []byte) {
+	binary.BigEndian.PutUint16(bytes[0:2], h.Version)
+	bytes[2] = h.Serializer
+	bytes[3] = h.Compression
+	binary.BigEndian.PutUint32(bytes[4:8], h.MaxPacketLen)
+	binary.BigEndian.PutUint32(bytes[8:12], h.Caps)
+}
+
+func DecodeHandshake(bytes []byte) (Handshake, error) {
+	if len(bytes) != handshakeLen {
+		return Handshake{}, PacketBytesErr
+	}
+	return Handshake{
+		Version:      binary.BigEndian.Uint16(bytes[0:2]),
+		Serializer:   bytes[2],
+		Compression:  bytes[3],
+		MaxPacketLen: binary.BigEndian.Uint32(bytes[4:8]),
+		Caps:         binary.BigEndian.Uint32(bytes[8:12]),
+	}, nil
+}
+
+// HandshakeRsp 服务端协商后的结果: code(1) handshake
+type HandshakeRsp struct {
+	Code HandshakeCode
+	Handshake
+}
+
+func (r HandshakeRsp) Encode() []byte {
+	bytes := make([]byte, handshakeLen+1)
+	bytes[0] = byte(r.Code)
+	r.Handshake.encodeTo(bytes[1:])
+	return bytes
+}
+
+func DecodeHandshakeRsp(bytes []byte) (HandshakeRsp, error) {
+	if len(bytes) != handshakeLen+1 {
+		return HandshakeRsp{}, PacketBytesErr
+	}
+	h, err := DecodeHandshake(bytes[1:])
+	if err != nil {
+		return HandshakeRsp{}, err
+	}
+	return HandshakeRsp{Code: HandshakeCode(bytes[0]), Handshake: h}, nil
+}
@@ -0,0 +1,86 @@
+package codec
+
+import (
+	"encoding/json"
+)
+
+var jsonNull = json.RawMessage("null")
+
+// JsonC2SEnvelope 浏览器等文本客户端使用的请求格式，body需要配合JsonSerializer
+type JsonC2SEnvelope struct {
+	Svc       uint32 `json:"svc"`
+	Router    uint32 `json:"router"`
+	ReqId     uint32 `json:"reqId"`
+	OneWay    bool   `json:"oneWay"`
+	Heartbeat bool   `json:"heartbeat,omitempty"`
+	// Rsp 响应服务端请求，只需要reqId和body
+	Rsp  bool            `json:"rsp,omitempty"`
+	Meta Metadata        `json:"meta,omitempty"`
+	Body json.RawMessage `json:"body"`
+}
+
+type JsonS2CEnvelope struct {
+	Push   bool            `json:"push"`
+	Ask    bool            `json:"ask,omitempty"`
+	Svc    uint32          `json:"svc,omitempty"`
+	Router uint32          `json:"router,omitempty"`
+	ReqId  uint32          `json:"reqId,omitempty"`
+	Meta   Metadata        `json:"meta,omitempty"`
+	Body   json.RawMessage `json:"body"`
+	// Bin 系统消息等非json的消息体，以base64编码，此时body为null
+	Bin []byte `json:"bin,omitempty"`
+}
+
+func JsonToC2SPacket(data []byte) (C2SPacket, error) {
+	var envelope JsonC2SEnvelope
+	err := json.Unmarshal(data, &envelope)
+	if err != nil {
+		return C2SPacket{}, err
+	}
+	if envelope.Heartbeat {
+		return *NewC2SHeartBeatPacket(), nil
+	}
+	body := []byte(envelope.Body)
+	if len(body) == 0 {
+		body = []byte("null")
+	}
+	if envelope.Rsp {
+		return NewC2SRspPacket(envelope.ReqId, body), nil
+	}
+	return NewC2SReqPacketWithMeta(envelope.Svc, envelope.Router, envelope.ReqId, envelope.OneWay, envelope.Meta, body)
+}
+
+func S2CPacketToJson(p S2CPacket) ([]byte, error) {
+	body := p.Body()
+	// 错误响应没有消息体
+	if len(body) == 0 {
+		body = jsonNull
+	}
+	var bin []byte
+	if !json.Valid(body) {
+		bin = body
+		body = jsonNull
+	}
+	md, err := p.Metadata()
+	if err != nil {
+		return nil, err
+	}
+	envelope := JsonS2CEnvelope{
+		Push: p.IsPushPacket(),
+		Ask:  p.IsAskPacket(),
+		Meta: md,
+		Body: body,
+		Bin:  bin,
+	}
+	if envelope.Ask {
+		envelope.Svc = p.ServiceId()
+		envelope.Router = p.RouterId()
+		envelope.ReqId = p.ReqId()
+	} else if envelope.Push {
+		envelope.Svc = p.ServiceId()
+		envelope.Router = p.RouterId()
+	} else {
+		envelope.ReqId = p.ReqId()
+	}
+	return json.Marshal(envelope)
+}
@@ -0,0 +1,74 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestJsonToC2SPacket(t *testing.T) {
+	packet, err := JsonToC2SPacket([]byte(`{"svc":1,"router":2,"reqId":3,"meta":{"k":"v"},"body":{"msg":"hi"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	md, err := packet.Metadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if packet.ServiceId() != 1 || packet.RouterId() != 2 || packet.ReqId() != 3 || packet.IsOneWay() || md["k"] != "v" {
+		t.Fatalf("unexpected packet %d %d %d %v", packet.ServiceId(), packet.RouterId(), packet.ReqId(), md)
+	}
+	if string(packet.Body()) != `{"msg":"hi"}` {
+		t.Fatalf("unexpected body %s", packet.Body())
+	}
+
+	packet, err = JsonToC2SPacket([]byte(`{"heartbeat":true}`))
+	if err != nil || !packet.IsHeartbeatPacket() {
+		t.Fatalf("heartbeat %v", err)
+	}
+
+	packet, err = JsonToC2SPacket([]byte(`{"rsp":true,"reqId":9}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !packet.IsRspPacket() || packet.ReqId() != 9 || string(packet.Body()) != "null" {
+		t.Fatalf("unexpected rsp %d %s", packet.ReqId(), packet.Body())
+	}
+
+	_, err = JsonToC2SPacket([]byte(`not json`))
+	if err == nil {
+		t.Fatal("invalid json should fail")
+	}
+}
+
+func TestS2CPacketToJson(t *testing.T) {
+	cases := []struct {
+		packet S2CPacket
+		want   JsonS2CEnvelope
+	}{
+		{NewS2CRspPacket(5, []byte(`{"a":1}`)), JsonS2CEnvelope{ReqId: 5, Body: json.RawMessage(`{"a":1}`)}},
+		{NewS2CPushPacket(1, 2, []byte(`"x"`)), JsonS2CEnvelope{Push: true, Svc: 1, Router: 2, Body: json.RawMessage(`"x"`)}},
+		{NewS2CAskPacket(1, 2, 3, []byte(`[]`)), JsonS2CEnvelope{Ask: true, Svc: 1, Router: 2, ReqId: 3, Body: json.RawMessage(`[]`)}},
+		// 非json消息体放到bin
+		{NewS2CPushPacket(SysServiceId, 7, []byte{0, 1, 0xff}), JsonS2CEnvelope{Push: true, Svc: SysServiceId, Router: 7, Body: jsonNull, Bin: []byte{0, 1, 0xff}}},
+	}
+	for i, c := range cases {
+		parsed, err := BytesToS2CPacket(c.packet.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		text, err := S2CPacketToJson(parsed)
+		if err != nil {
+			t.Fatalf("case %d %v", i, err)
+		}
+		var got JsonS2CEnvelope
+		err = json.Unmarshal(text, &got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Push != c.want.Push || got.Ask != c.want.Ask || got.Svc != c.want.Svc || got.Router != c.want.Router ||
+			got.ReqId != c.want.ReqId || !bytes.Equal(got.Body, c.want.Body) || !bytes.Equal(got.Bin, c.want.Bin) {
+			t.Fatalf("case %d unexpected json %s", i, text)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package codec
+
+import (
+	"context"
+	"encoding/binary"
+	"maps"
+	"strconv"
+	"time"
+)
+
+// 常用的元数据键
+const (
+	MetaKeyTraceId       = "trace-id"
+	MetaKeyClientVersion = "client-version"
+	// MetaKeyTimeout 剩余超时毫秒数，用相对值避免两端时钟不一致
+	MetaKeyTimeout = "timeout"
+)
+
+// MaxMetadataLen 元数据段长度用2字节表示
+const MaxMetadataLen = 1<<16 - 1
+
+// Metadata 请求和响应附带的键值头，编码为 metaLen(2) [keyLen(1) key valLen(2) val]...
+type Metadata map[string]string
+
+func (md Metadata) Get(key string) (string, bool) {
+	v, ok := md[key]
+	return v, ok
+}
+
+func (md Metadata) Set(key string, value string) {
+	md[key] = value
+}
+
+func (md Metadata) Clone() Metadata {
+	return maps.Clone(md)
+}
+
+// Timeout 解析请求方的超时，没有或格式错误时返回false
+func (md Metadata) Timeout() (time.Duration, bool) {
+	v, ok := md[MetaKeyTimeout]
+	if !ok {
+		return 0, false
+	}
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || ms < 0 {
+		return 0, false
+	}
+	return time.Duration(ms) * time.Millisecond, true
+}
+
+func (md Metadata) SetTimeout(timeout time.Duration) {
+	md[MetaKeyTimeout] = strconv.FormatInt(timeout.Milliseconds(), 10)
+}
+
+// appendMetadata 在包头后追加元数据段，key超过255或总长超过上限时返回错误
+func appendMetadata(dst []byte, md Metadata) ([]byte, error) {
+	start := len(dst)
+	dst = append(dst, 0, 0)
+	for k, v := range md {
+		if len(k) > 0xff || len(v) > 0xffff {
+			return dst[:start], MetadataTooLargeErr
+		}
+		dst = append(dst, byte(len(k)))
+		dst = append(dst, k...)
+		dst = binary.BigEndian.AppendUint16(dst, uint16(len(v)))
+		dst = append(dst, v...)
+	}
+	metaLen := len(dst) - start - 2
+	if metaLen > MaxMetadataLen {
+		return dst[:start], MetadataTooLargeErr
+	}
+	binary.BigEndian.PutUint16(dst[start:start+2], uint16(metaLen))
+	return dst, nil
+}
+
+// metadataLen 返回元数据段总长度(含长度头)
+func metadataLen(bytes []byte, offset int) (int, bool) {
+	if len(bytes) < offset+2 {
+		return 0, false
+	}
+	metaLen := int(binary.BigEndian.Uint16(bytes[offset : offset+2]))
+	if len(bytes) < offset+2+metaLen {
+		return 0, false
+	}
+	return 2 + metaLen, true
+}
+
+func decodeMetadata(bytes []byte) (Metadata, error) {
+	md := make(Metadata)
+	for len(bytes) > 0 {
+		keyLen := int(bytes[0])
+		if len(bytes) < 1+keyLen+2 {
+			return nil, PacketBytesErr
+		}
+		key := string(bytes[1 : 1+keyLen])
+		bytes = bytes[1+keyLen:]
+		valLen := int(binary.BigEndian.Uint16(bytes[:2]))
+		if len(bytes) < 2+valLen {
+			return nil, PacketBytesErr
+		}
+		md[key] = string(bytes[2 : 2+valLen])
+		bytes = bytes[2+valLen:]
+	}
+	return md, nil
+}
+
+type outgoingMetaKey struct{}
+
+// NewOutgoingContext 客户端请求附带的元数据，截止时间会转成超时一起发送
+func NewOutgoingContext(ctx context.Context, md Metadata) context.Context {
+	return context.WithValue(ctx, outgoingMetaKey{}, md)
+}
+
+func OutgoingMetadata(ctx context.Context) (Metadata, bool) {
+	md, ok := ctx.Value(outgoingMetaKey{}).(Metadata)
+	return md, ok
+}
+
+// MetadataFromContext 合并上下文中的元数据和截止时间，都没有时返回nil
+func MetadataFromContext(ctx context.Context) Metadata {
+	md, _ := OutgoingMetadata(ctx)
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return md
+	}
+	md = md.Clone()
+	if md == nil {
+		md = make(Metadata)
+	}
+	md.SetTimeout(max(time.Until(deadline), 0))
+	return md
+}
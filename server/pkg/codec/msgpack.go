@@ -0,0 +1,92 @@
+package codec
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// MsgpackSerializer 基于反射的msgpack，适合没有proto定义的普通结构体
+// 字段名取msgpack标签，没有时使用StructTag指定的标签，比如复用proto生成代码的json标签
+type MsgpackSerializer struct {
+	StructTag string
+}
+
+// appendWriter 实现msgpack内部的writer接口，编码结果直接追加到切片
+type appendWriter struct {
+	b []byte
+}
+
+func (w *appendWriter) Write(p []byte) (int, error) {
+	w.b = append(w.b, p...)
+	return len(p), nil
+}
+
+func (w *appendWriter) WriteByte(c byte) error {
+	w.b = append(w.b, c)
+	return nil
+}
+
+func (w *appendWriter) WriteString(s string) (int, error) {
+	w.b = append(w.b, s...)
+	return len(s), nil
+}
+
+type msgpackEncoder struct {
+	enc *msgpack.Encoder
+	w   appendWriter
+}
+
+type msgpackDecoder struct {
+	dec *msgpack.Decoder
+	r   bytes.Reader
+}
+
+var msgpackEncoderPool = sync.Pool{
+	New: func() any {
+		return &msgpackEncoder{enc: msgpack.NewEncoder(nil)}
+	},
+}
+
+var msgpackDecoderPool = sync.Pool{
+	New: func() any {
+		return &msgpackDecoder{dec: msgpack.NewDecoder(nil)}
+	},
+}
+
+func (m MsgpackSerializer) Marshal(v any) ([]byte, error) {
+	return m.MarshalAppend(nil, v)
+}
+
+func (m MsgpackSerializer) MarshalAppend(dst []byte, v any) ([]byte, error) {
+	e := msgpackEncoderPool.Get().(*msgpackEncoder)
+	e.w.b = dst
+	e.enc.Reset(&e.w)
+	if m.StructTag != "" {
+		e.enc.SetCustomStructTag(m.StructTag)
+	}
+	err := e.enc.Encode(v)
+	bytes := e.w.b
+	e.w.b = nil
+	e.enc.Reset(nil)
+	msgpackEncoderPool.Put(e)
+	if err != nil {
+		return dst, err
+	}
+	return bytes, nil
+}
+
+func (m MsgpackSerializer) Unmarshal(data []byte, v any) error {
+	d := msgpackDecoderPool.Get().(*msgpackDecoder)
+	d.r.Reset(data)
+	d.dec.Reset(&d.r)
+	if m.StructTag != "" {
+		d.dec.SetCustomStructTag(m.StructTag)
+	}
+	err := d.dec.Decode(v)
+	d.r.Reset(nil)
+	d.dec.Reset(nil)
+	msgpackDecoderPool.Put(d)
+	return err
+}
@@ -0,0 +1,45 @@
+package codec
+
+import "testing"
+
+type msgpackItem struct {
+	Id   uint32 `msgpack:"id" json:"item_id"`
+	Name string `json:"item_name"`
+	Tags []string
+}
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	for _, serializer := range []MsgpackSerializer{{}, {StructTag: "json"}} {
+		in := msgpackItem{Id: 7, Name: "sword", Tags: []string{"a", "b"}}
+		data, err := serializer.MarshalAppend([]byte{0xff}, &in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data[0] != 0xff {
+			t.Fatalf("append overwrote dst")
+		}
+		var out msgpackItem
+		err = serializer.Unmarshal(data[1:], &out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out.Id != in.Id || out.Name != in.Name || len(out.Tags) != 2 {
+			t.Fatalf("tag %q round trip mismatch %+v", serializer.StructTag, out)
+		}
+	}
+}
+
+func TestMsgpackStructTag(t *testing.T) {
+	var m map[string]any
+	data, _ := MsgpackSerializer{StructTag: "json"}.Marshal(&msgpackItem{Id: 1})
+	err := MsgpackSerializer{}.Unmarshal(data, &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m["id"]; !ok {
+		t.Fatalf("msgpack tag should win %v", m)
+	}
+	if _, ok := m["item_name"]; !ok {
+		t.Fatalf("json tag not used as fallback %v", m)
+	}
+}
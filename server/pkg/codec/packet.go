@@ -3,15 +3,27 @@ package codec
 import (
 	"encoding/binary"
 	"hutool/bitx"
+	"hutool/bytex"
+	"slices"
 )
 
 const (
 	HeartbeatBitPos = 1
 	IsOneWayBitPos  = 2
+	// IsRspPacketBitPos 客户端对服务端请求的响应
+	IsRspPacketBitPos = 3
+	// C2SHasMetaBitPos 固定包头后跟随元数据段
+	C2SHasMetaBitPos = 4
+	// C2SReservedBits 保留给以后的标记，目前收到直接拒绝
+	C2SReservedBits byte = 0b11100001
 )
 
 const (
 	IsPushPacketBitPost = 1
+	// IsAskPacketBitPos 服务端向客户端发起的请求
+	IsAskPacketBitPos      = 2
+	S2CHasMetaBitPos       = 3
+	S2CReservedBits   byte = 0b11110001
 )
 
 type S2CPacket struct {
@@ -19,26 +31,29 @@ type S2CPacket struct {
 }
 
 func NewS2CRspPacket(reqId uint32, body []byte) S2CPacket {
-	var head byte = 0
-	bitx.SetBit(&head, IsPushPacketBitPost, false)
-	bytes := make([]byte, len(body)+5)
-	bytes[0] = head
-
-	binary.BigEndian.PutUint32(bytes[1:5], reqId)
-	copy(bytes[5:], body)
-	return S2CPacket{bytes: bytes}
+	bytes := AppendS2CRspHead(make([]byte, 0, len(body)+S2CRspHeadLen), reqId)
+	return S2CPacket{bytes: append(bytes, body...)}
 }
 
 func NewS2CPushPacket(serviceId uint32, routerId uint32, body []byte) S2CPacket {
-	var head byte = 0
-	bitx.SetBit(&head, IsPushPacketBitPost, true)
-	bytes := make([]byte, len(body)+9)
-	bytes[0] = head
+	bytes := AppendS2CPushHead(make([]byte, 0, len(body)+S2CPushHeadLen), serviceId, routerId)
+	return S2CPacket{bytes: append(bytes, body...)}
+}
 
-	binary.BigEndian.PutUint32(bytes[1:5], serviceId)
-	binary.BigEndian.PutUint32(bytes[5:9], routerId)
-	copy(bytes[9:], body)
-	return S2CPacket{bytes: bytes}
+// NewS2CAskPacket 服务端请求: svc(4) router(4) reqId(4) body
+func NewS2CAskPacket(serviceId uint32, routerId uint32, reqId uint32, body []byte) S2CPacket {
+	bytes := AppendS2CAskHead(make([]byte, 0, len(body)+S2CAskHeadLen), serviceId, routerId, reqId)
+	return S2CPacket{bytes: append(bytes, body...)}
+}
+
+// NewS2CRspPacketWithMeta 响应附带元数据，没有元数据时和NewS2CRspPacket相同
+func NewS2CRspPacketWithMeta(reqId uint32, md Metadata, body []byte) (S2CPacket, error) {
+	bytes := AppendS2CRspHead(make([]byte, 0, len(body)+S2CRspHeadLen), reqId)
+	bytes, err := AppendS2CMetadata(bytes, md)
+	if err != nil {
+		return S2CPacket{}, err
+	}
+	return S2CPacket{bytes: append(bytes, body...)}, nil
 }
 
 func BytesToS2CPacket(bytes []byte) (S2CPacket, error) {
@@ -47,12 +62,15 @@ func BytesToS2CPacket(bytes []byte) (S2CPacket, error) {
 		return p, PacketBytesErr
 	}
 	head := bytes[0]
-	if bitx.IsBitSet(head, IsPushPacketBitPost) {
-		if len(bytes) < 9 {
-			return p, PacketBytesErr
-		}
-	} else {
-		if len(bytes) < 5 {
+	if head&S2CReservedBits != 0 {
+		return p, PacketReservedBitsErr
+	}
+	headLen := s2cHeadLen(head)
+	if len(bytes) < headLen {
+		return p, PacketBytesErr
+	}
+	if bitx.IsBitSet(head, S2CHasMetaBitPos) {
+		if _, ok := metadataLen(bytes, headLen); !ok {
 			return p, PacketBytesErr
 		}
 	}
@@ -61,6 +79,39 @@ func BytesToS2CPacket(bytes []byte) (S2CPacket, error) {
 	return p, nil
 }
 
+func s2cHeadLen(head byte) int {
+	if bitx.IsBitSet(head, IsAskPacketBitPos) {
+		return S2CAskHeadLen
+	}
+	if bitx.IsBitSet(head, IsPushPacketBitPost) {
+		return S2CPushHeadLen
+	}
+	return S2CRspHeadLen
+}
+
+func (p S2CPacket) HasMetadata() bool {
+	return bitx.IsBitSet(p.bytes[0], S2CHasMetaBitPos)
+}
+
+// Metadata 没有元数据时返回nil
+func (p S2CPacket) Metadata() (Metadata, error) {
+	if !p.HasMetadata() {
+		return nil, nil
+	}
+	headLen := s2cHeadLen(p.bytes[0])
+	metaLen, _ := metadataLen(p.bytes, headLen)
+	return decodeMetadata(p.bytes[headLen+2 : headLen+metaLen])
+}
+
+func (p S2CPacket) bodyOffset() int {
+	headLen := s2cHeadLen(p.bytes[0])
+	if p.HasMetadata() {
+		metaLen, _ := metadataLen(p.bytes, headLen)
+		return headLen + metaLen
+	}
+	return headLen
+}
+
 func (p S2CPacket) IsPushPacket() bool {
 	return bitx.IsBitSet(p.bytes[0], IsPushPacketBitPost)
 }
@@ -73,23 +124,29 @@ func (p S2CPacket) RouterId() uint32 {
 	return binary.BigEndian.Uint32(p.bytes[5:9])
 }
 
+func (p S2CPacket) IsAskPacket() bool {
+	return bitx.IsBitSet(p.bytes[0], IsAskPacketBitPos)
+}
+
 func (p S2CPacket) ReqId() uint32 {
+	if p.IsAskPacket() {
+		return binary.BigEndian.Uint32(p.bytes[9:13])
+	}
 	return binary.BigEndian.Uint32(p.bytes[1:5])
 }
 
 func (p S2CPacket) Body() []byte {
-	if p.IsPushPacket() {
-		return p.bytes[9:]
-	}
-	return p.bytes[5:]
+	return p.bytes[p.bodyOffset():]
 }
 
 func (p S2CPacket) Bytes() []byte {
 	return p.bytes
 }
 
+// C2SPacket 从缓冲解析时和缓冲共享内存，回调返回后继续使用需要Retain
 type C2SPacket struct {
 	bytes []byte
+	buf   *bytex.Buffer
 }
 
 func NewC2SHeartBeatPacket() *C2SPacket {
@@ -100,16 +157,24 @@ func NewC2SHeartBeatPacket() *C2SPacket {
 }
 
 func NewC2SReqPacket(serviceId uint32, routerId uint32, reqId uint32, isOneWay bool, body []byte) C2SPacket {
-	var head byte = 0
-	bitx.SetBit(&head, IsOneWayBitPos, isOneWay)
-	bitx.SetBit(&head, HeartbeatBitPos, false)
-	bytes := make([]byte, 13)
-	bytes[0] = head
-	binary.BigEndian.PutUint32(bytes[1:5], serviceId)
-	binary.BigEndian.PutUint32(bytes[5:9], routerId)
-	binary.BigEndian.PutUint32(bytes[9:13], reqId)
-	bytes = append(bytes, body...)
-	return C2SPacket{bytes: bytes}
+	bytes := AppendC2SReqHead(make([]byte, 0, len(body)+C2SReqHeadLen), serviceId, routerId, reqId, isOneWay)
+	return C2SPacket{bytes: append(bytes, body...)}
+}
+
+// NewC2SRspPacket 响应服务端请求: reqId(4) body
+func NewC2SRspPacket(reqId uint32, body []byte) C2SPacket {
+	bytes := AppendC2SRspHead(make([]byte, 0, len(body)+C2SRspHeadLen), reqId)
+	return C2SPacket{bytes: append(bytes, body...)}
+}
+
+// NewC2SReqPacketWithMeta 请求附带元数据，没有元数据时和NewC2SReqPacket相同
+func NewC2SReqPacketWithMeta(serviceId uint32, routerId uint32, reqId uint32, isOneWay bool, md Metadata, body []byte) (C2SPacket, error) {
+	bytes := AppendC2SReqHead(make([]byte, 0, len(body)+C2SReqHeadLen), serviceId, routerId, reqId, isOneWay)
+	bytes, err := AppendC2SMetadata(bytes, md)
+	if err != nil {
+		return C2SPacket{}, err
+	}
+	return C2SPacket{bytes: append(bytes, body...)}, nil
 }
 
 func BytesToC2SPacket(bytes []byte) (C2SPacket, error) {
@@ -118,12 +183,22 @@ func BytesToC2SPacket(bytes []byte) (C2SPacket, error) {
 		return p, PacketBytesErr
 	}
 	head := bytes[0]
+	if head&C2SReservedBits != 0 {
+		return p, PacketReservedBitsErr
+	}
 	if bitx.IsBitSet(head, HeartbeatBitPos) {
-		if len(bytes) != 1 {
+		if len(bytes) != 1 || bitx.IsBitSet(head, C2SHasMetaBitPos) {
 			return p, PacketBytesErr
 		}
-	} else {
-		if len(bytes) < 13 {
+		p.bytes = bytes
+		return p, nil
+	}
+	headLen := c2sHeadLen(head)
+	if len(bytes) < headLen {
+		return p, PacketBytesErr
+	}
+	if bitx.IsBitSet(head, C2SHasMetaBitPos) {
+		if _, ok := metadataLen(bytes, headLen); !ok {
 			return p, PacketBytesErr
 		}
 	}
@@ -132,6 +207,61 @@ func BytesToC2SPacket(bytes []byte) (C2SPacket, error) {
 	return p, nil
 }
 
+func c2sHeadLen(head byte) int {
+	if bitx.IsBitSet(head, IsRspPacketBitPos) {
+		return C2SRspHeadLen
+	}
+	return C2SReqHeadLen
+}
+
+func (p C2SPacket) HasMetadata() bool {
+	return bitx.IsBitSet(p.bytes[0], C2SHasMetaBitPos)
+}
+
+// Metadata 没有元数据时返回nil
+func (p C2SPacket) Metadata() (Metadata, error) {
+	if !p.HasMetadata() {
+		return nil, nil
+	}
+	headLen := c2sHeadLen(p.bytes[0])
+	metaLen, _ := metadataLen(p.bytes, headLen)
+	return decodeMetadata(p.bytes[headLen+2 : headLen+metaLen])
+}
+
+func (p C2SPacket) bodyOffset() int {
+	headLen := c2sHeadLen(p.bytes[0])
+	if p.HasMetadata() {
+		metaLen, _ := metadataLen(p.bytes, headLen)
+		return headLen + metaLen
+	}
+	return headLen
+}
+
+// BufferToC2SPacket 包和缓冲共享内存，不增加引用
+func BufferToC2SPacket(buf *bytex.Buffer) (C2SPacket, error) {
+	p, err := BytesToC2SPacket(buf.Bytes())
+	if err != nil {
+		return p, err
+	}
+	p.buf = buf
+	return p, nil
+}
+
+// Retain 跨协程持有包时调用，不是池化内存的包会复制一份
+func (p C2SPacket) Retain() C2SPacket {
+	if p.buf != nil {
+		p.buf.Retain()
+		return p
+	}
+	return C2SPacket{bytes: slices.Clone(p.bytes)}
+}
+
+func (p C2SPacket) Release() {
+	if p.buf != nil {
+		p.buf.Release()
+	}
+}
+
 func (p C2SPacket) IsHeartbeatPacket() bool {
 	return bitx.IsBitSet(p.bytes[0], HeartbeatBitPos)
 }
@@ -148,12 +278,19 @@ func (p C2SPacket) RouterId() uint32 {
 	return binary.BigEndian.Uint32(p.bytes[5:9])
 }
 
+func (p C2SPacket) IsRspPacket() bool {
+	return bitx.IsBitSet(p.bytes[0], IsRspPacketBitPos)
+}
+
 func (p C2SPacket) ReqId() uint32 {
+	if p.IsRspPacket() {
+		return binary.BigEndian.Uint32(p.bytes[1:5])
+	}
 	return binary.BigEndian.Uint32(p.bytes[9:13])
 }
 
 func (p C2SPacket) Body() []byte {
-	return p.bytes[13:]
+	return p.bytes[p.bodyOffset():]
 }
 
 func (p C2SPacket) Bytes() []byte {
@@ -0,0 +1,83 @@
+package codec
+
+import (
+	"hutool/bytex"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+var benchBody = make([]byte, 128)
+
+func BenchmarkNewS2CRspPacket(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = NewS2CRspPacket(uint32(i), benchBody)
+	}
+}
+
+func BenchmarkAppendS2CRspPacketPooled(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf := bytex.AllocateBuffer(256)
+		buf.SetBytes(append(AppendS2CRspHead(buf.Bytes()[:0], uint32(i)), benchBody...))
+		buf.Release()
+	}
+}
+
+func BenchmarkBytesToC2SPacketCopy(b *testing.B) {
+	data := NewC2SReqPacket(1, 2, 3, false, benchBody).Bytes()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		// 改造前读循环每个包都要新分配
+		readBytes := make([]byte, len(data))
+		copy(readBytes, data)
+		p, err := BytesToC2SPacket(readBytes)
+		if err != nil || len(p.Body()) != len(benchBody) {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBufferToC2SPacket(b *testing.B) {
+	data := NewC2SReqPacket(1, 2, 3, false, benchBody).Bytes()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf := bytex.AllocateBuffer(len(data))
+		copy(buf.Bytes(), data)
+		p, err := BufferToC2SPacket(buf)
+		if err != nil || len(p.Body()) != len(benchBody) {
+			b.Fatal(err)
+		}
+		p.Retain().Release()
+		buf.Release()
+	}
+}
+
+func BenchmarkProtoRspMarshal(b *testing.B) {
+	msg := wrapperspb.Bytes(benchBody)
+	serializer := ProtoSerializer{}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		body, err := serializer.Marshal(msg)
+		if err != nil {
+			b.Fatal(err)
+		}
+		_ = NewS2CRspPacket(uint32(i), body)
+	}
+}
+
+func BenchmarkProtoRspMarshalAppend(b *testing.B) {
+	msg := wrapperspb.Bytes(benchBody)
+	serializer := ProtoSerializer{}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf := bytex.AllocateBuffer(256)
+		bytes, err := MarshalAppend(serializer, AppendS2CRspHead(buf.Bytes()[:0], uint32(i)), msg)
+		if err != nil {
+			b.Fatal(err)
+		}
+		buf.SetBytes(bytes)
+		buf.Release()
+	}
+}
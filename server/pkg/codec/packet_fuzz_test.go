@@ -0,0 +1,121 @@
+package codec
+
+import (
+	"bytes"
+	"maps"
+	"testing"
+)
+
+func FuzzBytesToC2SPacket(f *testing.F) {
+	f.Add(NewC2SHeartBeatPacket().Bytes())
+	f.Add(NewC2SReqPacket(1, 2, 3, false, []byte("body")).Bytes())
+	f.Add(NewC2SReqPacket(1, 2, 3, true, nil).Bytes())
+	f.Add(NewC2SRspPacket(7, []byte("rsp")).Bytes())
+	withMeta, _ := NewC2SReqPacketWithMeta(1, 2, 3, false, Metadata{MetaKeyTraceId: "t1"}, []byte("body"))
+	f.Add(withMeta.Bytes())
+	f.Add([]byte{0xff})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		p, err := BytesToC2SPacket(data)
+		if err != nil {
+			return
+		}
+		if data[0]&C2SReservedBits != 0 {
+			t.Fatalf("reserved bits accepted %08b", data[0])
+		}
+		if p.IsHeartbeatPacket() {
+			return
+		}
+		body := p.Body()
+		if p.HasMetadata() {
+			checkC2SMetadata(t, p)
+			return
+		}
+		if p.IsRspPacket() {
+			again := NewC2SRspPacket(p.ReqId(), body)
+			if !bytes.Equal(again.Body(), body) || again.ReqId() != p.ReqId() {
+				t.Fatalf("rsp packet round trip mismatch")
+			}
+			return
+		}
+		again := NewC2SReqPacket(p.ServiceId(), p.RouterId(), p.ReqId(), p.IsOneWay(), body)
+		if !bytes.Equal(again.Bytes(), data) {
+			t.Fatalf("req packet round trip mismatch %x %x", again.Bytes(), data)
+		}
+	})
+}
+
+// checkC2SMetadata 元数据是map，重新编码后顺序可能不同，只比较解析结果
+func checkC2SMetadata(t *testing.T, p C2SPacket) {
+	md, err := p.Metadata()
+	if err != nil || p.IsRspPacket() {
+		return
+	}
+	again, err := NewC2SReqPacketWithMeta(p.ServiceId(), p.RouterId(), p.ReqId(), p.IsOneWay(), md, p.Body())
+	if err != nil {
+		return
+	}
+	parsed, err := BytesToC2SPacket(again.Bytes())
+	if err != nil {
+		t.Fatalf("re-encoded packet rejected %+v", err)
+	}
+	againMd, err := parsed.Metadata()
+	if err != nil || !maps.Equal(md, againMd) || !bytes.Equal(parsed.Body(), p.Body()) {
+		t.Fatalf("metadata round trip mismatch %v %v", md, againMd)
+	}
+}
+
+func FuzzBytesToS2CPacket(f *testing.F) {
+	f.Add(NewS2CRspPacket(3, []byte("body")).Bytes())
+	f.Add(NewS2CPushPacket(1, 2, []byte("push")).Bytes())
+	f.Add(NewS2CAskPacket(1, 2, 3, nil).Bytes())
+	withMeta, _ := NewS2CRspPacketWithMeta(3, Metadata{"server-node": "n1"}, []byte("body"))
+	f.Add(withMeta.Bytes())
+	f.Add([]byte{0xff, 0, 0, 0, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		p, err := BytesToS2CPacket(data)
+		if err != nil {
+			return
+		}
+		if data[0]&S2CReservedBits != 0 {
+			t.Fatalf("reserved bits accepted %08b", data[0])
+		}
+		body := p.Body()
+		if p.HasMetadata() {
+			// 元数据解析失败不能影响包体的定位
+			_, _ = p.Metadata()
+			if len(body) > len(data) {
+				t.Fatalf("body out of range")
+			}
+			return
+		}
+		var again S2CPacket
+		switch {
+		case p.IsAskPacket():
+			again = NewS2CAskPacket(p.ServiceId(), p.RouterId(), p.ReqId(), body)
+		case p.IsPushPacket():
+			again = NewS2CPushPacket(p.ServiceId(), p.RouterId(), body)
+		default:
+			again = NewS2CRspPacket(p.ReqId(), body)
+		}
+		if !bytes.Equal(again.Bytes()[1:], data[1:]) {
+			t.Fatalf("packet round trip mismatch %x %x", again.Bytes(), data)
+		}
+	})
+}
+
+func FuzzDecodeHandshake(f *testing.F) {
+	f.Add(Handshake{Version: ProtocolVersion, Serializer: SerializerIdProto, MaxPacketLen: MaxPacketLen, Caps: CapAll}.Encode())
+	f.Add(HandshakeRsp{Code: HandshakeOk}.Encode())
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if hs, err := DecodeHandshake(data); err == nil {
+			if !bytes.Equal(hs.Encode(), data) {
+				t.Fatalf("handshake round trip mismatch")
+			}
+		}
+		if rsp, err := DecodeHandshakeRsp(data); err == nil {
+			if !bytes.Equal(rsp.Encode(), data) {
+				t.Fatalf("handshake rsp round trip mismatch")
+			}
+		}
+	})
+}
@@ -0,0 +1,93 @@
+package codec
+
+import (
+	"reflect"
+	"sync"
+)
+
+type routeKey struct {
+	serviceId uint32
+	routerId  uint32
+}
+
+// SerializerRegistry 服务端和客户端共用，握手时按id选择连接的序列化，路由可以单独指定
+type SerializerRegistry struct {
+	mu          sync.RWMutex
+	serializers map[uint8]ISerializer
+	routes      map[routeKey]uint8
+}
+
+func NewSerializerRegistry() *SerializerRegistry {
+	return &SerializerRegistry{
+		serializers: make(map[uint8]ISerializer),
+		routes:      make(map[routeKey]uint8),
+	}
+}
+
+// DefaultSerializerRegistry 内置proto、json和msgpack
+func DefaultSerializerRegistry() *SerializerRegistry {
+	r := NewSerializerRegistry()
+	r.Register(SerializerIdProto, ProtoSerializer{})
+	r.Register(SerializerIdJson, JsonSerializer{})
+	r.Register(SerializerIdMsgpack, MsgpackSerializer{})
+	return r
+}
+
+// Register 自定义序列化建议使用128以上的id，0保留给未知序列化
+func (r *SerializerRegistry) Register(id uint8, serializer ISerializer) {
+	if id == SerializerIdUnknown {
+		panic("codec: serializer id 0 is reserved")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.serializers[id] = serializer
+}
+
+func (r *SerializerRegistry) Get(id uint8) (ISerializer, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	serializer, ok := r.serializers[id]
+	return serializer, ok
+}
+
+// IdOf 按类型查找注册的id，没有注册时返回Unknown
+func (r *SerializerRegistry) IdOf(serializer ISerializer) uint8 {
+	if id := SerializerIdOf(serializer); id != SerializerIdUnknown {
+		return id
+	}
+	t := reflect.TypeOf(serializer)
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for id, s := range r.serializers {
+		if reflect.TypeOf(s) == t {
+			return id
+		}
+	}
+	return SerializerIdUnknown
+}
+
+// BindRoute 路由固定使用某个序列化，不跟随连接协商的结果
+func (r *SerializerRegistry) BindRoute(serviceId uint32, routerId uint32, id uint8) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes[routeKey{serviceId: serviceId, routerId: routerId}] = id
+}
+
+func (r *SerializerRegistry) ForRoute(serviceId uint32, routerId uint32) (ISerializer, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	id, ok := r.routes[routeKey{serviceId: serviceId, routerId: routerId}]
+	if !ok {
+		return nil, false
+	}
+	serializer, ok := r.serializers[id]
+	return serializer, ok
+}
+
+// RouteSerializerId 路由绑定的序列化id，没有绑定时返回Unknown
+func (r *SerializerRegistry) RouteSerializerId(serviceId uint32, routerId uint32) (uint8, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	id, ok := r.routes[routeKey{serviceId: serviceId, routerId: routerId}]
+	return id, ok
+}
@@ -11,6 +11,23 @@ type ISerializer interface {
 	Unmarshal(data []byte, v any) error
 }
 
+// IAppendSerializer 支持序列化到已有缓冲
+type IAppendSerializer interface {
+	MarshalAppend(dst []byte, v any) ([]byte, error)
+}
+
+// MarshalAppend 序列化后追加到dst，不支持追加的序列化会多一次复制
+func MarshalAppend(serializer ISerializer, dst []byte, v any) ([]byte, error) {
+	if appender, ok := serializer.(IAppendSerializer); ok {
+		return appender.MarshalAppend(dst, v)
+	}
+	bytes, err := serializer.Marshal(v)
+	if err != nil {
+		return dst, err
+	}
+	return append(dst, bytes...), nil
+}
+
 type ProtoSerializer struct {
 }
 
@@ -22,6 +39,14 @@ func (p ProtoSerializer) Marshal(v any) ([]byte, error) {
 	return proto.Marshal(pbV)
 }
 
+func (p ProtoSerializer) MarshalAppend(dst []byte, v any) ([]byte, error) {
+	pbV, ok := v.(proto.Message)
+	if !ok {
+		return dst, TypeNotSupportErr
+	}
+	return proto.MarshalOptions{}.MarshalAppend(dst, pbV)
+}
+
 func (p ProtoSerializer) Unmarshal(data []byte, v any) error {
 	pbV, ok := v.(proto.Message)
 	if !ok {
@@ -0,0 +1,72 @@
+package codec
+
+import (
+	"server/app/test"
+	"strings"
+	"testing"
+)
+
+var benchSerializers = []struct {
+	name       string
+	serializer ISerializer
+}{
+	{"proto", ProtoSerializer{}},
+	{"json", JsonSerializer{}},
+	{"msgpack", MsgpackSerializer{}},
+}
+
+func benchHelloAsk() *test.HelloAsk {
+	return &test.HelloAsk{Msg: strings.Repeat("hello", 20)}
+}
+
+func BenchmarkSerializerMarshal(b *testing.B) {
+	msg := benchHelloAsk()
+	for _, bs := range benchSerializers {
+		b.Run(bs.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, err := bs.serializer.Marshal(msg)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSerializerMarshalAppend(b *testing.B) {
+	msg := benchHelloAsk()
+	for _, bs := range benchSerializers {
+		b.Run(bs.name, func(b *testing.B) {
+			dst := make([]byte, 0, 256)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, err := MarshalAppend(bs.serializer, dst[:0], msg)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSerializerUnmarshal(b *testing.B) {
+	msg := benchHelloAsk()
+	for _, bs := range benchSerializers {
+		data, err := bs.serializer.Marshal(msg)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(bs.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				out := &test.HelloAsk{}
+				err := bs.serializer.Unmarshal(data, out)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
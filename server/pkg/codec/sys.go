@@ -0,0 +1,38 @@
+package codec
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// SysServiceId 框架内部使用的服务id，业务服务不能占用
+const SysServiceId = uint32(math.MaxUint32)
+
+const (
+	SysRouterUdpBind = uint32(1)
+	SysRouterKick    = uint32(2)
+)
+
+type KickReason uint32
+
+const (
+	KickReasonUnknown KickReason = iota
+	// KickReasonDuplicateLogin 同一用户在别处登录
+	KickReasonDuplicateLogin
+	KickReasonAdmin
+)
+
+// EncodeKick 踢下线推送: reason(4) msg
+func EncodeKick(reason KickReason, msg string) []byte {
+	bytes := make([]byte, 4+len(msg))
+	binary.BigEndian.PutUint32(bytes[0:4], uint32(reason))
+	copy(bytes[4:], msg)
+	return bytes
+}
+
+func DecodeKick(bytes []byte) (KickReason, string, error) {
+	if len(bytes) < 4 {
+		return 0, "", PacketBytesErr
+	}
+	return KickReason(binary.BigEndian.Uint32(bytes[0:4])), string(bytes[4:]), nil
+}
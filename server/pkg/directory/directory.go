@@ -0,0 +1,253 @@
+package directory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hutool/logx"
+	"server/pkg/codec"
+	"server/pkg/service"
+	session2 "server/pkg/session"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Location 用户会话所在的节点和连接，Serializer是连接协商的序列化id，转发时按它编码
+type Location struct {
+	NodeId     string
+	ConnId     uint32
+	Serializer uint8
+}
+
+func (l Location) member() string {
+	return l.NodeId + "|" + strconv.FormatUint(uint64(l.ConnId), 10) + "|" + strconv.FormatUint(uint64(l.Serializer), 10)
+}
+
+func parseLocation(member string) (Location, error) {
+	idx := strings.LastIndexByte(member, '|')
+	if idx < 0 {
+		return Location{}, LocationBadErr
+	}
+	serializer, err := strconv.ParseUint(member[idx+1:], 10, 8)
+	if err != nil {
+		return Location{}, LocationBadErr
+	}
+	member = member[:idx]
+	idx = strings.LastIndexByte(member, '|')
+	if idx < 0 {
+		return Location{}, LocationBadErr
+	}
+	connId, err := strconv.ParseUint(member[idx+1:], 10, 32)
+	if err != nil {
+		return Location{}, LocationBadErr
+	}
+	return Location{NodeId: member[:idx], ConnId: uint32(connId), Serializer: uint8(serializer)}, nil
+}
+
+// record 会话上记录的注册信息，会话被顶号后身份已经解绑，仍然可以清理
+type record struct {
+	key       string
+	member    string
+	refreshAt time.Time
+}
+
+var recordKey = session2.NewKey[*record]("directory.record")
+
+type forwardMsg struct {
+	ConnId   uint32 `json:"connId"`
+	RouterId uint32 `json:"routerId"`
+	Body     []byte `json:"body"`
+}
+
+// Directory 基于redis的会话目录，作为service插件使用
+// 每个用户一个有序集合，成员是 节点|连接id，分数是过期时间，支持多端登录
+type Directory struct {
+	rc     redis.UniversalClient
+	nodeId string
+	cfg    *Config
+	svc    *service.Service
+	pubsub *redis.PubSub
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewDirectory(rc redis.UniversalClient, nodeId string, opts ...Option) *Directory {
+	cfg := DefaultConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Directory{
+		rc:     rc,
+		nodeId: nodeId,
+		cfg:    cfg,
+		ctx:    ctx,
+		cancel: cancel,
+		wg:     sync.WaitGroup{},
+	}
+}
+
+// Start 订阅本节点的转发频道，需要在service创建后调用
+func (d *Directory) Start(svc *service.Service) error {
+	d.svc = svc
+	d.pubsub = d.rc.Subscribe(d.ctx, d.cfg.channelPrefix+d.nodeId)
+	_, err := d.pubsub.Receive(d.ctx)
+	if err != nil {
+		return err
+	}
+	d.wg.Add(1)
+	go d.receive()
+	return nil
+}
+
+func (d *Directory) PostUserBind(session *session2.Session, identity any) {
+	serializer := codec.SerializerIdUnknown
+	if d.svc != nil {
+		serializer = d.svc.SerializerRegistry().IdOf(d.svc.SessionSerializer(session))
+	}
+	loc := Location{NodeId: d.nodeId, ConnId: session.GetConnId(), Serializer: serializer}
+	r := &record{
+		key:       d.userKey(identity),
+		member:    loc.member(),
+		refreshAt: time.Now(),
+	}
+	recordKey.Set(session, r)
+	err := d.refresh(r)
+	if err != nil {
+		logx.Errorf("directory bind %v err %+v", identity, err)
+	}
+}
+
+func (d *Directory) HeartBeat(session *session2.Session) {
+	r, ok := recordKey.Get(session)
+	if !ok || time.Since(r.refreshAt) < d.cfg.refreshInterval {
+		return
+	}
+	r.refreshAt = time.Now()
+	err := d.refresh(r)
+	if err != nil {
+		logx.Errorf("directory refresh %s err %+v", r.key, err)
+	}
+}
+
+func (d *Directory) SessionEnd(session *session2.Session) {
+	r, ok := recordKey.Get(session)
+	if !ok {
+		return
+	}
+	err := d.rc.ZRem(context.Background(), r.key, r.member).Err()
+	if err != nil {
+		logx.Errorf("directory remove %s err %+v", r.key, err)
+	}
+}
+
+// PostSvcStop 连接都关闭之后再停止订阅
+func (d *Directory) PostSvcStop(svc *service.Service) {
+	d.cancel()
+	if d.pubsub != nil {
+		_ = d.pubsub.Close()
+	}
+	d.wg.Wait()
+}
+
+// Lookup 查找用户所有在线的会话
+func (d *Directory) Lookup(ctx context.Context, identity any) ([]Location, error) {
+	key := d.userKey(identity)
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	members, err := d.rc.ZRangeByScore(ctx, key, &redis.ZRangeBy{Min: "(" + now, Max: "+inf"}).Result()
+	if err != nil {
+		return nil, err
+	}
+	locs := make([]Location, 0, len(members))
+	for _, member := range members {
+		loc, err := parseLocation(member)
+		if err != nil {
+			logx.Errorf("directory parse %s err %+v", member, err)
+			continue
+		}
+		locs = append(locs, loc)
+	}
+	return locs, nil
+}
+
+// PushUser 推送给用户所有在线的会话，其它节点上的会话通过频道转发
+// 每个连接按自己的序列化编码，相同序列化的远端连接共用一次编码
+func (d *Directory) PushUser(ctx context.Context, identity any, routerId uint32, data any) error {
+	if d.svc == nil {
+		return NotStartedErr
+	}
+	locs, err := d.Lookup(ctx, identity)
+	if err != nil {
+		return err
+	}
+	if len(locs) == 0 {
+		return UserOfflineErr
+	}
+	bodies := make(map[uint8][]byte)
+	for _, loc := range locs {
+		if loc.NodeId == d.nodeId {
+			err = d.svc.Push(loc.ConnId, routerId, data)
+		} else {
+			err = d.forwardData(ctx, loc, routerId, data, bodies)
+		}
+		if err != nil {
+			logx.Errorf("directory push %v to %+v err %+v", identity, loc, err)
+		}
+	}
+	return nil
+}
+
+func (d *Directory) forwardData(ctx context.Context, loc Location, routerId uint32, data any, bodies map[uint8][]byte) error {
+	body, ok := bodies[loc.Serializer]
+	if !ok {
+		var err error
+		body, err = d.svc.SerializerById(routerId, loc.Serializer).Marshal(data)
+		if err != nil {
+			return err
+		}
+		bodies[loc.Serializer] = body
+	}
+	return d.forward(ctx, loc, routerId, body)
+}
+
+func (d *Directory) forward(ctx context.Context, loc Location, routerId uint32, body []byte) error {
+	msg, err := json.Marshal(forwardMsg{ConnId: loc.ConnId, RouterId: routerId, Body: body})
+	if err != nil {
+		return err
+	}
+	return d.rc.Publish(ctx, d.cfg.channelPrefix+loc.NodeId, msg).Err()
+}
+
+func (d *Directory) receive() {
+	defer d.wg.Done()
+	for msg := range d.pubsub.Channel() {
+		fm := forwardMsg{}
+		err := json.Unmarshal([]byte(msg.Payload), &fm)
+		if err != nil {
+			logx.Errorf("directory forward msg err %+v", err)
+			continue
+		}
+		_ = d.svc.PushRaw(fm.ConnId, fm.RouterId, fm.Body)
+	}
+}
+
+// refresh 续期并顺带清理过期的成员
+func (d *Directory) refresh(r *record) error {
+	ctx := context.Background()
+	now := time.Now()
+	pipe := d.rc.TxPipeline()
+	pipe.ZAdd(ctx, r.key, redis.Z{Score: float64(now.Add(d.cfg.ttl).UnixMilli()), Member: r.member})
+	pipe.ZRemRangeByScore(ctx, r.key, "-inf", strconv.FormatInt(now.UnixMilli(), 10))
+	pipe.PExpire(ctx, r.key, d.cfg.ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (d *Directory) userKey(identity any) string {
+	return d.cfg.keyPrefix + fmt.Sprint(identity)
+}
@@ -0,0 +1,134 @@
+package directory
+
+import (
+	"context"
+	"net"
+	"server/app/test"
+	"server/pkg/codec"
+	"server/pkg/net/tcp"
+	router2 "server/pkg/router"
+	"server/pkg/service"
+	session2 "server/pkg/session"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+const pushRouterId = uint32(100)
+
+// startNode 监听随机端口，通过TCPAddr取实际端口
+func startNode(t *testing.T, rc redis.UniversalClient, nodeId string) (*service.Service, *Directory) {
+	dir := NewDirectory(rc, nodeId)
+	router := router2.NewRouter()
+	var svc *service.Service
+	router2.RegisterAskRouter[*test.HelloAsk, *test.HelloRsp](router, uint32(test.RouterId_Hello), func(ctx codec.ReqCtx, req *test.HelloAsk) *test.HelloRsp {
+		err := svc.BindUser(ctx.GetSession(), req.Msg)
+		if err != nil {
+			t.Errorf("bind user err %+v", err)
+		}
+		return &test.HelloRsp{Msg: req.Msg}
+	})
+	svc = service.NewService(0, service.WithRouter(router), service.WithPlugin(dir),
+		service.WithSessionOpts(session2.WithLoginPolicy(session2.LoginAllowMulti)))
+	err := dir.Start(svc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = svc.StartTCPServer("127.0.0.1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(svc.Stop)
+	return svc, dir
+}
+
+func TestCrossNodePush(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rc := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	svcA, dirA := startNode(t, rc, "a")
+	_, dirB := startNode(t, rc, "b")
+	portA := svcA.TCPAddr().(*net.TCPAddr).Port
+	time.Sleep(100 * time.Millisecond)
+
+	cl := tcp.NewClient(codec.ProtoSerializer{}, time.Second)
+	err := cl.Dial("127.0.0.1", portA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pushes, _ := tcp.Subscribe[*test.HelloRsp](cl, 0, pushRouterId)
+	logged := make(chan struct{})
+	err = tcp.Ask[*test.HelloAsk, *test.HelloRsp](cl, 0, uint32(test.RouterId_Hello), &test.HelloAsk{Msg: "u1"}, func(rsp *test.HelloRsp) {
+		close(logged)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-logged
+
+	// 另一端协商了json，推送要按各自连接的序列化编码
+	jsonCl := tcp.NewClient(codec.JsonSerializer{}, time.Second)
+	err = jsonCl.Dial("127.0.0.1", portA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer jsonCl.Close()
+	jsonPushes, _ := tcp.Subscribe[*test.HelloRsp](jsonCl, 0, pushRouterId)
+	jsonLogged := make(chan struct{})
+	err = tcp.Ask[*test.HelloAsk, *test.HelloRsp](jsonCl, 0, uint32(test.RouterId_Hello), &test.HelloAsk{Msg: "u1"}, func(rsp *test.HelloRsp) {
+		close(jsonLogged)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-jsonLogged
+
+	ctx := context.Background()
+	locs, err := dirB.Lookup(ctx, "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locs) != 2 || locs[0].NodeId != "a" || locs[1].NodeId != "a" {
+		t.Fatalf("unexpected locations %+v", locs)
+	}
+
+	for _, dir := range []*Directory{dirB, dirA} {
+		msg := "from " + dir.nodeId
+		err = dir.PushUser(ctx, "u1", pushRouterId, &test.HelloRsp{Msg: msg})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, ch := range []<-chan *test.HelloRsp{pushes, jsonPushes} {
+			select {
+			case push := <-ch:
+				if push.Msg != msg {
+					t.Fatalf("unexpected push %s", push.Msg)
+				}
+			case <-time.After(3 * time.Second):
+				t.Fatalf("push %s not received", msg)
+			}
+		}
+	}
+
+	if err = dirA.PushUser(ctx, "u2", pushRouterId, &test.HelloRsp{}); err != UserOfflineErr {
+		t.Fatalf("expected offline, got %+v", err)
+	}
+
+	cl.Close()
+	jsonCl.Close()
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		locs, err = dirB.Lookup(ctx, "u1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(locs) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("location not removed after session end %+v", locs)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
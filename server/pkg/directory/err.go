@@ -0,0 +1,9 @@
+package directory
+
+import "errors"
+
+var (
+	NotStartedErr  = errors.New("directory not started")
+	UserOfflineErr = errors.New("user offline")
+	LocationBadErr = errors.New("location format error")
+)
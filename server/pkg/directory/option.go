@@ -0,0 +1,47 @@
+package directory
+
+import "time"
+
+type Config struct {
+	keyPrefix     string
+	channelPrefix string
+	ttl           time.Duration
+	// 心跳刷新间隔，避免每次心跳都写redis
+	refreshInterval time.Duration
+}
+
+type Option func(*Config)
+
+func WithKeyPrefix(prefix string) Option {
+	return func(c *Config) {
+		c.keyPrefix = prefix
+	}
+}
+
+func WithChannelPrefix(prefix string) Option {
+	return func(c *Config) {
+		c.channelPrefix = prefix
+	}
+}
+
+// WithTTL 节点宕机后记录的最长保留时间
+func WithTTL(ttl time.Duration) Option {
+	return func(c *Config) {
+		c.ttl = ttl
+	}
+}
+
+func WithRefreshInterval(interval time.Duration) Option {
+	return func(c *Config) {
+		c.refreshInterval = interval
+	}
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		keyPrefix:       "session:user:",
+		channelPrefix:   "session:node:",
+		ttl:             30 * time.Second,
+		refreshInterval: 10 * time.Second,
+	}
+}
@@ -0,0 +1,470 @@
+package inspect
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"server/pkg/codec"
+	"server/pkg/net/udp"
+	zip2 "server/pkg/zip"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// MsgKind 包在协议中的角色
+type MsgKind uint8
+
+const (
+	// KindUnknown 包头解析失败
+	KindUnknown MsgKind = iota
+	KindHeartbeat
+	// KindReq 客户端请求，tell也按req绑定类型
+	KindReq
+	KindTell
+	KindRsp
+	KindPush
+	KindAsk
+	// KindAskRsp 客户端对服务端请求的响应
+	KindAskRsp
+)
+
+var kindNames = map[MsgKind]string{
+	KindUnknown:   "unknown",
+	KindHeartbeat: "heartbeat",
+	KindReq:       "req",
+	KindTell:      "tell",
+	KindRsp:       "rsp",
+	KindPush:      "push",
+	KindAsk:       "ask",
+	KindAskRsp:    "askrsp",
+}
+
+func (k MsgKind) String() string {
+	return kindNames[k]
+}
+
+func ParseKind(s string) (MsgKind, error) {
+	for kind, name := range kindNames {
+		if name == s && kind != KindUnknown {
+			return kind, nil
+		}
+	}
+	return 0, KindErr
+}
+
+type route struct {
+	svc    uint32
+	router uint32
+}
+
+type typeKey struct {
+	kind MsgKind
+	route
+}
+
+// Decoder 解析包头和消息体，按连接记录请求的路由，用来解析只带reqId的响应
+type Decoder struct {
+	files *protoregistry.Files
+	types map[typeKey]protoreflect.MessageType
+	// 连接上等待响应的请求，key为Frame.Stream
+	reqs map[string]map[uint32]route
+	asks map[string]map[uint32]route
+	// zips 握手响应协商的压缩算法，key为Frame.Stream
+	zips map[string]uint8
+}
+
+// Packet 解析结果，包头解析失败时Kind为KindUnknown，只有Frame有效
+type Packet struct {
+	Frame
+	Kind      MsgKind
+	Flags     []string
+	ServiceId uint32
+	RouterId  uint32
+	ReqId     uint32
+	// Matched 响应找到了对应的请求，ServiceId和RouterId来自请求
+	Matched bool
+	Meta    codec.Metadata
+	Body    []byte
+	// TypeName 解析消息体使用的类型，json或未知的proto为空
+	TypeName string
+	Text     string
+	Err      error
+}
+
+func NewDecoder() *Decoder {
+	return &Decoder{
+		types: map[typeKey]protoreflect.MessageType{},
+		reqs:  map[string]map[uint32]route{},
+		asks:  map[string]map[uint32]route{},
+		zips:  map[string]uint8{},
+	}
+}
+
+// LoadDescriptors 加载protoc --descriptor_set_out生成的描述文件，--include_imports可以省去依赖
+func (d *Decoder) LoadDescriptors(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	set := &descriptorpb.FileDescriptorSet{}
+	err = proto.Unmarshal(data, set)
+	if err != nil {
+		return err
+	}
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return err
+	}
+	d.files = files
+	return nil
+}
+
+// Bind 路由上某种包的消息类型，先在描述文件中查找，再查找编译进程序的类型
+func (d *Decoder) Bind(kind MsgKind, svc uint32, router uint32, fullName string) error {
+	mt, err := d.findType(protoreflect.FullName(fullName))
+	if err != nil {
+		return err
+	}
+	d.types[typeKey{kind: kind, route: route{svc: svc, router: router}}] = mt
+	return nil
+}
+
+func (d *Decoder) findType(name protoreflect.FullName) (protoreflect.MessageType, error) {
+	if d.files != nil {
+		desc, err := d.files.FindDescriptorByName(name)
+		if err == nil {
+			if md, ok := desc.(protoreflect.MessageDescriptor); ok {
+				return dynamicpb.NewMessageType(md), nil
+			}
+		}
+	}
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", TypeNotFoundErr, name)
+	}
+	return mt, nil
+}
+
+func (d *Decoder) Decode(frame Frame) Packet {
+	p := Packet{Frame: frame}
+	data := frame.Data
+	if frame.Dir == DirS2C {
+		var err error
+		data, err = d.unzip(&p, data)
+		if err != nil {
+			p.Err = err
+			return p
+		}
+	}
+	if frame.Dir == DirC2S {
+		d.decodeC2S(&p, data)
+	} else {
+		d.decodeS2C(&p, data)
+	}
+	return p
+}
+
+// unzip 按连接握手协商的压缩算法整包解压，没有握手的老连接按gzip魔数判断
+func (d *Decoder) unzip(p *Packet, data []byte) ([]byte, error) {
+	zipId, ok := d.zips[p.Stream]
+	if !ok {
+		if len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b {
+			zipId = zip2.IdGzip
+		} else {
+			zipId = zip2.IdNone
+		}
+	}
+	switch zipId {
+	case zip2.IdNone:
+		return data, nil
+	case zip2.IdGzip:
+		p.Flags = append(p.Flags, "gzip")
+		return zip2.GZIP{}.Unzip(data)
+	default:
+		return nil, fmt.Errorf("%w %d", UnknownCompressionErr, zipId)
+	}
+}
+
+func (d *Decoder) decodeC2S(p *Packet, data []byte) {
+	packet, err := codec.BytesToC2SPacket(data)
+	if err != nil {
+		p.Err = err
+		return
+	}
+	if packet.IsHeartbeatPacket() {
+		p.Kind = KindHeartbeat
+		return
+	}
+	p.ReqId = packet.ReqId()
+	if packet.IsRspPacket() {
+		p.Kind = KindAskRsp
+		r, ok := takePending(d.asks, p.Stream, p.ReqId)
+		p.Matched = ok
+		p.ServiceId, p.RouterId = r.svc, r.router
+	} else {
+		p.Kind = KindReq
+		p.ServiceId, p.RouterId = packet.ServiceId(), packet.RouterId()
+		if packet.IsOneWay() {
+			p.Kind = KindTell
+			p.Flags = append(p.Flags, "oneway")
+		} else {
+			putPending(d.reqs, p.Stream, p.ReqId, route{svc: p.ServiceId, router: p.RouterId})
+		}
+	}
+	if packet.HasMetadata() {
+		p.Flags = append(p.Flags, "meta")
+	}
+	p.Meta, p.Err = packet.Metadata()
+	p.Body = packet.Body()
+	d.decodeBody(p)
+}
+
+func (d *Decoder) decodeS2C(p *Packet, data []byte) {
+	packet, err := codec.BytesToS2CPacket(data)
+	if err != nil {
+		p.Err = err
+		return
+	}
+	switch {
+	case packet.IsAskPacket():
+		p.Kind = KindAsk
+		p.ServiceId, p.RouterId, p.ReqId = packet.ServiceId(), packet.RouterId(), packet.ReqId()
+		putPending(d.asks, p.Stream, p.ReqId, route{svc: p.ServiceId, router: p.RouterId})
+	case packet.IsPushPacket():
+		p.Kind = KindPush
+		p.ServiceId, p.RouterId = packet.ServiceId(), packet.RouterId()
+	default:
+		p.Kind = KindRsp
+		p.ReqId = packet.ReqId()
+		r, ok := takePending(d.reqs, p.Stream, p.ReqId)
+		p.Matched = ok
+		p.ServiceId, p.RouterId = r.svc, r.router
+	}
+	if packet.HasMetadata() {
+		p.Flags = append(p.Flags, "meta")
+	}
+	p.Meta, p.Err = packet.Metadata()
+	p.Body = packet.Body()
+	if p.Kind == KindRsp && p.Matched && p.ServiceId == codec.SysServiceId && p.RouterId == codec.SysRouterHandshake {
+		// 之后的下行包按协商结果解压
+		if rsp, err := codec.DecodeHandshakeRsp(p.Body); err == nil && rsp.Code == codec.HandshakeOk {
+			d.zips[p.Stream] = rsp.Compression
+		}
+	}
+	d.decodeBody(p)
+}
+
+func putPending(pending map[string]map[uint32]route, stream string, reqId uint32, r route) {
+	reqs, ok := pending[stream]
+	if !ok {
+		reqs = map[uint32]route{}
+		pending[stream] = reqs
+	}
+	reqs[reqId] = r
+}
+
+func takePending(pending map[string]map[uint32]route, stream string, reqId uint32) (route, bool) {
+	r, ok := pending[stream][reqId]
+	delete(pending[stream], reqId)
+	return r, ok
+}
+
+// decodeBody 依次尝试系统消息、绑定的类型、json和proto wire格式，都不是时输出十六进制
+func (d *Decoder) decodeBody(p *Packet) {
+	if len(p.Body) == 0 {
+		return
+	}
+	if p.ServiceId == codec.SysServiceId && (p.Kind != KindRsp || p.Matched) {
+		if text, ok := decodeSys(p); ok {
+			p.TypeName, p.Text = "sys", text
+			return
+		}
+	}
+	kind := p.Kind
+	if kind == KindTell {
+		kind = KindReq
+	}
+	if mt, ok := d.types[typeKey{kind: kind, route: route{svc: p.ServiceId, router: p.RouterId}}]; ok && (p.Kind != KindRsp && p.Kind != KindAskRsp || p.Matched) {
+		msg := mt.New().Interface()
+		err := proto.Unmarshal(p.Body, msg)
+		if err == nil {
+			text, err := protojson.Marshal(msg)
+			if err == nil {
+				p.TypeName, p.Text = string(mt.Descriptor().FullName()), string(text)
+				return
+			}
+		}
+	}
+	if json.Valid(p.Body) {
+		buf := &bytes.Buffer{}
+		if json.Compact(buf, p.Body) == nil {
+			p.Text = buf.String()
+			return
+		}
+	}
+	if text, ok := formatWire(p.Body, 0); ok {
+		p.Text = text
+		return
+	}
+	p.Text = hex.EncodeToString(p.Body)
+}
+
+func decodeSys(p *Packet) (string, bool) {
+	switch {
+	case p.RouterId == codec.SysRouterHandshake && p.Kind == KindReq:
+		hs, err := codec.DecodeHandshake(p.Body)
+		return fmt.Sprintf("handshake %+v", hs), err == nil
+	case p.RouterId == codec.SysRouterHandshake && p.Kind == KindRsp:
+		rsp, err := codec.DecodeHandshakeRsp(p.Body)
+		return fmt.Sprintf("handshake rsp %+v", rsp), err == nil
+	case p.RouterId == codec.SysRouterKick && p.Kind == KindPush:
+		reason, msg, err := codec.DecodeKick(p.Body)
+		return fmt.Sprintf("kick reason=%d msg=%q", reason, msg), err == nil
+	case p.RouterId == codec.SysRouterUdpBind && p.Kind == KindPush:
+		connId, token, err := udp.DecodeBind(p.Body)
+		return fmt.Sprintf("udp bind connId=%d token=%d", connId, token), err == nil
+	default:
+		return "", false
+	}
+}
+
+// maxWireDepth 限制嵌套解析，避免把普通字节误判成多层消息
+const maxWireDepth = 8
+
+// formatWire 没有类型时按proto wire格式输出字段号和值
+func formatWire(b []byte, depth int) (string, bool) {
+	if depth > maxWireDepth || len(b) == 0 {
+		return "", false
+	}
+	sb := strings.Builder{}
+	sb.WriteString("{")
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 || num <= 0 {
+			return "", false
+		}
+		b = b[n:]
+		if sb.Len() > 1 {
+			sb.WriteString(" ")
+		}
+		fmt.Fprintf(&sb, "%d:", num)
+		switch typ {
+		case protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return "", false
+			}
+			fmt.Fprint(&sb, v)
+			b = b[n:]
+		case protowire.Fixed32Type:
+			v, n := protowire.ConsumeFixed32(b)
+			if n < 0 {
+				return "", false
+			}
+			fmt.Fprint(&sb, v)
+			b = b[n:]
+		case protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(b)
+			if n < 0 {
+				return "", false
+			}
+			fmt.Fprint(&sb, v)
+			b = b[n:]
+		case protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return "", false
+			}
+			sb.WriteString(formatBytes(v, depth))
+			b = b[n:]
+		default:
+			return "", false
+		}
+	}
+	sb.WriteString("}")
+	return sb.String(), true
+}
+
+// formatBytes 可打印的按字符串输出，否则尝试按嵌套消息解析
+func formatBytes(v []byte, depth int) string {
+	if utf8.Valid(v) && isPrintable(string(v)) {
+		return fmt.Sprintf("%q", v)
+	}
+	if text, ok := formatWire(v, depth+1); ok {
+		return text
+	}
+	return hex.EncodeToString(v)
+}
+
+func isPrintable(s string) bool {
+	for _, r := range s {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p Packet) String() string {
+	sb := strings.Builder{}
+	sb.WriteString(p.Dir.String())
+	if !p.Time.IsZero() {
+		sb.WriteString(" ")
+		sb.WriteString(p.Time.Format("15:04:05.000000"))
+	}
+	if p.Stream != "" {
+		sb.WriteString(" ")
+		sb.WriteString(p.Stream)
+	}
+	if p.Kind == KindUnknown {
+		fmt.Fprintf(&sb, " err %v len=%d\n  raw %x\n", p.Err, len(p.Data), p.Data)
+		return sb.String()
+	}
+	sb.WriteString(" ")
+	sb.WriteString(p.Kind.String())
+	switch p.Kind {
+	case KindHeartbeat:
+	case KindRsp, KindAskRsp:
+		fmt.Fprintf(&sb, " reqId=%d", p.ReqId)
+		if p.Matched {
+			fmt.Fprintf(&sb, " svc=%d router=%d", p.ServiceId, p.RouterId)
+		}
+	case KindPush:
+		fmt.Fprintf(&sb, " svc=%d router=%d", p.ServiceId, p.RouterId)
+	default:
+		fmt.Fprintf(&sb, " svc=%d router=%d reqId=%d", p.ServiceId, p.RouterId, p.ReqId)
+	}
+	fmt.Fprintf(&sb, " len=%d", len(p.Data))
+	if len(p.Flags) > 0 {
+		fmt.Fprintf(&sb, " flags=%s", strings.Join(p.Flags, ","))
+	}
+	sb.WriteString("\n")
+	if p.Err != nil {
+		fmt.Fprintf(&sb, "  err %v\n", p.Err)
+	}
+	if len(p.Meta) > 0 {
+		fmt.Fprintf(&sb, "  meta %v\n", map[string]string(p.Meta))
+	}
+	if p.Text != "" {
+		sb.WriteString("  body ")
+		if p.TypeName != "" {
+			sb.WriteString(p.TypeName)
+			sb.WriteString(" ")
+		}
+		sb.WriteString(p.Text)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
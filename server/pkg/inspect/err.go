@@ -0,0 +1,16 @@
+package inspect
+
+import "errors"
+
+var (
+	FrameLenErr = errors.New("frame length invalid")
+	HexErr      = errors.New("invalid hex dump")
+	// PcapFormatErr 只支持经典pcap格式，pcapng需要先用editcap转换
+	PcapFormatErr = errors.New("unsupported pcap format")
+	// PcapTruncatedErr 最后一条记录不完整，之前还原的包仍然可用
+	PcapTruncatedErr = errors.New("pcap truncated")
+	TypeNotFoundErr  = errors.New("message type not found")
+	KindErr          = errors.New("unknown message kind")
+	// UnknownCompressionErr 握手协商的压缩算法无法解压，例如服务端的自定义压缩
+	UnknownCompressionErr = errors.New("unknown compression")
+)
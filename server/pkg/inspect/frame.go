@@ -0,0 +1,82 @@
+package inspect
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+type Dir uint8
+
+const (
+	DirC2S Dir = iota + 1
+	DirS2C
+)
+
+func (d Dir) String() string {
+	switch d {
+	case DirC2S:
+		return "c2s"
+	case DirS2C:
+		return "s2c"
+	default:
+		return "unknown"
+	}
+}
+
+// maxFrameLen 只用于识别错位的流，比传输层的限制宽松
+const maxFrameLen = 1 << 24
+
+// Frame 一个完整的包，不含长度头
+type Frame struct {
+	Dir Dir
+	// Time 十六进制输入没有时间
+	Time time.Time
+	// Stream 区分连接，请求和响应按连接匹配
+	Stream string
+	Data   []byte
+}
+
+// SplitFrames 按4字节长度头切分tcp字节流，返回剩余的不完整数据
+func SplitFrames(data []byte) ([][]byte, []byte, error) {
+	var frames [][]byte
+	for len(data) >= 4 {
+		frameLen := binary.BigEndian.Uint32(data[:4])
+		if frameLen == 0 || frameLen > maxFrameLen {
+			return frames, data, FrameLenErr
+		}
+		if uint32(len(data)-4) < frameLen {
+			break
+		}
+		frames = append(frames, data[4:4+frameLen])
+		data = data[4+frameLen:]
+	}
+	return frames, data, nil
+}
+
+// ParseHex 支持纯十六进制和xxd -p的输出，忽略空白、0x前缀和#开头的注释行
+func ParseHex(text []byte) ([]byte, error) {
+	sb := strings.Builder{}
+	scanner := bufio.NewScanner(bytes.NewReader(text))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxFrameLen)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, field := range strings.Fields(line) {
+			sb.WriteString(strings.TrimPrefix(strings.TrimPrefix(field, "0x"), "0X"))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	data, err := hex.DecodeString(sb.String())
+	if err != nil {
+		return nil, HexErr
+	}
+	return data, nil
+}
@@ -0,0 +1,192 @@
+package inspect
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"server/app/test"
+	"server/pkg/codec"
+	zip2 "server/pkg/zip"
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+)
+
+func lengthPrefixed(packets ...[]byte) []byte {
+	var stream []byte
+	for _, packet := range packets {
+		stream = binary.BigEndian.AppendUint32(stream, uint32(len(packet)))
+		stream = append(stream, packet...)
+	}
+	return stream
+}
+
+// tcpRecord 以太网+ipv4+tcp，不计算校验和
+func tcpRecord(srcPort uint16, dstPort uint16, seq uint32, flags byte, payload []byte) []byte {
+	tcp := make([]byte, 20)
+	binary.BigEndian.PutUint16(tcp[0:2], srcPort)
+	binary.BigEndian.PutUint16(tcp[2:4], dstPort)
+	binary.BigEndian.PutUint32(tcp[4:8], seq)
+	tcp[12] = 5 << 4
+	tcp[13] = flags
+	tcp = append(tcp, payload...)
+
+	ip := make([]byte, 20)
+	ip[0] = 0x45
+	binary.BigEndian.PutUint16(ip[2:4], uint16(20+len(tcp)))
+	ip[9] = 6
+	copy(ip[12:16], []byte{127, 0, 0, 1})
+	copy(ip[16:20], []byte{127, 0, 0, 1})
+	ip = append(ip, tcp...)
+
+	eth := make([]byte, 14)
+	binary.BigEndian.PutUint16(eth[12:14], 0x0800)
+	data := append(eth, ip...)
+
+	record := make([]byte, 16)
+	binary.LittleEndian.PutUint32(record[8:12], uint32(len(data)))
+	binary.LittleEndian.PutUint32(record[12:16], uint32(len(data)))
+	return append(record, data...)
+}
+
+func TestReadPcap(t *testing.T) {
+	body, _ := proto.Marshal(&test.HelloAsk{Msg: "hi"})
+	req := codec.NewC2SReqPacket(0, uint32(test.RouterId_Hello), 7, false, body)
+	rsp := codec.NewS2CRspPacket(7, []byte(`{"msg": "ok"}`))
+	c2s := lengthPrefixed(codec.NewC2SHeartBeatPacket().Bytes(), req.Bytes())
+	s2c := lengthPrefixed(rsp.Bytes())
+
+	pcap := make([]byte, 24)
+	binary.LittleEndian.PutUint32(pcap[0:4], 0xa1b2c3d4)
+	binary.LittleEndian.PutUint32(pcap[20:24], linkEthernet)
+	pcap = append(pcap, tcpRecord(5000, 8080, 99, tcpFlagSyn, nil)...)
+	// 请求跨两个分段，第一个分段重传一次
+	pcap = append(pcap, tcpRecord(5000, 8080, 100, 0, c2s[:8])...)
+	pcap = append(pcap, tcpRecord(5000, 8080, 100, 0, c2s[:8])...)
+	pcap = append(pcap, tcpRecord(5000, 8080, 108, 0, c2s[8:])...)
+	pcap = append(pcap, tcpRecord(8080, 5000, 1, 0, s2c)...)
+
+	frames, err := ReadPcap(bytes.NewReader(pcap), 8080)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != 3 || frames[0].Dir != DirC2S || frames[2].Dir != DirS2C || frames[1].Stream != "127.0.0.1:5000" {
+		t.Fatalf("unexpected frames %+v", frames)
+	}
+
+	decoder := NewDecoder()
+	err = decoder.Bind(KindReq, 0, uint32(test.RouterId_Hello), "test.HelloAsk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := decoder.Decode(frames[0]); p.Kind != KindHeartbeat {
+		t.Fatalf("unexpected heartbeat %+v", p)
+	}
+	p := decoder.Decode(frames[1])
+	if p.Kind != KindReq || p.ReqId != 7 || p.TypeName != "test.HelloAsk" || !strings.Contains(p.Text, `"hi"`) {
+		t.Fatalf("unexpected req %s", p)
+	}
+	p = decoder.Decode(frames[2])
+	if p.Kind != KindRsp || !p.Matched || p.RouterId != uint32(test.RouterId_Hello) || p.Text != `{"msg":"ok"}` {
+		t.Fatalf("unexpected rsp %s", p)
+	}
+}
+
+func TestReadPcapBroken(t *testing.T) {
+	s2c := lengthPrefixed(codec.NewS2CRspPacket(7, []byte(`{}`)).Bytes())
+	pcap := make([]byte, 24)
+	binary.LittleEndian.PutUint32(pcap[0:4], 0xa1b2c3d4)
+	binary.LittleEndian.PutUint32(pcap[16:20], 1500)
+	binary.LittleEndian.PutUint32(pcap[20:24], linkEthernet)
+	pcap = append(pcap, tcpRecord(8080, 5000, 1, 0, s2c)...)
+
+	// 抓包中断，最后一条记录不完整
+	truncated := append(pcap[:len(pcap):len(pcap)], tcpRecord(8080, 5000, 1+uint32(len(s2c)), 0, s2c)[:20]...)
+	frames, err := ReadPcap(bytes.NewReader(truncated), 8080)
+	if !errors.Is(err, PcapTruncatedErr) || len(frames) != 1 {
+		t.Fatalf("unexpected truncated read %d %v", len(frames), err)
+	}
+
+	// 记录长度超过snaplen
+	oversized := make([]byte, 16)
+	binary.LittleEndian.PutUint32(oversized[8:12], 1<<30)
+	frames, err = ReadPcap(bytes.NewReader(append(pcap, oversized...)), 8080)
+	if !errors.Is(err, PcapFormatErr) || len(frames) != 1 {
+		t.Fatalf("unexpected oversized read %d %v", len(frames), err)
+	}
+}
+
+func TestHalfStreamFarSeq(t *testing.T) {
+	s2c := lengthPrefixed(codec.NewS2CRspPacket(7, []byte(`{}`)).Bytes())
+	h := &halfStream{next: 100, started: true, buf: []byte{0, 0}}
+	packets := h.feed(segment{seq: 100 + 0x80000000, payload: s2c})
+	if len(packets) != 1 {
+		t.Fatalf("unexpected packets %d", len(packets))
+	}
+}
+
+func TestDecodeHex(t *testing.T) {
+	errRsp, err := codec.NewS2CErrRspPacket(3, codec.ErrCodeMaintenance, "down")
+	if err != nil {
+		t.Fatal(err)
+	}
+	push := codec.NewS2CPushPacket(0, 9, []byte{0x0a, 0x02, 'o', 'k', 0x10, 0x05})
+	stream := hex.EncodeToString(lengthPrefixed(errRsp.Bytes(), push.Bytes()))
+	dump := "# s2c\n0x" + strings.ToUpper(stream[:20]) + "\n" + stream[20:]
+	data, err := ParseHex([]byte(dump))
+	if err != nil {
+		t.Fatal(err)
+	}
+	packets, rest, err := SplitFrames(data)
+	if err != nil || len(rest) != 0 || len(packets) != 2 {
+		t.Fatalf("unexpected split %d %d %v", len(packets), len(rest), err)
+	}
+	decoder := NewDecoder()
+	p := decoder.Decode(Frame{Dir: DirS2C, Data: packets[0]})
+	if p.Kind != KindRsp || p.Meta.Err() == nil || p.Meta.Err().Code != codec.ErrCodeMaintenance {
+		t.Fatalf("unexpected err rsp %s", p)
+	}
+	p = decoder.Decode(Frame{Dir: DirS2C, Data: packets[1]})
+	if p.Kind != KindPush || p.Text != `{1:"ok" 2:5}` {
+		t.Fatalf("unexpected push %s", p)
+	}
+}
+
+func TestDecodeNegotiatedZip(t *testing.T) {
+	handshake := func(decoder *Decoder, stream string, zipId uint8) {
+		hs := codec.Handshake{Version: codec.ProtocolVersion, Compression: zipId, MaxPacketLen: codec.MaxPacketLen}
+		req := codec.NewC2SReqPacket(codec.SysServiceId, codec.SysRouterHandshake, 1, false, hs.Encode())
+		decoder.Decode(Frame{Dir: DirC2S, Stream: stream, Data: req.Bytes()})
+		rsp := codec.NewS2CRspPacket(1, codec.HandshakeRsp{Code: codec.HandshakeOk, Handshake: hs}.Encode())
+		p := decoder.Decode(Frame{Dir: DirS2C, Stream: stream, Data: rsp.Bytes()})
+		if p.TypeName != "sys" || p.Err != nil {
+			t.Fatalf("unexpected handshake rsp %s", p)
+		}
+	}
+	push := codec.NewS2CPushPacket(0, 9, []byte(`{"ok":true}`))
+	zipped, err := zip2.GZIP{}.Zip(push.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoder := NewDecoder()
+	handshake(decoder, "gzip", zip2.IdGzip)
+	p := decoder.Decode(Frame{Dir: DirS2C, Stream: "gzip", Data: zipped})
+	if p.Kind != KindPush || p.Text != `{"ok":true}` || !slices.Contains(p.Flags, "gzip") {
+		t.Fatalf("unexpected gzip push %s", p)
+	}
+	// 其它连接的协商结果互不影响
+	p = decoder.Decode(Frame{Dir: DirS2C, Stream: "other", Data: push.Bytes()})
+	if p.Kind != KindPush || slices.Contains(p.Flags, "gzip") {
+		t.Fatalf("unexpected plain push %s", p)
+	}
+
+	handshake(decoder, "custom", zip2.IdCustom)
+	p = decoder.Decode(Frame{Dir: DirS2C, Stream: "custom", Data: push.Bytes()})
+	if p.Kind != KindUnknown || !errors.Is(p.Err, UnknownCompressionErr) {
+		t.Fatalf("unexpected custom push %s", p)
+	}
+}
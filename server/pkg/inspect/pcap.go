@@ -0,0 +1,252 @@
+package inspect
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"slices"
+	"time"
+)
+
+// 链路层类型
+const (
+	linkNull     = 0
+	linkEthernet = 1
+	linkRaw      = 101
+	linkSll      = 113
+	linkSll2     = 276
+)
+
+const (
+	pcapHeadLen   = 24
+	pcapRecordLen = 16
+	tcpFlagSyn    = 0x02
+	// maxCapLen 防止损坏的文件申请过大的内存
+	maxCapLen = 256 << 10
+)
+
+// halfStream 一个方向的tcp字节流，按序号去掉重传后切分成包
+type halfStream struct {
+	next    uint32
+	started bool
+	buf     []byte
+}
+
+// ReadPcap 从经典pcap中还原与port通信的tcp连接上的包，目的端口为port的是c2s
+// 抓包中断导致最后一条记录不完整时返回已还原的包和PcapTruncatedErr
+func ReadPcap(r io.Reader, port uint16) ([]Frame, error) {
+	head := make([]byte, pcapHeadLen)
+	_, err := io.ReadFull(r, head)
+	if err != nil {
+		return nil, PcapFormatErr
+	}
+	var order binary.ByteOrder
+	nano := false
+	switch {
+	case binary.LittleEndian.Uint32(head) == 0xa1b2c3d4:
+		order = binary.LittleEndian
+	case binary.BigEndian.Uint32(head) == 0xa1b2c3d4:
+		order = binary.BigEndian
+	case binary.LittleEndian.Uint32(head) == 0xa1b23c4d:
+		order, nano = binary.LittleEndian, true
+	case binary.BigEndian.Uint32(head) == 0xa1b23c4d:
+		order, nano = binary.BigEndian, true
+	default:
+		return nil, PcapFormatErr
+	}
+	snapLen := order.Uint32(head[16:20])
+	if snapLen == 0 || snapLen > maxCapLen {
+		snapLen = maxCapLen
+	}
+	link := order.Uint32(head[20:24])
+
+	streams := map[string]*halfStream{}
+	var frames []Frame
+	record := make([]byte, pcapRecordLen)
+	for {
+		_, err = io.ReadFull(r, record)
+		if errors.Is(err, io.EOF) {
+			return frames, nil
+		}
+		if err != nil {
+			return frames, readErr(err)
+		}
+		sec, frac := order.Uint32(record[0:4]), order.Uint32(record[4:8])
+		ts := time.Unix(int64(sec), int64(frac)*1000)
+		if nano {
+			ts = time.Unix(int64(sec), int64(frac))
+		}
+		capLen := order.Uint32(record[8:12])
+		if capLen > snapLen {
+			return frames, PcapFormatErr
+		}
+		data := make([]byte, capLen)
+		_, err = io.ReadFull(r, data)
+		if err != nil {
+			return frames, readErr(err)
+		}
+		seg, ok := parseSegment(link, data)
+		if !ok {
+			continue
+		}
+		var dir Dir
+		var client string
+		switch port {
+		case seg.dstPort:
+			dir, client = DirC2S, net.JoinHostPort(seg.src.String(), fmt.Sprint(seg.srcPort))
+		case seg.srcPort:
+			dir, client = DirS2C, net.JoinHostPort(seg.dst.String(), fmt.Sprint(seg.dstPort))
+		default:
+			continue
+		}
+		key := dir.String() + client
+		half, ok := streams[key]
+		if !ok {
+			half = &halfStream{}
+			streams[key] = half
+		}
+		for _, packet := range half.feed(seg) {
+			frames = append(frames, Frame{Dir: dir, Time: ts, Stream: client, Data: packet})
+		}
+	}
+}
+
+func readErr(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return PcapTruncatedErr
+	}
+	return err
+}
+
+func (h *halfStream) feed(seg segment) [][]byte {
+	if seg.flags&tcpFlagSyn != 0 {
+		// 同一个四元组上的新连接
+		h.next, h.started, h.buf = seg.seq+1, true, nil
+		return nil
+	}
+	payload := seg.payload
+	if len(payload) == 0 {
+		return nil
+	}
+	if !h.started {
+		h.next, h.started = seg.seq, true
+	}
+	diff := int32(seg.seq - h.next)
+	switch {
+	case diff > 0 || diff == math.MinInt32:
+		// 丢包后无法对齐，丢弃半包从新数据开始，相差正好一半序号空间时无法区分方向也按丢包处理
+		h.buf = nil
+	case diff < 0:
+		// 重传，跳过已经收到的部分
+		if int(-diff) >= len(payload) {
+			return nil
+		}
+		payload = payload[-diff:]
+	}
+	h.next = seg.seq + uint32(len(seg.payload))
+	h.buf = append(h.buf, payload...)
+	packets, rest, err := SplitFrames(h.buf)
+	if err != nil {
+		h.buf = nil
+		return packets
+	}
+	h.buf = slices.Clone(rest)
+	return packets
+}
+
+type segment struct {
+	src, dst         net.IP
+	srcPort, dstPort uint16
+	seq              uint32
+	flags            byte
+	payload          []byte
+}
+
+func parseSegment(link uint32, data []byte) (segment, bool) {
+	var ip []byte
+	switch link {
+	case linkNull:
+		if len(data) < 4 {
+			return segment{}, false
+		}
+		ip = data[4:]
+	case linkEthernet:
+		if len(data) < 14 {
+			return segment{}, false
+		}
+		offset := 14
+		// 802.1Q
+		if binary.BigEndian.Uint16(data[12:14]) == 0x8100 {
+			offset = 18
+		}
+		if len(data) < offset {
+			return segment{}, false
+		}
+		ip = data[offset:]
+	case linkRaw:
+		ip = data
+	case linkSll:
+		if len(data) < 16 {
+			return segment{}, false
+		}
+		ip = data[16:]
+	case linkSll2:
+		if len(data) < 20 {
+			return segment{}, false
+		}
+		ip = data[20:]
+	default:
+		return segment{}, false
+	}
+	return parseIp(ip)
+}
+
+func parseIp(ip []byte) (segment, bool) {
+	if len(ip) < 1 {
+		return segment{}, false
+	}
+	seg := segment{}
+	var tcp []byte
+	switch ip[0] >> 4 {
+	case 4:
+		if len(ip) < 20 {
+			return segment{}, false
+		}
+		ihl := int(ip[0]&0x0f) * 4
+		total := int(binary.BigEndian.Uint16(ip[2:4]))
+		// 以太网最小帧会补齐，按ip总长截断
+		if ip[9] != 6 || ihl < 20 || total < ihl || len(ip) < total {
+			return segment{}, false
+		}
+		seg.src, seg.dst = net.IP(ip[12:16]), net.IP(ip[16:20])
+		tcp = ip[ihl:total]
+	case 6:
+		if len(ip) < 40 {
+			return segment{}, false
+		}
+		total := 40 + int(binary.BigEndian.Uint16(ip[4:6]))
+		if ip[6] != 6 || len(ip) < total {
+			return segment{}, false
+		}
+		seg.src, seg.dst = net.IP(ip[8:24]), net.IP(ip[24:40])
+		tcp = ip[40:total]
+	default:
+		return segment{}, false
+	}
+	if len(tcp) < 20 {
+		return segment{}, false
+	}
+	offset := int(tcp[12]>>4) * 4
+	if offset < 20 || len(tcp) < offset {
+		return segment{}, false
+	}
+	seg.srcPort = binary.BigEndian.Uint16(tcp[0:2])
+	seg.dstPort = binary.BigEndian.Uint16(tcp[2:4])
+	seg.seq = binary.BigEndian.Uint32(tcp[4:8])
+	seg.flags = tcp[13]
+	seg.payload = tcp[offset:]
+	return seg, true
+}
@@ -0,0 +1,10 @@
+package batch
+
+import "errors"
+
+var (
+	QueueFullErr    = errors.New("write queue is full")
+	WriterClosedErr = errors.New("writer is closed")
+	SlowConsumerErr = errors.New("too many pending bytes")
+	FrameLenErr     = errors.New("frame len exceeds limit")
+)
@@ -0,0 +1,82 @@
+package batch
+
+import "time"
+
+type OverflowPolicy int
+
+const (
+	// OverflowClose 队列满时关闭连接
+	OverflowClose OverflowPolicy = iota
+	// OverflowDrop 队列满时丢弃当前包
+	OverflowDrop
+)
+
+type Config struct {
+	queueSize      int
+	maxBatchBytes  int
+	flushInterval  time.Duration
+	overflowPolicy OverflowPolicy
+	// 未写出的字节数上限，0表示不限制
+	maxPendingBytes int64
+	// 未写出的字节数超过该值时丢弃低优先级的包
+	lowPriorityPendingBytes int64
+	// 单个包的长度上限，对端读取时会拒绝超长的包，0表示不限制
+	maxFrameLen int
+}
+
+type Option func(*Config)
+
+func WithQueueSize(queueSize int) Option {
+	return func(c *Config) {
+		c.queueSize = queueSize
+	}
+}
+
+func WithMaxBatchBytes(maxBatchBytes int) Option {
+	return func(c *Config) {
+		c.maxBatchBytes = maxBatchBytes
+	}
+}
+
+// WithFlushInterval 攒批的最长等待时间，0表示队列为空立即写出
+func WithFlushInterval(interval time.Duration) Option {
+	return func(c *Config) {
+		c.flushInterval = interval
+	}
+}
+
+func WithOverflowPolicy(policy OverflowPolicy) Option {
+	return func(c *Config) {
+		c.overflowPolicy = policy
+	}
+}
+
+func WithMaxPendingBytes(maxPendingBytes int64) Option {
+	return func(c *Config) {
+		c.maxPendingBytes = maxPendingBytes
+	}
+}
+
+func WithLowPriorityPendingBytes(pendingBytes int64) Option {
+	return func(c *Config) {
+		c.lowPriorityPendingBytes = pendingBytes
+	}
+}
+
+func WithMaxFrameLen(maxFrameLen int) Option {
+	return func(c *Config) {
+		c.maxFrameLen = maxFrameLen
+	}
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		queueSize:      256,
+		maxBatchBytes:  64 * 1024,
+		flushInterval:  time.Millisecond,
+		overflowPolicy: OverflowClose,
+
+		maxPendingBytes:         1024 * 1024,
+		lowPriorityPendingBytes: 256 * 1024,
+	}
+}
@@ -0,0 +1,293 @@
+package batch
+
+import (
+	"context"
+	"hutool/bytex"
+	"hutool/chanx"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// FlushFunc 一次写出多个包，实现方应尽量合并成一次系统调用
+type FlushFunc func(frames [][]byte) error
+
+// item buf不为空时写出或丢弃后释放
+type item struct {
+	frame []byte
+	buf   *bytex.Buffer
+}
+
+func (it item) release() {
+	if it.buf != nil {
+		it.buf.Release()
+	}
+}
+
+type Stats struct {
+	QueueLen    int    `json:"queueLen"`
+	QueuedBytes int64  `json:"queuedBytes"`
+	Frames      uint64 `json:"frames"`
+	Flushes     uint64 `json:"flushes"`
+	Dropped     uint64 `json:"dropped"`
+	Overflows   uint64 `json:"overflows"`
+}
+
+// Writer 连接的发送队列，由单独的协程攒批写出
+type Writer struct {
+	queue          chan item
+	flush          FlushFunc
+	onError        func(err error)
+	maxBatchBytes  int
+	flushInterval  time.Duration
+	overflowPolicy OverflowPolicy
+
+	maxPendingBytes         int64
+	lowPriorityPendingBytes int64
+	maxFrameLen             int
+
+	queuedBytes atomic.Int64
+	frames      atomic.Uint64
+	flushes     atomic.Uint64
+	dropped     atomic.Uint64
+	overflows   atomic.Uint64
+
+	// scratch 只在写协程使用，复用传给flush的切片
+	scratch [][]byte
+
+	// closeMu 入队时持有读锁，保证写协程标记关闭后不会再有包进入队列
+	closeMu sync.RWMutex
+	closed  atomic.Bool
+	ctx     context.Context
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// NewWriter onError在写出失败或队列溢出需要关闭连接时调用，调用时写协程已经退出
+func NewWriter(flush FlushFunc, onError func(err error), opts ...Option) *Writer {
+	cfg := DefaultConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &Writer{
+		queue:          make(chan item, cfg.queueSize),
+		flush:          flush,
+		onError:        onError,
+		maxBatchBytes:  cfg.maxBatchBytes,
+		flushInterval:  cfg.flushInterval,
+		overflowPolicy: cfg.overflowPolicy,
+
+		maxPendingBytes:         cfg.maxPendingBytes,
+		lowPriorityPendingBytes: cfg.lowPriorityPendingBytes,
+		maxFrameLen:             cfg.maxFrameLen,
+
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Write 入队后立即返回，frame在写出前不能被修改
+func (w *Writer) Write(frame []byte) error {
+	return w.write(item{frame: frame}, false)
+}
+
+// WriteLowPriority 积压过多时直接丢弃，不会触发溢出策略
+func (w *Writer) WriteLowPriority(frame []byte) error {
+	return w.write(item{frame: frame}, true)
+}
+
+// WriteBuffer 缓冲的引用转交给Writer，写出或失败后释放，调用方不能再使用
+func (w *Writer) WriteBuffer(buf *bytex.Buffer) error {
+	return w.write(item{frame: buf.Bytes(), buf: buf}, false)
+}
+
+func (w *Writer) write(it item, lowPriority bool) error {
+	err := w.enqueue(it, lowPriority)
+	if err != nil {
+		it.release()
+	}
+	return err
+}
+
+func (w *Writer) enqueue(it item, lowPriority bool) error {
+	w.closeMu.RLock()
+	defer w.closeMu.RUnlock()
+	if w.closed.Load() {
+		return WriterClosedErr
+	}
+	if w.maxFrameLen > 0 && len(it.frame) > w.maxFrameLen {
+		w.dropped.Add(1)
+		return FrameLenErr
+	}
+	pending := w.queuedBytes.Load() + int64(len(it.frame))
+	if lowPriority && w.lowPriorityPendingBytes > 0 && pending > w.lowPriorityPendingBytes {
+		w.dropped.Add(1)
+		return SlowConsumerErr
+	}
+	if w.maxPendingBytes > 0 && pending > w.maxPendingBytes {
+		return w.overflow(SlowConsumerErr, lowPriority)
+	}
+	select {
+	case w.queue <- it:
+		w.queuedBytes.Add(int64(len(it.frame)))
+		return nil
+	default:
+		return w.overflow(QueueFullErr, lowPriority)
+	}
+}
+
+func (w *Writer) overflow(err error, lowPriority bool) error {
+	w.overflows.Add(1)
+	if lowPriority || w.overflowPolicy == OverflowDrop {
+		w.dropped.Add(1)
+		return err
+	}
+	if w.closed.CompareAndSwap(false, true) {
+		w.cancel()
+		if w.onError != nil {
+			go w.onError(err)
+		}
+	}
+	return err
+}
+
+func (w *Writer) Stats() Stats {
+	return Stats{
+		QueueLen:    len(w.queue),
+		QueuedBytes: w.queuedBytes.Load(),
+		Frames:      w.frames.Load(),
+		Flushes:     w.flushes.Load(),
+		Dropped:     w.dropped.Load(),
+		Overflows:   w.overflows.Load(),
+	}
+}
+
+// Close 停止写协程，已入队的数据会尽量写出
+func (w *Writer) Close() {
+	if w.closed.CompareAndSwap(false, true) {
+		w.cancel()
+	}
+	<-w.done
+}
+
+func (w *Writer) run() {
+	err := w.loop()
+	w.closeMu.Lock()
+	w.closed.Store(true)
+	w.closeMu.Unlock()
+	// 标记关闭前并发入队的包，释放掉避免缓冲泄漏
+	w.discard(chanx.DrainNow[item](w.queue))
+	close(w.done)
+	if err != nil {
+		if w.onError != nil {
+			w.onError(err)
+		}
+	}
+}
+
+func (w *Writer) loop() error {
+	batch := make([]item, 0, 64)
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	defer timer.Stop()
+	for {
+		select {
+		case <-w.ctx.Done():
+			return w.flushRest(batch[:0])
+		case it := <-w.queue:
+			batch = append(batch[:0], it)
+			batch = w.collect(batch, len(it.frame), timer)
+			err := w.doFlush(batch)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// collect 合并队列中的包直到达到字节上限或等待超时
+func (w *Writer) collect(batch []item, size int, timer *time.Timer) []item {
+	for size < w.maxBatchBytes {
+		select {
+		case it := <-w.queue:
+			batch = append(batch, it)
+			size += len(it.frame)
+			continue
+		default:
+		}
+		if w.flushInterval <= 0 {
+			return batch
+		}
+		timer.Reset(w.flushInterval)
+		select {
+		case it := <-w.queue:
+			if !timer.Stop() {
+				<-timer.C
+			}
+			batch = append(batch, it)
+			size += len(it.frame)
+		case <-timer.C:
+			return batch
+		case <-w.ctx.Done():
+			if !timer.Stop() {
+				<-timer.C
+			}
+			return batch
+		}
+	}
+	return batch
+}
+
+func (w *Writer) flushRest(batch []item) error {
+	size := 0
+	rest := chanx.DrainNow[item](w.queue)
+	for i, it := range rest {
+		batch = append(batch, it)
+		size += len(it.frame)
+		if size >= w.maxBatchBytes {
+			err := w.doFlush(batch)
+			if err != nil {
+				w.discard(rest[i+1:])
+				return err
+			}
+			batch = batch[:0]
+			size = 0
+		}
+	}
+	if len(batch) > 0 {
+		return w.doFlush(batch)
+	}
+	return nil
+}
+
+// discard 释放没有写出的包
+func (w *Writer) discard(items []item) {
+	for _, it := range items {
+		w.queuedBytes.Add(-int64(len(it.frame)))
+		it.release()
+	}
+}
+
+func (w *Writer) doFlush(batch []item) error {
+	size := 0
+	frames := w.scratch[:0]
+	for _, it := range batch {
+		frames = append(frames, it.frame)
+		size += len(it.frame)
+	}
+	err := w.flush(frames)
+	w.queuedBytes.Add(-int64(size))
+	w.frames.Add(uint64(len(batch)))
+	w.flushes.Add(1)
+	for i := range batch {
+		batch[i].release()
+		batch[i] = item{}
+		frames[i] = nil
+	}
+	w.scratch = frames
+	return err
+}
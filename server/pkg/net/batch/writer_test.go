@@ -0,0 +1,219 @@
+package batch
+
+import (
+	"errors"
+	"hutool/bytex"
+	"sync"
+	"testing"
+	"time"
+)
+
+type sink struct {
+	mu      sync.Mutex
+	frames  [][]byte
+	flushes int
+	block   chan struct{}
+	// 第failAt次flush开始返回err
+	failAt int
+	err    error
+}
+
+func (s *sink) flush(frames [][]byte) error {
+	if s.block != nil {
+		<-s.block
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, f := range frames {
+		s.frames = append(s.frames, append([]byte(nil), f...))
+	}
+	s.flushes++
+	if s.err != nil && s.flushes >= s.failAt {
+		return s.err
+	}
+	return nil
+}
+
+func (s *sink) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.frames)
+}
+
+func TestWriterBatchesInOrder(t *testing.T) {
+	s := &sink{}
+	w := NewWriter(s.flush, nil, WithFlushInterval(10*time.Millisecond))
+	for i := 0; i < 10; i++ {
+		if err := w.Write([]byte{byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Close()
+	if s.count() != 10 {
+		t.Fatalf("frames %d", s.count())
+	}
+	for i, f := range s.frames {
+		if f[0] != byte(i) {
+			t.Fatalf("frame %d out of order", i)
+		}
+	}
+	if s.flushes >= 10 {
+		t.Fatalf("frames not batched, flushes %d", s.flushes)
+	}
+	if err := w.Write([]byte{1}); !errors.Is(err, WriterClosedErr) {
+		t.Fatalf("write after close err %v", err)
+	}
+}
+
+func TestWriterMaxFrameLen(t *testing.T) {
+	s := &sink{}
+	w := NewWriter(s.flush, nil, WithMaxFrameLen(4))
+	defer w.Close()
+	if err := w.Write(make([]byte, 5)); !errors.Is(err, FrameLenErr) {
+		t.Fatalf("oversized frame err %v", err)
+	}
+	if err := w.Write(make([]byte, 4)); err != nil {
+		t.Fatal(err)
+	}
+	if w.Stats().Dropped != 1 {
+		t.Fatalf("dropped %d", w.Stats().Dropped)
+	}
+}
+
+func TestWriterCloseReportsFlushErr(t *testing.T) {
+	flushErr := errors.New("flush")
+	s := &sink{block: make(chan struct{}), failAt: 2, err: flushErr}
+	errCh := make(chan error, 1)
+	w := NewWriter(s.flush, func(err error) { errCh <- err }, WithFlushInterval(0))
+	// 第一个包阻塞在flush，第二个包留在队列中由关闭时写出
+	_ = w.Write([]byte{1})
+	time.Sleep(10 * time.Millisecond)
+	_ = w.Write([]byte{2})
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(s.block)
+	}()
+	w.Close()
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, flushErr) {
+			t.Fatalf("onError err %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("flush err swallowed")
+	}
+}
+
+func TestWriterMaxPendingBytes(t *testing.T) {
+	s := &sink{block: make(chan struct{})}
+	errCh := make(chan error, 1)
+	w := NewWriter(s.flush, func(err error) { errCh <- err },
+		WithMaxPendingBytes(10), WithLowPriorityPendingBytes(0), WithFlushInterval(0))
+	// 第一个包被写协程取走后阻塞在flush
+	if err := w.Write(make([]byte, 6)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(make([]byte, 4)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(make([]byte, 1)); !errors.Is(err, SlowConsumerErr) {
+		t.Fatalf("pending overflow err %v", err)
+	}
+	close(s.block)
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, SlowConsumerErr) {
+			t.Fatalf("onError err %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("onError not called")
+	}
+	w.Close()
+	if w.Stats().Overflows != 1 {
+		t.Fatalf("overflows %d", w.Stats().Overflows)
+	}
+}
+
+func TestWriterDropLowPriority(t *testing.T) {
+	s := &sink{block: make(chan struct{})}
+	w := NewWriter(s.flush, func(err error) { t.Errorf("unexpected onError %v", err) },
+		WithMaxPendingBytes(100), WithLowPriorityPendingBytes(8), WithFlushInterval(0))
+	if err := w.Write(make([]byte, 6)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteLowPriority(make([]byte, 4)); !errors.Is(err, SlowConsumerErr) {
+		t.Fatalf("low priority err %v", err)
+	}
+	// 普通优先级不受影响
+	if err := w.Write(make([]byte, 4)); err != nil {
+		t.Fatal(err)
+	}
+	close(s.block)
+	w.Close()
+	if s.count() != 2 || w.Stats().Dropped != 1 {
+		t.Fatalf("frames %d dropped %d", s.count(), w.Stats().Dropped)
+	}
+}
+
+// wrapBuffers 提前创建好缓冲，测试过程中不再从池里取，释放后切片被清空可以用来计数
+func wrapBuffers(n int) []*bytex.Buffer {
+	bufs := make([]*bytex.Buffer, n)
+	for i := range bufs {
+		bufs[i] = bytex.WrapBuffer([]byte{byte(i)})
+	}
+	return bufs
+}
+
+func released(bufs []*bytex.Buffer) int {
+	n := 0
+	for _, buf := range bufs {
+		if buf.Bytes() == nil {
+			n++
+		}
+	}
+	return n
+}
+
+func TestWriterReleasesOnFlushErr(t *testing.T) {
+	for round := 0; round < 20; round++ {
+		s := &sink{block: make(chan struct{}), failAt: 2, err: errors.New("flush")}
+		w := NewWriter(s.flush, nil, WithMaxBatchBytes(1), WithFlushInterval(0))
+		bufs := wrapBuffers(8)
+		for _, buf := range bufs {
+			_ = w.WriteBuffer(buf)
+		}
+		// 关闭时队列里还有包，第二次flush失败后剩下的包也要释放
+		go func() {
+			time.Sleep(time.Millisecond)
+			close(s.block)
+		}()
+		w.Close()
+		if n := released(bufs); n != len(bufs) {
+			t.Fatalf("round %d released %d of %d", round, n, len(bufs))
+		}
+		if w.Stats().QueuedBytes != 0 {
+			t.Fatalf("round %d queued bytes %d", round, w.Stats().QueuedBytes)
+		}
+	}
+}
+
+func TestWriterReleasesOnConcurrentClose(t *testing.T) {
+	s := &sink{}
+	w := NewWriter(s.flush, nil, WithFlushInterval(0), WithMaxPendingBytes(0))
+	bufs := wrapBuffers(800)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(bufs []*bytex.Buffer) {
+			defer wg.Done()
+			for _, buf := range bufs {
+				_ = w.WriteBuffer(buf)
+			}
+		}(bufs[i*100 : (i+1)*100])
+	}
+	w.Close()
+	wg.Wait()
+	if n := released(bufs); n != len(bufs) {
+		t.Fatalf("released %d of %d", n, len(bufs))
+	}
+}
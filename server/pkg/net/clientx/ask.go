@@ -0,0 +1,64 @@
+package clientx
+
+import (
+	"hutool/logx"
+	"hutool/reflectx"
+	"reflect"
+	"server/pkg/codec"
+	"sync"
+)
+
+type askHandler struct {
+	reqType reflect.Type
+	handler func(req any) any
+}
+
+// AskHandlerRegistry 处理服务端发起的请求，一个路由只有一个处理器
+type AskHandlerRegistry struct {
+	mu       sync.RWMutex
+	handlers map[uint64]askHandler
+}
+
+func NewAskHandlerRegistry() *AskHandlerRegistry {
+	return &AskHandlerRegistry{
+		handlers: make(map[uint64]askHandler),
+	}
+}
+
+func (r *AskHandlerRegistry) register(serviceId uint32, routerId uint32, handler askHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[routeKey(serviceId, routerId)] = handler
+}
+
+// Handle 返回序列化后的响应，没有处理器时不响应，由服务端超时
+func (r *AskHandlerRegistry) Handle(serviceId uint32, routerId uint32, body []byte, serializer codec.ISerializer) ([]byte, bool) {
+	r.mu.RLock()
+	h, ok := r.handlers[routeKey(serviceId, routerId)]
+	r.mu.RUnlock()
+	if !ok {
+		logx.Warnf("no handler for server ask %d %d", serviceId, routerId)
+		return nil, false
+	}
+	req := reflectx.NewPointerIns(h.reqType)
+	err := serializer.Unmarshal(body, req)
+	if err != nil {
+		logx.Errorf("unmarshal err %+v", err)
+		return nil, false
+	}
+	rspBodyBytes, err := serializer.Marshal(h.handler(req))
+	if err != nil {
+		logx.Errorf("marshal err %+v", err)
+		return nil, false
+	}
+	return rspBodyBytes, true
+}
+
+func RegisterAskHandler[Req any, Rsp any](registry *AskHandlerRegistry, serviceId uint32, routerId uint32, handler func(req Req) Rsp) {
+	registry.register(serviceId, routerId, askHandler{
+		reqType: reflectx.GenericTypeOf[Req](),
+		handler: func(req any) any {
+			return handler(req.(Req))
+		},
+	})
+}
@@ -0,0 +1,11 @@
+package clientx
+
+import "errors"
+
+var (
+	InFlightFullErr       = errors.New("too many in-flight requests")
+	RequestTableClosedErr = errors.New("request table is closed")
+	HandshakeRejectedErr  = errors.New("handshake rejected by server")
+	HandshakeTimeoutErr   = errors.New("handshake timeout")
+	RequestTimeoutErr     = errors.New("request timeout")
+)
@@ -0,0 +1,103 @@
+package clientx
+
+import (
+	"fmt"
+	"server/pkg/codec"
+	zip2 "server/pkg/zip"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Negotiation 客户端握手状态，握手前按协议默认值收发
+type Negotiation struct {
+	zip    zip2.IZip
+	result atomic.Pointer[codec.HandshakeRsp]
+	err    error
+	done   chan struct{}
+	once   sync.Once
+}
+
+func NewNegotiation(zip zip2.IZip) *Negotiation {
+	if zip == nil {
+		zip = zip2.None{}
+	}
+	return &Negotiation{
+		zip:  zip,
+		done: make(chan struct{}),
+	}
+}
+
+func (n *Negotiation) Request(serializerId uint8) codec.Handshake {
+	return codec.Handshake{
+		Version:      codec.ProtocolVersion,
+		Serializer:   serializerId,
+		Compression:  zip2.MaskOf(n.zip),
+		MaxPacketLen: codec.MaxPacketLen,
+		Caps:         codec.CapAll,
+	}
+}
+
+// Handler 在读协程中回调，之后的数据按协商结果解压
+func (n *Negotiation) Handler() MsgHandler {
+	return NewRawMsgHandler(func(body []byte) {
+		rsp, err := codec.DecodeHandshakeRsp(body)
+		if err == nil && rsp.Code != codec.HandshakeOk {
+			err = fmt.Errorf("%w code %d", HandshakeRejectedErr, rsp.Code)
+		}
+		if err == nil && rsp.Version < codec.MinProtocolVersion {
+			err = fmt.Errorf("%w version %d", HandshakeRejectedErr, rsp.Version)
+		}
+		if err == nil {
+			n.result.Store(&rsp)
+		}
+		n.finish(err)
+	})
+}
+
+func (n *Negotiation) Wait(timeout time.Duration) (codec.HandshakeRsp, error) {
+	select {
+	case <-n.done:
+	case <-time.After(timeout):
+		n.finish(HandshakeTimeoutErr)
+	}
+	if n.err != nil {
+		return codec.HandshakeRsp{}, n.err
+	}
+	return *n.result.Load(), nil
+}
+
+func (n *Negotiation) finish(err error) {
+	n.once.Do(func() {
+		n.err = err
+		close(n.done)
+	})
+}
+
+func (n *Negotiation) Result() (codec.HandshakeRsp, bool) {
+	rsp := n.result.Load()
+	if rsp == nil {
+		return codec.HandshakeRsp{}, false
+	}
+	return *rsp, true
+}
+
+func (n *Negotiation) Unzip(data []byte) ([]byte, error) {
+	rsp := n.result.Load()
+	if rsp == nil || rsp.Compression == zip2.IdNone {
+		return data, nil
+	}
+	return n.zip.Unzip(data)
+}
+
+// CheckLen 超过服务端声明的包长时不发送
+func (n *Negotiation) CheckLen(packetLen int) error {
+	maxPacketLen := uint32(codec.MaxPacketLen)
+	if rsp := n.result.Load(); rsp != nil {
+		maxPacketLen = rsp.MaxPacketLen
+	}
+	if uint32(packetLen) > maxPacketLen {
+		return codec.PacketTooLargeErr
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package clientx
+
+import (
+	"hutool/logx"
+	"server/pkg/codec"
+	"sync/atomic"
+)
+
+type KickHandler func(reason codec.KickReason, msg string)
+
+// KickNotifier 服务端踢下线时回调，之后连接会被服务端关闭
+type KickNotifier struct {
+	handler atomic.Pointer[KickHandler]
+}
+
+func (n *KickNotifier) Set(handler KickHandler) {
+	n.handler.Store(&handler)
+}
+
+func (n *KickNotifier) Notify(body []byte) {
+	reason, msg, err := codec.DecodeKick(body)
+	if err != nil {
+		logx.Errorf("decode kick err %+v", err)
+		return
+	}
+	handler := n.handler.Load()
+	if handler == nil || *handler == nil {
+		logx.Warnf("kicked by server reason %d %s", reason, msg)
+		return
+	}
+	(*handler)(reason, msg)
+}
@@ -0,0 +1,95 @@
+package clientx
+
+import (
+	"hutool/timewheel"
+	"time"
+)
+
+type DropPolicy int
+
+const (
+	// DropOldest 缓冲满时丢弃最早的推送，适合状态类推送
+	DropOldest DropPolicy = iota
+	// DropNewest 缓冲满时丢弃新到的推送
+	DropNewest
+)
+
+type SubscribeConfig struct {
+	bufferSize int
+	dropPolicy DropPolicy
+}
+
+type SubscribeOption func(*SubscribeConfig)
+
+func WithBufferSize(size int) SubscribeOption {
+	return func(c *SubscribeConfig) {
+		c.bufferSize = size
+	}
+}
+
+func WithDropPolicy(policy DropPolicy) SubscribeOption {
+	return func(c *SubscribeConfig) {
+		c.dropPolicy = policy
+	}
+}
+
+func DefaultSubscribeConfig() *SubscribeConfig {
+	return &SubscribeConfig{
+		bufferSize: 64,
+		dropPolicy: DropOldest,
+	}
+}
+
+type RequestConfig struct {
+	maxInFlight    int
+	acquireTimeout time.Duration
+	requestTimeout time.Duration
+	rttWindow      int
+	wheel          *timewheel.TimingWheel
+}
+
+type RequestOption func(*RequestConfig)
+
+// WithMaxInFlight 同时等待响应的请求上限，0表示不限制
+func WithMaxInFlight(maxInFlight int) RequestOption {
+	return func(c *RequestConfig) {
+		c.maxInFlight = maxInFlight
+	}
+}
+
+// WithAcquireTimeout 达到上限时的最长等待时间，0表示直接失败
+func WithAcquireTimeout(timeout time.Duration) RequestOption {
+	return func(c *RequestConfig) {
+		c.acquireTimeout = timeout
+	}
+}
+
+// WithRequestTimeout 超时未响应的请求会被丢弃并释放名额
+func WithRequestTimeout(timeout time.Duration) RequestOption {
+	return func(c *RequestConfig) {
+		c.requestTimeout = timeout
+	}
+}
+
+// WithRttWindow 统计rtt分位数时保留的最近样本数
+func WithRttWindow(size int) RequestOption {
+	return func(c *RequestConfig) {
+		c.rttWindow = size
+	}
+}
+
+// WithTimingWheel 驱动请求超时的时间轮，默认使用共享的时间轮
+func WithTimingWheel(wheel *timewheel.TimingWheel) RequestOption {
+	return func(c *RequestConfig) {
+		c.wheel = wheel
+	}
+}
+
+func DefaultRequestConfig() *RequestConfig {
+	return &RequestConfig{
+		maxInFlight:    0,
+		acquireTimeout: 0,
+		requestTimeout: 30 * time.Second,
+		rttWindow:      1024,
+	}
+}
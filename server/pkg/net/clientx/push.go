@@ -0,0 +1,175 @@
+package clientx
+
+import (
+	"server/pkg/codec"
+	"sync"
+)
+
+type pushHandler struct {
+	id      uint64
+	handler MsgHandler
+	onClose func()
+}
+
+// PushRegistry 推送订阅表，同一路由可以有多个订阅者
+type PushRegistry struct {
+	mu     sync.RWMutex
+	nextId uint64
+	// 写时复制，分发时不需要持锁
+	handlers map[uint64][]pushHandler
+}
+
+func NewPushRegistry() *PushRegistry {
+	return &PushRegistry{
+		handlers: make(map[uint64][]pushHandler),
+	}
+}
+
+type Subscription struct {
+	registry *PushRegistry
+	key      uint64
+	id       uint64
+	once     sync.Once
+}
+
+func (s *Subscription) Unsubscribe() {
+	s.once.Do(func() {
+		s.registry.remove(s.key, s.id)
+	})
+}
+
+func routeKey(serviceId uint32, routerId uint32) uint64 {
+	return uint64(serviceId)<<32 | uint64(routerId)
+}
+
+func (r *PushRegistry) Subscribe(serviceId uint32, routerId uint32, handler MsgHandler) *Subscription {
+	return r.subscribe(serviceId, routerId, handler, nil)
+}
+
+func (r *PushRegistry) subscribe(serviceId uint32, routerId uint32, handler MsgHandler, onClose func()) *Subscription {
+	key := routeKey(serviceId, routerId)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextId++
+	old := r.handlers[key]
+	handlers := make([]pushHandler, 0, len(old)+1)
+	handlers = append(handlers, old...)
+	handlers = append(handlers, pushHandler{
+		id:      r.nextId,
+		handler: handler,
+		onClose: onClose,
+	})
+	r.handlers[key] = handlers
+	return &Subscription{registry: r, key: key, id: r.nextId}
+}
+
+func (r *PushRegistry) remove(key uint64, id uint64) {
+	r.mu.Lock()
+	old := r.handlers[key]
+	handlers := make([]pushHandler, 0, len(old))
+	var removed *pushHandler
+	for i := range old {
+		if old[i].id == id {
+			removed = &old[i]
+			continue
+		}
+		handlers = append(handlers, old[i])
+	}
+	if len(handlers) == 0 {
+		delete(r.handlers, key)
+	} else {
+		r.handlers[key] = handlers
+	}
+	r.mu.Unlock()
+
+	if removed != nil && removed.onClose != nil {
+		removed.onClose()
+	}
+}
+
+// Dispatch 每个订阅者拿到独立反序列化的消息，没有订阅者返回false
+func (r *PushRegistry) Dispatch(serviceId uint32, routerId uint32, body []byte, serializer codec.ISerializer) bool {
+	r.mu.RLock()
+	handlers := r.handlers[routeKey(serviceId, routerId)]
+	r.mu.RUnlock()
+	if len(handlers) == 0 {
+		return false
+	}
+	for _, h := range handlers {
+		h.handler.Handle(body, serializer)
+	}
+	return true
+}
+
+// Close 移除所有订阅，通道订阅会被关闭
+func (r *PushRegistry) Close() {
+	r.mu.Lock()
+	all := r.handlers
+	r.handlers = make(map[uint64][]pushHandler)
+	r.mu.Unlock()
+	for _, handlers := range all {
+		for _, h := range handlers {
+			if h.onClose != nil {
+				h.onClose()
+			}
+		}
+	}
+}
+
+func RegisterPushHandler[Push any](registry *PushRegistry, serviceId uint32, routerId uint32, handler func(push Push)) *Subscription {
+	return registry.Subscribe(serviceId, routerId, NewMsgHandler(handler))
+}
+
+// Subscribe 以通道的方式消费推送，消费不及时按丢弃策略处理，取消订阅后通道会被关闭
+func Subscribe[Push any](registry *PushRegistry, serviceId uint32, routerId uint32, opts ...SubscribeOption) (<-chan Push, *Subscription) {
+	cfg := DefaultSubscribeConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	sink := &chanSink[Push]{
+		ch:     make(chan Push, cfg.bufferSize),
+		policy: cfg.dropPolicy,
+	}
+	sub := registry.subscribe(serviceId, routerId, NewMsgHandler(sink.push), sink.close)
+	return sink.ch, sub
+}
+
+type chanSink[T any] struct {
+	mu     sync.Mutex
+	ch     chan T
+	policy DropPolicy
+	closed bool
+}
+
+func (s *chanSink[T]) push(v T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.ch <- v:
+		return
+	default:
+	}
+	if s.policy == DropNewest {
+		return
+	}
+	select {
+	case <-s.ch:
+	default:
+	}
+	select {
+	case s.ch <- v:
+	default:
+	}
+}
+
+func (s *chanSink[T]) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
+}
@@ -0,0 +1,77 @@
+package clientx
+
+import (
+	"server/pkg/codec"
+	"strconv"
+	"testing"
+)
+
+type testPush struct {
+	N int `json:"n"`
+}
+
+func pushBody(n int) []byte {
+	return []byte(`{"n":` + strconv.Itoa(n) + `}`)
+}
+
+func TestPushSubscribe(t *testing.T) {
+	registry := NewPushRegistry()
+	var got1, got2 []*testPush
+	sub1 := RegisterPushHandler(registry, 1, 2, func(push *testPush) {
+		got1 = append(got1, push)
+	})
+	RegisterPushHandler(registry, 1, 2, func(push *testPush) {
+		got2 = append(got2, push)
+	})
+	if !registry.Dispatch(1, 2, pushBody(1), codec.JsonSerializer{}) {
+		t.Fatal("dispatch found no subscriber")
+	}
+	// 每个订阅者拿到独立的消息
+	if len(got1) != 1 || len(got2) != 1 || got1[0] == got2[0] || got1[0].N != 1 {
+		t.Fatalf("unexpected pushes %v %v", got1, got2)
+	}
+	if registry.Dispatch(1, 3, pushBody(1), codec.JsonSerializer{}) {
+		t.Fatal("dispatch to other route")
+	}
+
+	sub1.Unsubscribe()
+	sub1.Unsubscribe()
+	registry.Dispatch(1, 2, pushBody(2), codec.JsonSerializer{})
+	if len(got1) != 1 || len(got2) != 2 {
+		t.Fatalf("unsubscribed handler called %v %v", got1, got2)
+	}
+}
+
+func TestPushChannelDropPolicy(t *testing.T) {
+	registry := NewPushRegistry()
+	oldest, _ := Subscribe[*testPush](registry, 1, 2, WithBufferSize(2))
+	newest, sub := Subscribe[*testPush](registry, 1, 2, WithBufferSize(2), WithDropPolicy(DropNewest))
+	for i := 1; i <= 3; i++ {
+		registry.Dispatch(1, 2, pushBody(i), codec.JsonSerializer{})
+	}
+	if a, b := <-oldest, <-oldest; a.N != 2 || b.N != 3 {
+		t.Fatalf("drop oldest kept %d %d", a.N, b.N)
+	}
+	if a, b := <-newest, <-newest; a.N != 1 || b.N != 2 {
+		t.Fatalf("drop newest kept %d %d", a.N, b.N)
+	}
+
+	// 取消订阅后通道关闭，不再收到推送
+	sub.Unsubscribe()
+	if _, ok := <-newest; ok {
+		t.Fatal("channel not closed after unsubscribe")
+	}
+	registry.Dispatch(1, 2, pushBody(4), codec.JsonSerializer{})
+	if p := <-oldest; p.N != 4 {
+		t.Fatalf("remaining subscriber got %d", p.N)
+	}
+
+	// 关闭注册表时关闭所有通道
+	registry.Close()
+	if _, ok := <-oldest; ok {
+		t.Fatal("channel not closed after registry close")
+	}
+	if registry.Dispatch(1, 2, pushBody(5), codec.JsonSerializer{}) {
+		t.Fatal("dispatch after close")
+	}
+}
@@ -0,0 +1,379 @@
+package clientx
+
+import (
+	"hutool/logx"
+	"hutool/reflectx"
+	"hutool/timewheel"
+	"reflect"
+	"server/pkg/codec"
+	"server/pkg/trace"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type MsgHandler struct {
+	msgType reflect.Type
+	handler func(msg any)
+	// metaHandler 需要响应元数据的处理器
+	metaHandler func(msg any, md codec.Metadata)
+	// raw 不经过序列化，直接处理消息体
+	raw func(body []byte)
+	// serializer 路由单独指定的序列化，为空时使用Handle传入的
+	serializer codec.ISerializer
+	// span 收到响应、超时或关闭时结束
+	span *trace.Span
+	// onErr 被拒绝、解析失败、超时或关闭时调用，不设置时普通处理器收不到任何通知
+	onErr func(err error)
+}
+
+func NewMsgHandler[Msg any](handler func(msg Msg)) MsgHandler {
+	return MsgHandler{
+		msgType: reflectx.GenericTypeOf[Msg](),
+		handler: func(msg any) {
+			handler(msg.(Msg))
+		},
+	}
+}
+
+func NewMetaMsgHandler[Msg any](handler func(msg Msg, md codec.Metadata)) MsgHandler {
+	return MsgHandler{
+		msgType: reflectx.GenericTypeOf[Msg](),
+		metaHandler: func(msg any, md codec.Metadata) {
+			handler(msg.(Msg), md)
+		},
+	}
+}
+
+func NewRawMsgHandler(handler func(body []byte)) MsgHandler {
+	return MsgHandler{raw: handler}
+}
+
+// WithSerializer 响应按发起请求时的序列化解析
+func (h MsgHandler) WithSerializer(serializer codec.ISerializer) MsgHandler {
+	h.serializer = serializer
+	return h
+}
+
+func (h MsgHandler) WithSpan(span *trace.Span) MsgHandler {
+	h.span = span
+	return h
+}
+
+func (h MsgHandler) WithErrHandler(onErr func(err error)) MsgHandler {
+	h.onErr = onErr
+	return h
+}
+
+// fail 请求没有收到响应
+func (h MsgHandler) fail(err error) {
+	h.span.SetError(err)
+	h.span.End()
+	if h.onErr != nil {
+		h.onErr(err)
+	}
+}
+
+func (h MsgHandler) Handle(body []byte, serializer codec.ISerializer) {
+	h.HandleWithMeta(body, nil, serializer)
+}
+
+func (h MsgHandler) HandleWithMeta(body []byte, md codec.Metadata, serializer codec.ISerializer) {
+	defer h.span.End()
+	if h.raw != nil {
+		h.raw(body)
+		return
+	}
+	msg := reflectx.NewPointerIns(h.msgType)
+	// 错误响应没有消息体，只有关心元数据的处理器能收到
+	if rspErr := md.Err(); rspErr != nil {
+		h.span.SetError(rspErr)
+		if h.metaHandler != nil {
+			h.metaHandler(msg, md)
+			return
+		}
+		if h.onErr != nil {
+			h.onErr(rspErr)
+			return
+		}
+		logx.Warnf("request rejected %+v", rspErr)
+		return
+	}
+	if h.serializer != nil {
+		serializer = h.serializer
+	}
+	err := serializer.Unmarshal(body, msg)
+	if err != nil {
+		h.span.SetError(err)
+		logx.Errorf("unmarshal err %+v", err)
+		if h.onErr != nil {
+			h.onErr(err)
+		}
+		return
+	}
+	if h.metaHandler != nil {
+		h.metaHandler(msg, md)
+		return
+	}
+	h.handler(msg)
+}
+
+type pendingReq struct {
+	handler  MsgHandler
+	sendTime time.Time
+	timer    *timewheel.Timer
+}
+
+type RequestStats struct {
+	Pending   int
+	Completed uint64
+	Timeouts  uint64
+	RttP50    time.Duration
+	RttP90    time.Duration
+	RttP99    time.Duration
+	RttMax    time.Duration
+}
+
+// sharedWheel 所有客户端共用的超时时间轮，避免每个请求一个runtime timer
+var sharedWheel = sync.OnceValue(func() *timewheel.TimingWheel {
+	return timewheel.NewTimingWheel(timewheel.WithTick(10 * time.Millisecond))
+})
+
+// RequestTable 客户端等待响应的请求表
+type RequestTable struct {
+	mu      sync.Mutex
+	nextId  uint32
+	pending map[uint32]*pendingReq
+	// 在途请求名额，不限制时为nil
+	slots          chan struct{}
+	acquireTimeout time.Duration
+	requestTimeout time.Duration
+	wheel          *timewheel.TimingWheel
+
+	rtt       []time.Duration
+	rttNext   int
+	completed atomic.Uint64
+	timeouts  atomic.Uint64
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func NewRequestTable(opts ...RequestOption) *RequestTable {
+	cfg := DefaultRequestConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	t := &RequestTable{
+		pending:        make(map[uint32]*pendingReq),
+		acquireTimeout: cfg.acquireTimeout,
+		requestTimeout: cfg.requestTimeout,
+		wheel:          cfg.wheel,
+		rtt:            make([]time.Duration, 0, cfg.rttWindow),
+		closed:         make(chan struct{}),
+	}
+	if t.wheel == nil {
+		t.wheel = sharedWheel()
+	}
+	if cfg.maxInFlight > 0 {
+		t.slots = make(chan struct{}, cfg.maxInFlight)
+	}
+	return t
+}
+
+// Begin 占用一个在途名额并分配请求id，关闭后返回RequestTableClosedErr
+func (t *RequestTable) Begin(handler MsgHandler) (uint32, error) {
+	err := t.acquire()
+	if err != nil {
+		return 0, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// 和Close互斥，关闭后不会再有请求进入表中
+	if t.isClosed() {
+		t.release()
+		return 0, RequestTableClosedErr
+	}
+	reqId := t.allocId()
+	req := &pendingReq{
+		handler:  handler,
+		sendTime: time.Now(),
+	}
+	if t.requestTimeout > 0 {
+		req.timer = t.wheel.AfterFunc(t.requestTimeout, func() {
+			t.expire(reqId, req)
+		})
+	}
+	t.pending[reqId] = req
+	return reqId, nil
+}
+
+// allocId 跳过0和仍在等待响应的id，回绕后也不会冲突
+func (t *RequestTable) allocId() uint32 {
+	for {
+		t.nextId++
+		if t.nextId == 0 {
+			continue
+		}
+		if _, ok := t.pending[t.nextId]; !ok {
+			return t.nextId
+		}
+	}
+}
+
+func (t *RequestTable) isClosed() bool {
+	select {
+	case <-t.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func (t *RequestTable) acquire() error {
+	if t.slots == nil {
+		return nil
+	}
+	select {
+	case <-t.closed:
+		return RequestTableClosedErr
+	case t.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	if t.acquireTimeout <= 0 {
+		return InFlightFullErr
+	}
+	tm := time.NewTimer(t.acquireTimeout)
+	defer tm.Stop()
+	select {
+	case <-t.closed:
+		return RequestTableClosedErr
+	case t.slots <- struct{}{}:
+		return nil
+	case <-tm.C:
+		return InFlightFullErr
+	}
+}
+
+func (t *RequestTable) release() {
+	if t.slots != nil {
+		<-t.slots
+	}
+}
+
+// Cancel 请求没有发出去时归还名额
+func (t *RequestTable) Cancel(reqId uint32) {
+	if t.remove(reqId) != nil {
+		t.release()
+	}
+}
+
+// Complete 收到响应，返回对应的处理函数
+func (t *RequestTable) Complete(reqId uint32) (MsgHandler, bool) {
+	req := t.remove(reqId)
+	if req == nil {
+		return MsgHandler{}, false
+	}
+	t.release()
+	t.completed.Add(1)
+	t.recordRtt(time.Since(req.sendTime))
+	return req.handler, true
+}
+
+func (t *RequestTable) remove(reqId uint32) *pendingReq {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	req, ok := t.pending[reqId]
+	if !ok {
+		return nil
+	}
+	delete(t.pending, reqId)
+	if req.timer != nil {
+		req.timer.Stop()
+	}
+	return req
+}
+
+func (t *RequestTable) expire(reqId uint32, req *pendingReq) {
+	t.mu.Lock()
+	// id可能已经被新请求复用
+	if t.pending[reqId] != req {
+		t.mu.Unlock()
+		return
+	}
+	delete(t.pending, reqId)
+	t.mu.Unlock()
+	t.release()
+	t.timeouts.Add(1)
+	req.handler.fail(RequestTimeoutErr)
+	logx.Debugf("request %d timeout", reqId)
+}
+
+func (t *RequestTable) recordRtt(rtt time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.rtt) < cap(t.rtt) {
+		t.rtt = append(t.rtt, rtt)
+		return
+	}
+	if len(t.rtt) == 0 {
+		return
+	}
+	t.rtt[t.rttNext] = rtt
+	t.rttNext = (t.rttNext + 1) % len(t.rtt)
+}
+
+func (t *RequestTable) Stats() RequestStats {
+	t.mu.Lock()
+	pending := len(t.pending)
+	samples := slices.Clone(t.rtt)
+	t.mu.Unlock()
+
+	stats := RequestStats{
+		Pending:   pending,
+		Completed: t.completed.Load(),
+		Timeouts:  t.timeouts.Load(),
+	}
+	if len(samples) == 0 {
+		return stats
+	}
+	slices.Sort(samples)
+	stats.RttP50 = percentile(samples, 50)
+	stats.RttP90 = percentile(samples, 90)
+	stats.RttP99 = percentile(samples, 99)
+	stats.RttMax = samples[len(samples)-1]
+	return stats
+}
+
+func percentile(sorted []time.Duration, p int) time.Duration {
+	idx := (len(sorted)*p+99)/100 - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return sorted[idx]
+}
+
+// Close 丢弃所有等待中的请求，唤醒等待名额的调用方
+// 失败回调在锁外执行，回调中可以继续调用Begin
+func (t *RequestTable) Close() {
+	t.closeOnce.Do(func() {
+		close(t.closed)
+		t.mu.Lock()
+		reqs := make([]*pendingReq, 0, len(t.pending))
+		for reqId, req := range t.pending {
+			if req.timer != nil {
+				req.timer.Stop()
+			}
+			reqs = append(reqs, req)
+			delete(t.pending, reqId)
+		}
+		t.mu.Unlock()
+		for _, req := range reqs {
+			t.release()
+			req.handler.fail(RequestTableClosedErr)
+		}
+	})
+}
@@ -0,0 +1,137 @@
+package clientx
+
+import (
+	"errors"
+	"hutool/timewheel"
+	"math"
+	"testing"
+	"time"
+)
+
+// errHandler 失败时把错误写入通道
+func errHandler(errs chan error) MsgHandler {
+	return NewRawMsgHandler(func(body []byte) {}).WithErrHandler(func(err error) {
+		errs <- err
+	})
+}
+
+func TestRequestIdWrap(t *testing.T) {
+	table := NewRequestTable()
+	defer table.Close()
+	first, err := table.Begin(NewRawMsgHandler(nil))
+	if err != nil || first != 1 {
+		t.Fatalf("first id %d err %v", first, err)
+	}
+	table.nextId = math.MaxUint32 - 1
+	last, _ := table.Begin(NewRawMsgHandler(nil))
+	// 回绕后跳过0和仍在等待的1
+	wrapped, _ := table.Begin(NewRawMsgHandler(nil))
+	if last != math.MaxUint32 || wrapped != 2 {
+		t.Fatalf("ids around wrap %d %d", last, wrapped)
+	}
+	if _, ok := table.Complete(first); !ok {
+		t.Fatal("first request lost")
+	}
+	if _, ok := table.Complete(first); ok {
+		t.Fatal("request completed twice")
+	}
+	if stats := table.Stats(); stats.Pending != 2 || stats.Completed != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestRequestInFlightLimit(t *testing.T) {
+	table := NewRequestTable(WithMaxInFlight(1))
+	defer table.Close()
+	reqId, err := table.Begin(NewRawMsgHandler(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = table.Begin(NewRawMsgHandler(nil)); !errors.Is(err, InFlightFullErr) {
+		t.Fatalf("over limit err %v", err)
+	}
+	// 没发出去的请求归还名额
+	table.Cancel(reqId)
+	if _, err = table.Begin(NewRawMsgHandler(nil)); err != nil {
+		t.Fatalf("begin after cancel err %v", err)
+	}
+
+	// 等待名额期间有请求完成
+	waiting := NewRequestTable(WithMaxInFlight(1), WithAcquireTimeout(time.Second))
+	defer waiting.Close()
+	first, _ := waiting.Begin(NewRawMsgHandler(nil))
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		waiting.Complete(first)
+	}()
+	if _, err = waiting.Begin(NewRawMsgHandler(nil)); err != nil {
+		t.Fatalf("acquire after complete err %v", err)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	wheel := timewheel.NewTimingWheel(timewheel.WithTick(time.Millisecond))
+	defer wheel.Stop()
+	table := NewRequestTable(WithMaxInFlight(1), WithRequestTimeout(20*time.Millisecond), WithTimingWheel(wheel))
+	defer table.Close()
+	errs := make(chan error, 1)
+	reqId, err := table.Begin(errHandler(errs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err = <-errs:
+		if !errors.Is(err, RequestTimeoutErr) {
+			t.Fatalf("timeout err %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("request not timed out")
+	}
+	if _, ok := table.Complete(reqId); ok {
+		t.Fatal("timed out request completed")
+	}
+	if stats := table.Stats(); stats.Pending != 0 || stats.Timeouts != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	// 超时释放名额
+	if _, err = table.Begin(NewRawMsgHandler(nil)); err != nil {
+		t.Fatalf("begin after timeout err %v", err)
+	}
+}
+
+func TestRequestTableClose(t *testing.T) {
+	table := NewRequestTable()
+	errs := make(chan error, 4)
+	for i := 0; i < 2; i++ {
+		// 回调中再发请求不能死锁
+		_, err := table.Begin(NewRawMsgHandler(nil).WithErrHandler(func(err error) {
+			errs <- err
+			_, err = table.Begin(NewRawMsgHandler(nil))
+			errs <- err
+		}))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	done := make(chan struct{})
+	go func() {
+		table.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("close deadlocked")
+	}
+	for i := 0; i < 4; i++ {
+		if err := <-errs; !errors.Is(err, RequestTableClosedErr) {
+			t.Fatalf("pending request err %v", err)
+		}
+	}
+	if _, err := table.Begin(NewRawMsgHandler(nil)); !errors.Is(err, RequestTableClosedErr) {
+		t.Fatalf("begin after close err %v", err)
+	}
+	if stats := table.Stats(); stats.Pending != 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
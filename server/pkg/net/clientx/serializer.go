@@ -0,0 +1,35 @@
+package clientx
+
+import "server/pkg/codec"
+
+// Serializers 客户端的序列化选择，和服务端共用注册表时路由绑定保持一致
+type Serializers struct {
+	registry *codec.SerializerRegistry
+	def      codec.ISerializer
+}
+
+func NewSerializers(registry *codec.SerializerRegistry, def codec.ISerializer) *Serializers {
+	if registry == nil {
+		registry = codec.DefaultSerializerRegistry()
+	}
+	return &Serializers{
+		registry: registry,
+		def:      def,
+	}
+}
+
+// Id 握手时声明的序列化，没有注册的自定义序列化返回Unknown，由服务端使用默认序列化
+func (s *Serializers) Id() uint8 {
+	return s.registry.IdOf(s.def)
+}
+
+func (s *Serializers) Default() codec.ISerializer {
+	return s.def
+}
+
+func (s *Serializers) For(serviceId uint32, routerId uint32) codec.ISerializer {
+	if serializer, ok := s.registry.ForRoute(serviceId, routerId); ok {
+		return serializer
+	}
+	return s.def
+}
@@ -0,0 +1,25 @@
+package clientx
+
+import (
+	"context"
+	"server/pkg/codec"
+	"server/pkg/trace"
+)
+
+// StartAskSpan 开始客户端请求的span，并把上下文中的元数据和trace一起放进请求元数据
+// tracer为空时不追踪，只返回上下文中的元数据
+func StartAskSpan(ctx context.Context, tracer *trace.Tracer, serviceId uint32, routerId uint32) (codec.Metadata, *trace.Span) {
+	md := codec.MetadataFromContext(ctx)
+	if tracer == nil {
+		return md, nil
+	}
+	ctx, span := tracer.Start(ctx, "client.ask", trace.KindClient)
+	span.SetAttr("rpc.service_id", serviceId)
+	span.SetAttr("rpc.router_id", routerId)
+	md = md.Clone()
+	if md == nil {
+		md = make(codec.Metadata)
+	}
+	trace.Inject(ctx, md)
+	return md, span
+}
@@ -1,14 +1,44 @@
 package inet
 
+import (
+	"hutool/bytex"
+	"server/pkg/net/batch"
+)
+
 type IConn interface {
 	GetConnId() uint32
 	RemoteAddr() string
 	Write(data []byte) error
+	// WriteLowPriority 积压时允许被丢弃的数据，比如状态同步
+	WriteLowPriority(data []byte) error
+	// WriteBuffer 缓冲的引用转交给连接，写出后释放
+	WriteBuffer(buf *bytex.Buffer) error
 	Close()
 }
 
+// IAttrConn 建立连接时携带的属性，绑定session时写入
+type IAttrConn interface {
+	Attrs() map[string]any
+}
+
+// ICompressConn 传输层自身支持压缩的连接
+type ICompressConn interface {
+	Compressed() bool
+}
+
+// ITextConn 使用文本协议的连接，消息体固定为json
+type ITextConn interface {
+	TextMode() bool
+}
+
+// IStatsConn 暴露发送队列统计的连接
+type IStatsConn interface {
+	WriterStats() batch.Stats
+}
+
 type IService interface {
 	OnConnStart(conn IConn)
-	OnConnRead(conn IConn, readData []byte)
+	// OnConnRead readBuf只在调用期间有效，需要继续持有时Retain
+	OnConnRead(conn IConn, readBuf *bytex.Buffer)
 	OnConnStop(conn IConn)
 }
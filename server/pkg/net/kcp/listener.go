@@ -27,12 +27,12 @@ func (l *Listener) Listen(host string, port int) error {
 	return nil
 }
 
-func (l *Listener) Accept(svc inet.IService) (*Conn, error) {
+func (l *Listener) Accept(svc inet.IService, cfg *Config) (*Conn, error) {
 	rawConn, err := l.ln.AcceptKCP()
 	if err != nil {
 		return nil, err
 	}
-	conn := NewConn(rawConn, svc)
+	conn := NewConn(rawConn, svc, cfg)
 	return conn, nil
 }
 
@@ -13,13 +13,20 @@ import (
 )
 
 type Server struct {
+	cfg      *Config
 	listener *Listener
 	wg       sync.WaitGroup
 	svc      inet.IService
 }
 
-func NewServer() *Server {
-	return &Server{}
+func NewServer(opts ...Option) *Server {
+	cfg := DefaultConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return &Server{
+		cfg: cfg,
+	}
 }
 
 func (s *Server) ListenAndServe(host string, port int, svc inet.IService) error {
@@ -37,14 +44,14 @@ func (s *Server) ListenAndServe(host string, port int, svc inet.IService) error
 func (s *Server) serve() {
 	defer s.wg.Done()
 	for {
-		conn, err := s.listener.Accept(s.svc)
+		conn, err := s.listener.Accept(s.svc, s.cfg)
 		if err != nil {
 			if !errors.Is(err, net.ErrClosed) {
 				logx.Error(err)
 			}
 			return
 		}
-		s.svc.OnConnStop(conn)
+		s.svc.OnConnStart(conn)
 		logx.Debugf("new kcp conn %s", conn.RemoteAddr())
 		go s.handleConn(conn)
 	}
@@ -71,16 +78,16 @@ func (s *Server) handleConn(conn *Conn) {
 		}
 		// 读取包体
 		ok := func() bool {
-			packetBytes := bytex.Allocate(int(packetLen))
-			defer bytex.Return(packetBytes)
-			err = iox.ReadFixBytes(conn, packetBytes)
+			packetBuf := bytex.AllocateBuffer(int(packetLen))
+			defer packetBuf.Release()
+			err = iox.ReadFixBytes(conn, packetBuf.Bytes())
 			if err != nil {
 				if !errors.Is(err, net.ErrClosed) && !errors.Is(err, io.EOF) {
 					logx.Errorf("read conn %s err %+v", conn.RemoteAddr(), err)
 				}
 				return false
 			}
-			s.svc.OnConnRead(conn, packetBytes)
+			s.svc.OnConnRead(conn, packetBuf)
 			return true
 		}()
 		if !ok {
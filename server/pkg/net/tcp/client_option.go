@@ -0,0 +1,61 @@
+package tcp
+
+import (
+	"server/pkg/codec"
+	"server/pkg/net/clientx"
+	"server/pkg/trace"
+	zip2 "server/pkg/zip"
+)
+
+type ClientConfig struct {
+	requestOpts []clientx.RequestOption
+	zip         zip2.IZip
+	handshake   bool
+	serializers *codec.SerializerRegistry
+	tracer      *trace.Tracer
+}
+
+type ClientOption func(*ClientConfig)
+
+func WithRequestOptions(opts ...clientx.RequestOption) ClientOption {
+	return func(c *ClientConfig) {
+		c.requestOpts = opts
+	}
+}
+
+// WithZip 握手时声明支持的压缩算法，需要和服务端一致才会启用
+func WithZip(zip zip2.IZip) ClientOption {
+	return func(c *ClientConfig) {
+		c.zip = zip
+	}
+}
+
+// WithHandshake 连接老版本服务端时关闭握手
+func WithHandshake(enabled bool) ClientOption {
+	return func(c *ClientConfig) {
+		c.handshake = enabled
+	}
+}
+
+// WithSerializerRegistry 和服务端使用相同的注册表，路由绑定的序列化保持一致
+func WithSerializerRegistry(registry *codec.SerializerRegistry) ClientOption {
+	return func(c *ClientConfig) {
+		c.serializers = registry
+	}
+}
+
+// WithTracer 每个ask一个客户端span，trace通过请求元数据传给服务端
+func WithTracer(tracer *trace.Tracer) ClientOption {
+	return func(c *ClientConfig) {
+		c.tracer = tracer
+	}
+}
+
+func DefaultClientConfig() *ClientConfig {
+	return &ClientConfig{
+		requestOpts: []clientx.RequestOption{},
+		zip:         zip2.None{},
+		handshake:   true,
+		serializers: codec.DefaultSerializerRegistry(),
+	}
+}
@@ -2,13 +2,15 @@ package tcp
 
 import (
 	"encoding/binary"
+	"errors"
 	"hutool/bytex"
-	"hutool/iox"
 	"hutool/logx"
 	"net"
+	"server/pkg/net/batch"
 	"server/pkg/net/conn_id"
 	"server/pkg/net/inet"
 	"sync/atomic"
+	"time"
 )
 
 const MaxPacketLen = 4 * 1024
@@ -17,16 +19,26 @@ type Conn struct {
 	rawConn *net.TCPConn
 	connId  uint32
 	svc     inet.IService
-	closed  atomic.Bool
+	writer  *batch.Writer
+	// 写超时，防止慢客户端长期占用写协程
+	writeTimeout time.Duration
+	// 只在写协程中使用
+	heads  []byte
+	bufs   net.Buffers
+	closed atomic.Bool
 }
 
-func NewConn(rawConn *net.TCPConn, svc inet.IService) *Conn {
+func NewConn(rawConn *net.TCPConn, svc inet.IService, cfg *Config) *Conn {
 	c := &Conn{
-		rawConn: rawConn,
-		connId:  conn_id.NextId(),
-		closed:  atomic.Bool{},
-		svc:     svc,
+		rawConn:      rawConn,
+		connId:       conn_id.NextId(),
+		closed:       atomic.Bool{},
+		svc:          svc,
+		writeTimeout: cfg.writeTimeout,
 	}
+	// 对端读取时会拒绝超过MaxPacketLen的包，写入时提前拦截
+	writerOpts := append([]batch.Option{batch.WithMaxFrameLen(MaxPacketLen)}, cfg.writerOpts...)
+	c.writer = batch.NewWriter(c.flush, c.onWriteErr, writerOpts...)
 	return c
 }
 
@@ -43,20 +55,60 @@ func (c *Conn) RemoteAddr() string {
 }
 
 func (c *Conn) Write(b []byte) error {
-	packetLen := len(b)
-	packet := bytex.Allocate(packetLen + 4)
-	defer bytex.Return(packet)
-	binary.BigEndian.PutUint32(packet, uint32(packetLen))
-	copy(packet[4:], b)
-	err := iox.WriteLimit(c.rawConn, packet, MaxPacketLen)
+	return c.writer.Write(b)
+}
+
+func (c *Conn) WriteLowPriority(b []byte) error {
+	return c.writer.WriteLowPriority(b)
+}
+
+func (c *Conn) WriteBuffer(buf *bytex.Buffer) error {
+	return c.writer.WriteBuffer(buf)
+}
+
+func (c *Conn) WriterStats() batch.Stats {
+	return c.writer.Stats()
+}
+
+// flush 给每个包加上长度头，通过writev一次写出
+func (c *Conn) flush(frames [][]byte) error {
+	if cap(c.heads) < len(frames)*4 {
+		c.heads = make([]byte, len(frames)*4)
+	}
+	heads := c.heads[:len(frames)*4]
+	bufs := c.bufs[:0]
+	for i, frame := range frames {
+		head := heads[i*4 : i*4+4]
+		binary.BigEndian.PutUint32(head, uint32(len(frame)))
+		bufs = append(bufs, head, frame)
+	}
+	c.bufs = bufs
+	err := c.setWriteDeadline()
 	if err != nil {
 		return err
 	}
-	return nil
+	_, err = bufs.WriteTo(c.rawConn)
+	clear(c.bufs)
+	return err
+}
+
+func (c *Conn) setWriteDeadline() error {
+	if c.writeTimeout <= 0 {
+		return nil
+	}
+	return c.rawConn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+}
+
+func (c *Conn) onWriteErr(err error) {
+	if !errors.Is(err, net.ErrClosed) {
+		logx.Errorf("write conn %v err %+v", c.RemoteAddr(), err)
+	}
+	c.Close()
 }
 
 func (c *Conn) Close() {
 	if c.closed.CompareAndSwap(false, true) {
+		c.writer.Close()
 		err := c.rawConn.Close()
 		if err != nil {
 			logx.Errorf("tcp conn close err %+v", err)
@@ -15,12 +15,12 @@ func NewListener() *Listener {
 	return &Listener{}
 }
 
-func (l *Listener) Accept(svc inet.IService) (*Conn, error) {
+func (l *Listener) Accept(svc inet.IService, cfg *Config) (*Conn, error) {
 	rawConn, err := l.ln.AcceptTCP()
 	if err != nil {
 		return nil, err
 	}
-	conn := NewConn(rawConn, svc)
+	conn := NewConn(rawConn, svc, cfg)
 	return conn, nil
 }
 
@@ -35,10 +35,15 @@ func (l *Listener) Listen(host string, port int) error {
 		return err
 	}
 	l.ln = ln
-	logx.Infof("tcp listener start at %s", address)
+	logx.Infof("tcp listener start at %s", ln.Addr())
 	return nil
 }
 
+// Addr 实际监听的地址，端口为0时由系统分配
+func (l *Listener) Addr() net.Addr {
+	return l.ln.Addr()
+}
+
 func (l *Listener) Close() {
 	err := l.ln.Close()
 	if err != nil {
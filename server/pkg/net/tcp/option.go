@@ -0,0 +1,33 @@
+package tcp
+
+import (
+	"server/pkg/net/batch"
+	"time"
+)
+
+type Config struct {
+	writerOpts   []batch.Option
+	writeTimeout time.Duration
+}
+
+type Option func(*Config)
+
+func WithWriterOptions(opts ...batch.Option) Option {
+	return func(c *Config) {
+		c.writerOpts = opts
+	}
+}
+
+// WithWriteTimeout 单次写出的超时时间，超时后关闭连接，0表示不超时
+func WithWriteTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		c.writeTimeout = timeout
+	}
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		writerOpts:   []batch.Option{},
+		writeTimeout: 5 * time.Second,
+	}
+}
@@ -14,15 +14,21 @@ import (
 )
 
 type Server struct {
+	cfg      *Config
 	listener *Listener
 	cancel   context.CancelFunc
 	wg       sync.WaitGroup
 	svc      inet.IService
 }
 
-func NewServer() *Server {
+func NewServer(opts ...Option) *Server {
+	cfg := DefaultConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
 	return &Server{
-		wg: sync.WaitGroup{},
+		cfg: cfg,
+		wg:  sync.WaitGroup{},
 	}
 }
 
@@ -41,7 +47,7 @@ func (s *Server) ListenAndServe(host string, port int, svc inet.IService) error
 func (s *Server) serve() {
 	defer s.wg.Done()
 	for {
-		conn, err := s.listener.Accept(s.svc)
+		conn, err := s.listener.Accept(s.svc, s.cfg)
 		if err != nil {
 			if !errors.Is(err, net.ErrClosed) {
 				logx.Errorf("accept tcp err %+v", err)
@@ -76,16 +82,16 @@ func (s *Server) handleConn(conn *Conn) {
 		}
 		// 读取包体
 		ok := func() bool {
-			packetBytes := bytex.Allocate(int(packetLen))
-			defer bytex.Return(packetBytes)
-			err = iox.ReadFixBytes(conn, packetBytes)
+			packetBuf := bytex.AllocateBuffer(int(packetLen))
+			defer packetBuf.Release()
+			err = iox.ReadFixBytes(conn, packetBuf.Bytes())
 			if err != nil {
 				if !errors.Is(err, net.ErrClosed) && !errors.Is(err, io.EOF) {
 					logx.Errorf("read conn %s err %+v", conn.RemoteAddr(), err)
 				}
 				return false
 			}
-			s.svc.OnConnRead(conn, packetBytes)
+			s.svc.OnConnRead(conn, packetBuf)
 			return true
 		}()
 		if !ok {
@@ -94,6 +100,10 @@ func (s *Server) handleConn(conn *Conn) {
 	}
 }
 
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
 func (s *Server) Stop() {
 	s.listener.Close()
 	s.wg.Wait()
@@ -0,0 +1,139 @@
+package udp
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hutool/logx"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+type IHandler interface {
+	OnDatagram(connId uint32, data []byte)
+}
+
+type peer struct {
+	token   uint32
+	addr    atomic.Pointer[net.UDPAddr]
+	sendSeq atomic.Uint32
+	// 只在读协程访问
+	recvFilter seqFilter
+}
+
+// Channel 服务端的不可靠通道，每个peer通过connId与一条可靠连接配对
+type Channel struct {
+	conn    *net.UDPConn
+	peers   sync.Map
+	handler IHandler
+	wg      sync.WaitGroup
+}
+
+func NewChannel() *Channel {
+	return &Channel{
+		peers: sync.Map{},
+		wg:    sync.WaitGroup{},
+	}
+}
+
+func (c *Channel) Listen(host string, port int, handler IHandler) error {
+	addr, err := net.ResolveUDPAddr("udp", fmt.Sprintf("%s:%d", host, port))
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return err
+	}
+	c.conn = conn
+	c.handler = handler
+	c.wg.Add(1)
+	go c.serve()
+	logx.Infof("udp channel start at %s", conn.LocalAddr())
+	return nil
+}
+
+// Bind 为连接生成token，客户端需要带上connId和token才能使用该通道
+func (c *Channel) Bind(connId uint32) uint32 {
+	p := &peer{token: randToken()}
+	c.peers.Store(connId, p)
+	return p.token
+}
+
+func (c *Channel) Unbind(connId uint32) {
+	c.peers.Delete(connId)
+}
+
+func (c *Channel) Write(connId uint32, data []byte) error {
+	v, ok := c.peers.Load(connId)
+	if !ok {
+		return NotBoundErr
+	}
+	p := v.(*peer)
+	addr := p.addr.Load()
+	if addr == nil {
+		return NotBoundErr
+	}
+	datagram, err := encodeDatagram(connId, p.token, p.sendSeq.Add(1), data)
+	if err != nil {
+		return err
+	}
+	_, err = c.conn.WriteToUDP(datagram, addr)
+	return err
+}
+
+func (c *Channel) serve() {
+	defer c.wg.Done()
+	buf := make([]byte, MaxDatagramLen)
+	for {
+		n, addr, err := c.conn.ReadFromUDP(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				logx.Errorf("read udp err %+v", err)
+			}
+			return
+		}
+		connId, token, seq, payload, err := decodeDatagram(buf[:n])
+		if err != nil {
+			continue
+		}
+		v, ok := c.peers.Load(connId)
+		if !ok {
+			continue
+		}
+		p := v.(*peer)
+		if p.token != token {
+			continue
+		}
+		if !p.recvFilter.accept(seq) {
+			continue
+		}
+		// 客户端地址可能因为nat变化，以最新的包为准
+		p.addr.Store(addr)
+		if len(payload) == 0 {
+			continue
+		}
+		data := make([]byte, len(payload))
+		copy(data, payload)
+		c.handler.OnDatagram(connId, data)
+	}
+}
+
+func (c *Channel) Close() {
+	if c.conn == nil {
+		return
+	}
+	err := c.conn.Close()
+	if err != nil {
+		logx.Errorf("udp channel close err %+v", err)
+	}
+	c.wg.Wait()
+}
+
+func randToken() uint32 {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return binary.BigEndian.Uint32(b)
+}
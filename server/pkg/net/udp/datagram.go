@@ -0,0 +1,67 @@
+package udp
+
+import (
+	"encoding/binary"
+)
+
+// MaxDatagramLen 不超过常见MTU，避免ip分片
+const MaxDatagramLen = 1200
+
+const headLen = 12
+
+// datagram: connId(4) + token(4) + seq(4) + payload
+func encodeDatagram(connId uint32, token uint32, seq uint32, payload []byte) ([]byte, error) {
+	if len(payload)+headLen > MaxDatagramLen {
+		return nil, DatagramLenErr
+	}
+	bytes := make([]byte, headLen+len(payload))
+	binary.BigEndian.PutUint32(bytes[0:4], connId)
+	binary.BigEndian.PutUint32(bytes[4:8], token)
+	binary.BigEndian.PutUint32(bytes[8:12], seq)
+	copy(bytes[headLen:], payload)
+	return bytes, nil
+}
+
+func decodeDatagram(bytes []byte) (connId uint32, token uint32, seq uint32, payload []byte, err error) {
+	if len(bytes) < headLen {
+		return 0, 0, 0, nil, DatagramLenErr
+	}
+	connId = binary.BigEndian.Uint32(bytes[0:4])
+	token = binary.BigEndian.Uint32(bytes[4:8])
+	seq = binary.BigEndian.Uint32(bytes[8:12])
+	return connId, token, seq, bytes[headLen:], nil
+}
+
+// seqAfter 序号回绕安全的比较，a比b新返回true
+func seqAfter(a uint32, b uint32) bool {
+	return int32(a-b) > 0
+}
+
+type seqFilter struct {
+	last     uint32
+	received bool
+}
+
+// accept 丢弃过期或重复的包
+func (f *seqFilter) accept(seq uint32) bool {
+	if f.received && !seqAfter(seq, f.last) {
+		return false
+	}
+	f.last = seq
+	f.received = true
+	return true
+}
+
+func EncodeBind(connId uint32, token uint32) []byte {
+	bytes := make([]byte, 8)
+	binary.BigEndian.PutUint32(bytes[0:4], connId)
+	binary.BigEndian.PutUint32(bytes[4:8], token)
+	return bytes
+}
+
+func DecodeBind(bytes []byte) (connId uint32, token uint32, err error) {
+	if len(bytes) != 8 {
+		return 0, 0, DatagramLenErr
+	}
+	return binary.BigEndian.Uint32(bytes[0:4]), binary.BigEndian.Uint32(bytes[4:8]), nil
+}
@@ -0,0 +1,49 @@
+package udp
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestDatagramEncodeDecode(t *testing.T) {
+	payload := []byte("hello")
+	datagram, err := encodeDatagram(7, 0xdeadbeef, 42, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	connId, token, seq, got, err := decodeDatagram(datagram)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if connId != 7 || token != 0xdeadbeef || seq != 42 || !bytes.Equal(got, payload) {
+		t.Fatalf("decode mismatch %d %x %d %q", connId, token, seq, got)
+	}
+
+	_, err = encodeDatagram(1, 1, 1, make([]byte, MaxDatagramLen-headLen+1))
+	if !errors.Is(err, DatagramLenErr) {
+		t.Fatalf("oversized payload err %v", err)
+	}
+	_, _, _, _, err = decodeDatagram(make([]byte, headLen-1))
+	if !errors.Is(err, DatagramLenErr) {
+		t.Fatalf("short datagram err %v", err)
+	}
+}
+
+func TestSeqFilterWrapAround(t *testing.T) {
+	f := seqFilter{}
+	if !f.accept(math.MaxUint32 - 1) {
+		t.Fatal("first seq should be accepted")
+	}
+	if !f.accept(math.MaxUint32) {
+		t.Fatal("next seq should be accepted")
+	}
+	// 回绕后的序号比回绕前新
+	if !f.accept(0) || !f.accept(2) {
+		t.Fatal("wrapped seq should be accepted")
+	}
+	if f.accept(2) || f.accept(1) || f.accept(math.MaxUint32) {
+		t.Fatal("stale or duplicate seq should be dropped")
+	}
+}
@@ -0,0 +1,10 @@
+package udp
+
+import "errors"
+
+var (
+	NotBoundErr      = errors.New("udp peer not bound")
+	BindTimeoutErr   = errors.New("udp bind timeout")
+	DatagramLenErr   = errors.New("datagram len error")
+	DatagramTokenErr = errors.New("datagram token error")
+)
@@ -0,0 +1,130 @@
+package udp
+
+import (
+	"errors"
+	"fmt"
+	"hutool/logx"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Peer 客户端的不可靠通道
+type Peer struct {
+	conn       *net.UDPConn
+	connId     uint32
+	token      uint32
+	sendSeq    atomic.Uint32
+	recvFilter seqFilter
+	wg         sync.WaitGroup
+}
+
+func NewPeer(connId uint32, token uint32) *Peer {
+	return &Peer{
+		connId: connId,
+		token:  token,
+		wg:     sync.WaitGroup{},
+	}
+}
+
+func (p *Peer) Dial(host string, port int, onRead func(data []byte)) error {
+	addr, err := net.ResolveUDPAddr("udp", fmt.Sprintf("%s:%d", host, port))
+	if err != nil {
+		return err
+	}
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		return err
+	}
+	p.conn = conn
+	p.wg.Add(1)
+	go p.serve(onRead)
+	// 空包用于让服务端记录地址
+	return p.Ping()
+}
+
+func (p *Peer) Ping() error {
+	return p.Write(nil)
+}
+
+func (p *Peer) Write(data []byte) error {
+	datagram, err := encodeDatagram(p.connId, p.token, p.sendSeq.Add(1), data)
+	if err != nil {
+		return err
+	}
+	_, err = p.conn.Write(datagram)
+	return err
+}
+
+func (p *Peer) serve(onRead func(data []byte)) {
+	defer p.wg.Done()
+	buf := make([]byte, MaxDatagramLen)
+	for {
+		n, err := p.conn.Read(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			// 对端端口未开放时会收到icmp错误，忽略继续读
+			logx.Debugf("read udp err %+v", err)
+			continue
+		}
+		connId, token, seq, payload, err := decodeDatagram(buf[:n])
+		if err != nil || connId != p.connId || token != p.token {
+			continue
+		}
+		if !p.recvFilter.accept(seq) || len(payload) == 0 {
+			continue
+		}
+		data := make([]byte, len(payload))
+		copy(data, payload)
+		onRead(data)
+	}
+}
+
+func (p *Peer) Close() {
+	if p.conn == nil {
+		return
+	}
+	_ = p.conn.Close()
+	p.wg.Wait()
+}
+
+// Binding 保存服务端下发的绑定信息
+type Binding struct {
+	connId uint32
+	token  uint32
+	done   chan struct{}
+	once   sync.Once
+}
+
+func NewBinding() *Binding {
+	return &Binding{
+		done: make(chan struct{}),
+	}
+}
+
+func (b *Binding) Set(body []byte) error {
+	connId, token, err := DecodeBind(body)
+	if err != nil {
+		return err
+	}
+	b.once.Do(func() {
+		b.connId = connId
+		b.token = token
+		close(b.done)
+	})
+	return nil
+}
+
+func (b *Binding) Wait(timeout time.Duration) (uint32, uint32, error) {
+	tm := time.NewTimer(timeout)
+	defer tm.Stop()
+	select {
+	case <-b.done:
+		return b.connId, b.token, nil
+	case <-tm.C:
+		return 0, 0, BindTimeoutErr
+	}
+}
@@ -4,9 +4,10 @@ import (
 	"context"
 	"fmt"
 	"hutool/logx"
-	"hutool/reflectx"
-	"reflect"
 	"server/pkg/codec"
+	"server/pkg/net/clientx"
+	"server/pkg/net/udp"
+	"server/pkg/trace"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -17,24 +18,44 @@ import (
 type Client struct {
 	reqId             atomic.Uint32
 	rawConn           *websocket.Conn
-	rspMsgHandlerMap  sync.Map
-	pushMsgHandlerMap map[uint32]map[uint32]S2CMsgHandler
+	requests          *clientx.RequestTable
+	pushRegistry      *clientx.PushRegistry
+	askHandlers       *clientx.AskHandlerRegistry
+	negotiation       *clientx.Negotiation
+	handshake         bool
 	heartbeatInterval time.Duration
-	serializer        codec.ISerializer
+	serializers       *clientx.Serializers
+	tracer            *trace.Tracer
+	cfg               *ClientConfig
+	writeMu           sync.Mutex
+	compressor        compressor
+	udpBinding        *udp.Binding
+	kick              clientx.KickNotifier
+	udpPeer           atomic.Pointer[udp.Peer]
 	ctx               context.Context
 	cancel            context.CancelFunc
 	wg                sync.WaitGroup
 }
 
-func NewClient(serializer codec.ISerializer, heartbeatInterval time.Duration) *Client {
+func NewClient(serializer codec.ISerializer, heartbeatInterval time.Duration, opts ...ClientOption) *Client {
+	cfg := DefaultClientConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 	c := &Client{
 		reqId:             atomic.Uint32{},
 		rawConn:           nil,
-		rspMsgHandlerMap:  sync.Map{},
-		pushMsgHandlerMap: make(map[uint32]map[uint32]S2CMsgHandler),
+		requests:          clientx.NewRequestTable(cfg.requestOpts...),
+		pushRegistry:      clientx.NewPushRegistry(),
+		askHandlers:       clientx.NewAskHandlerRegistry(),
+		negotiation:       clientx.NewNegotiation(cfg.zip),
+		handshake:         cfg.handshake,
 		heartbeatInterval: heartbeatInterval,
-		serializer:        serializer,
+		serializers:       clientx.NewSerializers(cfg.serializers, serializer),
+		tracer:            cfg.tracer,
+		cfg:               cfg,
+		udpBinding:        udp.NewBinding(),
 		ctx:               ctx,
 		cancel:            cancel,
 		wg:                sync.WaitGroup{},
@@ -43,44 +64,95 @@ func NewClient(serializer codec.ISerializer, heartbeatInterval time.Duration) *C
 }
 
 func (c *Client) Dial(host string, port int) error {
-	url := fmt.Sprintf("ws://%s:%d/ws", host, port)
+	url := fmt.Sprintf("ws://%s:%d%s", host, port, c.cfg.path)
 
 	dialer := websocket.Dialer{
-		HandshakeTimeout: 5 * time.Second,
+		HandshakeTimeout:  5 * time.Second,
+		EnableCompression: c.cfg.compression,
 	}
 
-	rawConn, _, err := dialer.Dial(url, nil)
+	rawConn, rsp, err := dialer.Dial(url, c.cfg.header)
 	if err != nil {
 		return err
 	}
 	c.rawConn = rawConn
+	negotiated := c.cfg.compression && deflateNegotiated(rsp.Header)
+	c.compressor = newCompressor(rawConn, negotiated, c.cfg.compressionLevel, c.cfg.compressionMinSize)
 	c.wg.Add(1)
 	go c.handleMsgFromServer()
 	c.wg.Add(1)
 	go c.keepAlive()
 
+	if c.handshake {
+		err = c.doHandshake()
+		if err != nil {
+			c.Close()
+			return err
+		}
+	}
 	return nil
 }
 
-type S2CMsgHandler struct {
-	msgType reflect.Type
-	handler func(msg any)
+func (c *Client) doHandshake() error {
+	handler := c.negotiation.Handler()
+	reqId, err := c.requests.Begin(handler)
+	if err != nil {
+		return err
+	}
+	hs := c.negotiation.Request(c.serializers.Id())
+	reqPacket := codec.NewC2SReqPacket(codec.SysServiceId, codec.SysRouterHandshake, reqId, false, hs.Encode())
+	err = c.writeToServer(reqPacket.Bytes())
+	if err != nil {
+		c.requests.Cancel(reqId)
+		return err
+	}
+	_, err = c.negotiation.Wait(handshakeTimeout)
+	if err != nil {
+		c.requests.Cancel(reqId)
+	}
+	return err
+}
+
+// Handshake 握手协商的结果
+func (c *Client) Handshake() (codec.HandshakeRsp, bool) {
+	return c.negotiation.Result()
 }
 
-func (c *Client) ask(serviceId uint32, routerId uint32, reqBody any, handler S2CMsgHandler) error {
-	reqId := c.reqId.Add(1)
-	reqBodyBytes, err := c.serializer.Marshal(reqBody)
+const (
+	udpBindTimeout   = 3 * time.Second
+	handshakeTimeout = 3 * time.Second
+)
+
+func (c *Client) ask(ctx context.Context, serviceId uint32, routerId uint32, reqBody any, handler clientx.MsgHandler) error {
+	md, span := clientx.StartAskSpan(ctx, c.tracer, serviceId, routerId)
+	serializer := c.serializers.For(serviceId, routerId)
+	reqBodyBytes, err := serializer.Marshal(reqBody)
 	if err != nil {
+		span.SetError(err)
+		span.End()
 		return err
 	}
-	reqPacket := codec.NewC2SReqPacket(serviceId, routerId, reqId, false, reqBodyBytes)
-	c.rspMsgHandlerMap.Store(reqId, handler)
-	return c.writeToServer(reqPacket.Bytes())
+	reqId, err := c.requests.Begin(handler.WithSerializer(serializer).WithSpan(span))
+	if err != nil {
+		span.SetError(err)
+		span.End()
+		return err
+	}
+	reqPacket, err := codec.NewC2SReqPacketWithMeta(serviceId, routerId, reqId, false, md, reqBodyBytes)
+	if err == nil {
+		err = c.writeToServer(reqPacket.Bytes())
+	}
+	if err != nil {
+		span.SetError(err)
+		span.End()
+		c.requests.Cancel(reqId)
+	}
+	return err
 }
 
 func (c *Client) tell(serviceId uint32, routerId uint32, reqBody any) error {
 	reqId := c.reqId.Add(1)
-	reqBodyBytes, err := c.serializer.Marshal(reqBody)
+	reqBodyBytes, err := c.serializers.For(serviceId, routerId).Marshal(reqBody)
 	if err != nil {
 		return err
 	}
@@ -88,6 +160,21 @@ func (c *Client) tell(serviceId uint32, routerId uint32, reqBody any) error {
 	return c.writeToServer(reqPacket.Bytes())
 }
 
+// tellUnreliable 未连接不可靠通道时走可靠连接
+func (c *Client) tellUnreliable(serviceId uint32, routerId uint32, reqBody any) error {
+	peer := c.udpPeer.Load()
+	if peer == nil {
+		return c.tell(serviceId, routerId, reqBody)
+	}
+	reqId := c.reqId.Add(1)
+	reqBodyBytes, err := c.serializers.For(serviceId, routerId).Marshal(reqBody)
+	if err != nil {
+		return err
+	}
+	reqPacket := codec.NewC2SReqPacket(serviceId, routerId, reqId, true, reqBodyBytes)
+	return peer.Write(reqPacket.Bytes())
+}
+
 func (c *Client) handleMsgFromServer() {
 	defer c.wg.Done()
 	for {
@@ -107,6 +194,11 @@ func (c *Client) handleMsgFromServer() {
 			logx.Errorf("packet len %d too large", len(message))
 			continue
 		}
+		message, err = c.negotiation.Unzip(message)
+		if err != nil {
+			logx.Errorf("unzip err %+v", err)
+			continue
+		}
 		c.handleOnMsg(message)
 	}
 }
@@ -117,45 +209,77 @@ func (c *Client) handleOnMsg(readData []byte) {
 		logx.Errorf("unmarshal err %+v", err)
 		return
 	}
+	if msgPacket.IsAskPacket() {
+		c.handleServerAsk(msgPacket)
+		return
+	}
 	isPushPacket := msgPacket.IsPushPacket()
 	msgBodyBytes := msgPacket.Body()
 	if isPushPacket {
 		serviceId := msgPacket.ServiceId()
 		routerId := msgPacket.RouterId()
-		handler, ok := c.getPushHandler(serviceId, routerId)
-		if !ok {
-			return
-		}
-		msgBody := reflectx.NewPointerIns(handler.msgType)
-		err := c.serializer.Unmarshal(msgBodyBytes, msgBody)
-		if err != nil {
-			logx.Errorf("unmarshal err %+v", err)
+		if serviceId == codec.SysServiceId {
+			c.handleSysPush(routerId, msgBodyBytes)
 			return
 		}
-		handler.handler(msgBody)
+		c.pushRegistry.Dispatch(serviceId, routerId, msgBodyBytes, c.serializers.For(serviceId, routerId))
 	} else {
 		reqId := msgPacket.ReqId()
-		handler, ok := c.getRspMsgHandler(reqId)
+		handler, ok := c.requests.Complete(reqId)
 		if !ok {
 			return
 		}
-		msgBody := reflectx.NewPointerIns(handler.msgType)
-		err := c.serializer.Unmarshal(msgBodyBytes, msgBody)
+		md, err := msgPacket.Metadata()
 		if err != nil {
-			logx.Errorf("unmarshal err %+v", err)
-			return
+			logx.Errorf("rsp metadata err %+v", err)
 		}
-		handler.handler(msgBody)
-		c.rspMsgHandlerMap.Delete(reqId)
+		handler.HandleWithMeta(msgBodyBytes, md, c.serializers.Default())
 	}
 }
 
-func (c *Client) getRspMsgHandler(reqId uint32) (S2CMsgHandler, bool) {
-	handler, ok := c.rspMsgHandlerMap.Load(reqId)
+// handleServerAsk 在读协程中处理，处理器需要尽快返回
+func (c *Client) handleServerAsk(askPacket codec.S2CPacket) {
+	rspBodyBytes, ok := c.askHandlers.Handle(askPacket.ServiceId(), askPacket.RouterId(), askPacket.Body(), c.serializers.For(askPacket.ServiceId(), askPacket.RouterId()))
 	if !ok {
-		return S2CMsgHandler{}, false
+		return
+	}
+	rspPacket := codec.NewC2SRspPacket(askPacket.ReqId(), rspBodyBytes)
+	err := c.writeToServer(rspPacket.Bytes())
+	if err != nil {
+		logx.Errorf("rsp server ask err %+v", err)
+	}
+}
+
+func (c *Client) handleSysPush(routerId uint32, body []byte) {
+	switch routerId {
+	case codec.SysRouterUdpBind:
+		err := c.udpBinding.Set(body)
+		if err != nil {
+			logx.Errorf("udp bind err %+v", err)
+		}
+	case codec.SysRouterKick:
+		c.kick.Notify(body)
+	}
+}
+
+// OnKick 设置被服务端踢下线的回调
+func (c *Client) OnKick(handler clientx.KickHandler) {
+	c.kick.Set(handler)
+}
+
+// DialUnreliable 连接服务端的不可靠通道，需要在Dial之后调用
+func (c *Client) DialUnreliable(host string, port int) error {
+	connId, token, err := c.udpBinding.Wait(udpBindTimeout)
+	if err != nil {
+		return err
+	}
+	peer := udp.NewPeer(connId, token)
+	err = peer.Dial(host, port, c.handleOnMsg)
+	if err != nil {
+		return err
 	}
-	return handler.(S2CMsgHandler), true
+	c.udpPeer.Store(peer)
+	return nil
 }
 
 func (c *Client) keepAlive() {
@@ -169,61 +293,72 @@ func (c *Client) keepAlive() {
 		case <-tk.C:
 			heartBeatPacket := codec.NewC2SHeartBeatPacket()
 			_ = c.writeToServer(heartBeatPacket.Bytes())
+			if peer := c.udpPeer.Load(); peer != nil {
+				_ = peer.Ping()
+			}
 		}
 	}
 }
 
 func (c *Client) writeToServer(data []byte) error {
-	return c.rawConn.WriteMessage(websocket.BinaryMessage, data)
+	err := c.negotiation.CheckLen(len(data))
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.compressor.writeMessage(c.rawConn, websocket.BinaryMessage, data)
 }
 
 func (c *Client) Close() {
 	c.cancel()
+	if peer := c.udpPeer.Load(); peer != nil {
+		peer.Close()
+	}
 	if c.rawConn != nil {
 		_ = c.rawConn.Close()
 	}
 	c.wg.Wait()
+	c.requests.Close()
+	c.pushRegistry.Close()
+}
+
+func (c *Client) RequestStats() clientx.RequestStats {
+	return c.requests.Stats()
 }
 
+// Ask 请求被拒绝、超时或连接关闭时handler不会被调用，需要感知失败时使用AskWithErr
 func Ask[Req any, Rsp any](client *Client, serviceId uint32, routerId uint32, req Req, handler func(rsp Rsp)) error {
-	return client.ask(serviceId, routerId, req, S2CMsgHandler{
-		msgType: reflectx.GenericTypeOf[Rsp](),
-		handler: func(msg any) {
-			handler(msg.(Rsp))
-		},
-	})
+	return client.ask(context.Background(), serviceId, routerId, req, clientx.NewMsgHandler(handler))
+}
+
+// AskWithErr 成功时调用handler，被拒绝、解析失败、超时或连接关闭时调用onErr，两者只会调用一个
+func AskWithErr[Req any, Rsp any](client *Client, serviceId uint32, routerId uint32, req Req, handler func(rsp Rsp), onErr func(err error)) error {
+	return client.ask(context.Background(), serviceId, routerId, req, clientx.NewMsgHandler(handler).WithErrHandler(onErr))
+}
+
+// AskContext 上下文中的元数据、截止时间和trace随请求发送，响应的元数据传给handler
+func AskContext[Req any, Rsp any](ctx context.Context, client *Client, serviceId uint32, routerId uint32, req Req, handler func(rsp Rsp, md codec.Metadata)) error {
+	return client.ask(ctx, serviceId, routerId, req, clientx.NewMetaMsgHandler(handler))
 }
 
 func Tell[Req any](client *Client, serviceId uint32, routerId uint32, req any) error {
 	return client.tell(serviceId, routerId, req)
 }
 
-func RegisterPushHandler[Push any](client *Client, serviceId uint32, routerId uint32, handler func(push Push)) {
-	client.registerPushHandler(serviceId, routerId, S2CMsgHandler{
-		msgType: reflectx.GenericTypeOf[Push](),
-		handler: func(msg any) {
-			handler(msg.(Push))
-		},
-	})
+func TellUnreliable[Req any](client *Client, serviceId uint32, routerId uint32, req Req) error {
+	return client.tellUnreliable(serviceId, routerId, req)
 }
 
-func (c *Client) registerPushHandler(serviceId uint32, routerId uint32, handler S2CMsgHandler) {
-	serviceMap, ok := c.pushMsgHandlerMap[serviceId]
-	if !ok {
-		serviceMap = make(map[uint32]S2CMsgHandler)
-		c.pushMsgHandlerMap[serviceId] = serviceMap
-	}
-	serviceMap[routerId] = handler
+func RegisterPushHandler[Push any](client *Client, serviceId uint32, routerId uint32, handler func(push Push)) *clientx.Subscription {
+	return clientx.RegisterPushHandler[Push](client.pushRegistry, serviceId, routerId, handler)
 }
 
-func (c *Client) getPushHandler(serviceId uint32, routerId uint32) (S2CMsgHandler, bool) {
-	serviceMap, ok := c.pushMsgHandlerMap[serviceId]
-	if !ok {
-		return S2CMsgHandler{}, false
-	}
-	msgHandler, ok := serviceMap[routerId]
-	if !ok {
-		return S2CMsgHandler{}, false
-	}
-	return msgHandler, true
+// RegisterAskHandler 注册服务端请求的处理器，返回值作为响应
+func RegisterAskHandler[Req any, Rsp any](client *Client, serviceId uint32, routerId uint32, handler func(req Req) Rsp) {
+	clientx.RegisterAskHandler[Req, Rsp](client.askHandlers, serviceId, routerId, handler)
+}
+
+func Subscribe[Push any](client *Client, serviceId uint32, routerId uint32, opts ...clientx.SubscribeOption) (<-chan Push, *clientx.Subscription) {
+	return clientx.Subscribe[Push](client.pushRegistry, serviceId, routerId, opts...)
 }
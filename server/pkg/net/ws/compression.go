@@ -0,0 +1,42 @@
+package ws
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// deflateNegotiated 握手头中是否带有permessage-deflate扩展
+func deflateNegotiated(header http.Header) bool {
+	for _, value := range header.Values("Sec-WebSocket-Extensions") {
+		for _, ext := range strings.Split(value, ",") {
+			name, _, _ := strings.Cut(ext, ";")
+			if strings.TrimSpace(name) == "permessage-deflate" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// compressor 控制每条消息是否压缩，小包压缩收益低于开销
+type compressor struct {
+	enabled bool
+	minSize int
+}
+
+func newCompressor(rawConn *websocket.Conn, negotiated bool, level int, minSize int) compressor {
+	if !negotiated {
+		return compressor{}
+	}
+	_ = rawConn.SetCompressionLevel(level)
+	return compressor{enabled: true, minSize: minSize}
+}
+
+func (c compressor) writeMessage(rawConn *websocket.Conn, messageType int, data []byte) error {
+	if c.enabled {
+		rawConn.EnableWriteCompression(len(data) >= c.minSize)
+	}
+	return rawConn.WriteMessage(messageType, data)
+}
@@ -1,10 +1,16 @@
 package ws
 
 import (
+	"errors"
+	"hutool/bytex"
 	"hutool/logx"
+	"net"
+	"server/pkg/codec"
+	"server/pkg/net/batch"
 	"server/pkg/net/conn_id"
 	"server/pkg/net/inet"
 	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -15,16 +21,29 @@ type Conn struct {
 	rawConn *websocket.Conn
 	connId  uint32
 	svc     inet.IService
-	closed  atomic.Bool
+	writer  *batch.Writer
+	// 写超时，防止慢客户端长期占用写协程
+	writeTimeout time.Duration
+	// 升级时附带的属性
+	attrs map[string]any
+	// 客户端使用json文本协议
+	textMode atomic.Bool
+	// 只在写协程中使用
+	compressor compressor
+	closed     atomic.Bool
 }
 
-func NewConn(rawConn *websocket.Conn, svc inet.IService) *Conn {
+func NewConn(rawConn *websocket.Conn, svc inet.IService, cfg *Config) *Conn {
 	c := &Conn{
-		rawConn: rawConn,
-		connId:  conn_id.NextId(),
-		closed:  atomic.Bool{},
-		svc:     svc,
+		rawConn:      rawConn,
+		connId:       conn_id.NextId(),
+		closed:       atomic.Bool{},
+		svc:          svc,
+		writeTimeout: cfg.writeTimeout,
 	}
+	// 对端读取时会拒绝超过MaxPacketLen的消息，写入时提前拦截
+	writerOpts := append([]batch.Option{batch.WithMaxFrameLen(MaxPacketLen)}, cfg.writerOpts...)
+	c.writer = batch.NewWriter(c.flush, c.onWriteErr, writerOpts...)
 	return c
 }
 
@@ -36,12 +55,87 @@ func (c *Conn) RemoteAddr() string {
 	return c.rawConn.RemoteAddr().String()
 }
 
+func (c *Conn) Attrs() map[string]any {
+	return c.attrs
+}
+
+// Compressed 已协商permessage-deflate，上层不需要再压缩
+func (c *Conn) Compressed() bool {
+	return c.compressor.enabled
+}
+
 func (c *Conn) Write(data []byte) error {
-	return c.rawConn.WriteMessage(websocket.BinaryMessage, data)
+	return c.writer.Write(data)
+}
+
+func (c *Conn) WriteLowPriority(data []byte) error {
+	return c.writer.WriteLowPriority(data)
+}
+
+func (c *Conn) TextMode() bool {
+	return c.textMode.Load()
+}
+
+func (c *Conn) WriteBuffer(buf *bytex.Buffer) error {
+	return c.writer.WriteBuffer(buf)
+}
+
+func (c *Conn) WriterStats() batch.Stats {
+	return c.writer.Stats()
+}
+
+// flush websocket每个包是一条消息，无法合并，只省去逐包调度的开销
+func (c *Conn) flush(frames [][]byte) error {
+	err := c.setWriteDeadline()
+	if err != nil {
+		return err
+	}
+	textMode := c.textMode.Load()
+	for _, frame := range frames {
+		if textMode {
+			err = c.writeText(frame)
+		} else {
+			err = c.compressor.writeMessage(c.rawConn, websocket.BinaryMessage, frame)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Conn) writeText(frame []byte) error {
+	packet, err := codec.BytesToS2CPacket(frame)
+	if err != nil {
+		logx.Errorf("text conn %v packet err %+v", c.RemoteAddr(), err)
+		return nil
+	}
+	text, err := codec.S2CPacketToJson(packet)
+	if err != nil {
+		// 元数据损坏的包直接丢弃，不影响连接
+		logx.Warnf("text conn %v drop packet %+v", c.RemoteAddr(), err)
+		return nil
+	}
+	return c.compressor.writeMessage(c.rawConn, websocket.TextMessage, text)
+}
+
+func (c *Conn) setWriteDeadline() error {
+	if c.writeTimeout <= 0 {
+		return nil
+	}
+	return c.rawConn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+}
+
+func (c *Conn) onWriteErr(err error) {
+	if !errors.Is(err, net.ErrClosed) && !errors.Is(err, websocket.ErrCloseSent) {
+		logx.Errorf("write conn %v err %+v", c.RemoteAddr(), err)
+	}
+	c.Close()
 }
 
 func (c *Conn) Close() {
 	if c.closed.CompareAndSwap(false, true) {
+		c.writer.Close()
 		err := c.rawConn.Close()
 		if err != nil {
 			logx.Errorf("ws conn close err %+v", err)
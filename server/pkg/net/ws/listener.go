@@ -8,83 +8,136 @@ import (
 	"net"
 	"net/http"
 	"server/pkg/net/inet"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
 
+type acceptedConn struct {
+	rawConn    *websocket.Conn
+	attrs      map[string]any
+	compressed bool
+}
+
 type Listener struct {
 	httpServer *http.Server
+	ln         net.Listener
 	upgrader   websocket.Upgrader
-	connChan   chan *websocket.Conn
+	cfg        *Config
+	connChan   chan acceptedConn
 	ctx        context.Context
 	cancel     context.CancelFunc
 }
 
-func NewListener() *Listener {
+func NewListener(cfg *Config) *Listener {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Listener{
+		cfg:      cfg,
 		ctx:      ctx,
 		cancel:   cancel,
-		connChan: make(chan *websocket.Conn, 10),
+		connChan: make(chan acceptedConn, cfg.acceptQueueSize),
 	}
 }
 
-func (l *Listener) Accept(svc inet.IService) (*Conn, error) {
+func (l *Listener) Accept(svc inet.IService, cfg *Config) (*Conn, error) {
 	select {
 	case <-l.ctx.Done():
 		return nil, net.ErrClosed
-	case rawConn := <-l.connChan:
-		if rawConn == nil {
-			return nil, net.ErrClosed
-		}
-		conn := NewConn(rawConn, svc)
+	case accepted := <-l.connChan:
+		conn := NewConn(accepted.rawConn, svc, cfg)
+		conn.attrs = accepted.attrs
+		conn.compressor = newCompressor(accepted.rawConn, accepted.compressed, cfg.compressionLevel, cfg.compressionMinSize)
 		return conn, nil
 	}
 }
 
 func (l *Listener) Listen(host string, port int, upgrader websocket.Upgrader) error {
-	address := fmt.Sprintf("%s:%d", host, port)
+	l.upgrader = upgrader
+	l.upgrader.EnableCompression = l.cfg.compression
+	if l.cfg.mux != nil {
+		l.cfg.mux.HandleFunc(l.cfg.path, l.handleWebSocket)
+		logx.Infof("ws listener mount at %s", l.cfg.path)
+		return nil
+	}
 
+	address := fmt.Sprintf("%s:%d", host, port)
 	mux := http.NewServeMux()
-	mux.HandleFunc("/ws", l.handleWebSocket)
+	mux.HandleFunc(l.cfg.path, l.handleWebSocket)
 	l.httpServer = &http.Server{
 		Addr:    address,
 		Handler: mux,
 	}
-	l.upgrader = upgrader
+	// 同步监听，返回后就可以连接，端口冲突也能直接返回
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	l.ln = ln
 
 	go func() {
-		err := l.httpServer.ListenAndServe()
+		err := l.httpServer.Serve(ln)
 		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			logx.Errorf("ws listener err %+v", err)
 		}
 	}()
 
-	logx.Infof("ws listener start %s", address)
+	logx.Infof("ws listener start %s%s", ln.Addr(), l.cfg.path)
 	return nil
 }
 
+func (l *Listener) Addr() net.Addr {
+	if l.ln == nil {
+		return nil
+	}
+	return l.ln.Addr()
+}
+
 func (l *Listener) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	if l.ctx.Err() != nil {
+		http.Error(w, "ws listener closed", http.StatusServiceUnavailable)
+		return
+	}
+	var attrs map[string]any
+	if l.cfg.upgradeHook != nil {
+		var err error
+		attrs, err = l.cfg.upgradeHook(r)
+		if err != nil {
+			logx.Infof("reject ws conn %s %+v", r.RemoteAddr, err)
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
 	rawConn, err := l.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		logx.Errorf("upgrade err %+v", err)
 		return
 	}
+
+	accepted := acceptedConn{
+		rawConn:    rawConn,
+		attrs:      attrs,
+		compressed: l.cfg.compression && deflateNegotiated(r.Header),
+	}
+	// 接收队列满时短暂等待，而不是直接拒绝
+	tm := time.NewTimer(l.cfg.acceptTimeout)
+	defer tm.Stop()
 	select {
-	case l.connChan <- rawConn:
+	case l.connChan <- accepted:
 	case <-l.ctx.Done():
-		rawConn.Close()
-	default:
-		logx.Errorf("ws listener too many conn")
-		rawConn.Close()
+		_ = rawConn.Close()
+	case <-tm.C:
+		logx.Errorf("ws listener accept timeout %s", r.RemoteAddr)
+		_ = rawConn.Close()
 	}
 }
 
 func (l *Listener) Close() {
-	close(l.connChan)
+	l.cancel()
+	if l.httpServer == nil {
+		return
+	}
 	err := l.httpServer.Close()
 	if err != nil {
 		logx.Errorf("ws listener close err %+v", err)
 	}
-	l.cancel()
 }
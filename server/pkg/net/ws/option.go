@@ -0,0 +1,107 @@
+package ws
+
+import (
+	"compress/flate"
+	"net/http"
+	"server/pkg/net/batch"
+	"time"
+)
+
+// UpgradeHook 升级前调用，返回的属性会写入新连接的session，返回错误则拒绝连接
+type UpgradeHook func(r *http.Request) (map[string]any, error)
+
+type Config struct {
+	writerOpts   []batch.Option
+	writeTimeout time.Duration
+
+	path            string
+	mux             *http.ServeMux
+	upgradeHook     UpgradeHook
+	acceptQueueSize int
+	acceptTimeout   time.Duration
+	textMode        bool
+
+	compression        bool
+	compressionLevel   int
+	compressionMinSize int
+}
+
+type Option func(*Config)
+
+func WithWriterOptions(opts ...batch.Option) Option {
+	return func(c *Config) {
+		c.writerOpts = opts
+	}
+}
+
+// WithWriteTimeout 单次写出的超时时间，超时后关闭连接，0表示不超时
+func WithWriteTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		c.writeTimeout = timeout
+	}
+}
+
+func WithPath(path string) Option {
+	return func(c *Config) {
+		c.path = path
+	}
+}
+
+// WithServeMux 挂载到已有的mux上，由调用方负责启动http服务，不再监听host和port
+func WithServeMux(mux *http.ServeMux) Option {
+	return func(c *Config) {
+		c.mux = mux
+	}
+}
+
+func WithUpgradeHook(hook UpgradeHook) Option {
+	return func(c *Config) {
+		c.upgradeHook = hook
+	}
+}
+
+func WithAcceptQueueSize(size int) Option {
+	return func(c *Config) {
+		c.acceptQueueSize = size
+	}
+}
+
+// WithAcceptTimeout 接收队列满时的最长等待时间，超时后拒绝连接
+func WithAcceptTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		c.acceptTimeout = timeout
+	}
+}
+
+// WithTextMode 接受json文本帧，连接收到文本帧后下行也改为json文本帧，服务需要使用JsonSerializer
+func WithTextMode(enable bool) Option {
+	return func(c *Config) {
+		c.textMode = enable
+	}
+}
+
+// WithCompression 协商permessage-deflate，小于minSize的消息不压缩，level参考compress/flate
+func WithCompression(level int, minSize int) Option {
+	return func(c *Config) {
+		c.compression = true
+		c.compressionLevel = level
+		c.compressionMinSize = minSize
+	}
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		writerOpts:      []batch.Option{},
+		writeTimeout:    5 * time.Second,
+		path:            "/ws",
+		mux:             nil,
+		upgradeHook:     nil,
+		acceptQueueSize: 128,
+		acceptTimeout:   3 * time.Second,
+		textMode:        false,
+
+		compression:        false,
+		compressionLevel:   flate.BestSpeed,
+		compressionMinSize: 256,
+	}
+}
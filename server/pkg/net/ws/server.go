@@ -2,8 +2,10 @@ package ws
 
 import (
 	"errors"
+	"hutool/bytex"
 	"hutool/logx"
 	"net"
+	"server/pkg/codec"
 	"server/pkg/net/inet"
 	"sync"
 
@@ -11,20 +13,26 @@ import (
 )
 
 type Server struct {
+	cfg      *Config
 	listener *Listener
 	svc      inet.IService
 	wg       sync.WaitGroup
 }
 
-func NewServer() *Server {
+func NewServer(opts ...Option) *Server {
+	cfg := DefaultConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
 	return &Server{
-		wg: sync.WaitGroup{},
+		cfg: cfg,
+		wg:  sync.WaitGroup{},
 	}
 }
 
 func (s *Server) ListenAndServe(host string, port int, svc inet.IService, upgrader websocket.Upgrader) error {
 	s.svc = svc
-	s.listener = NewListener()
+	s.listener = NewListener(s.cfg)
 	err := s.listener.Listen(host, port, upgrader)
 	if err != nil {
 		return err
@@ -37,7 +45,7 @@ func (s *Server) ListenAndServe(host string, port int, svc inet.IService, upgrad
 func (s *Server) serve() {
 	defer s.wg.Done()
 	for {
-		conn, err := s.listener.Accept(s.svc)
+		conn, err := s.listener.Accept(s.svc, s.cfg)
 		if err != nil {
 			if !errors.Is(err, net.ErrClosed) {
 				logx.Errorf("accept err %+v", err)
@@ -75,18 +83,39 @@ func (s *Server) handleConn(conn *Conn) {
 			}
 			return
 		}
-		if messageType != websocket.BinaryMessage {
-			logx.Warnf("unsupported message type: %d", messageType)
-			continue
-		}
 		if len(message) > MaxPacketLen {
 			logx.Errorf("read conn %v packet too large", conn.RemoteAddr())
 			return
 		}
-		s.svc.OnConnRead(conn, message)
+		switch {
+		case messageType == websocket.BinaryMessage:
+			s.onRead(conn, message)
+		case messageType == websocket.TextMessage && s.cfg.textMode:
+			packet, err := codec.JsonToC2SPacket(message)
+			if err != nil {
+				logx.Errorf("read conn %v json err %+v", conn.RemoteAddr(), err)
+				continue
+			}
+			conn.textMode.Store(true)
+			s.onRead(conn, packet.Bytes())
+		default:
+			logx.Warnf("unsupported message type: %d", messageType)
+		}
 	}
 }
 
+// onRead gorilla每条消息都是新切片，包装后交给服务
+func (s *Server) onRead(conn *Conn, message []byte) {
+	buf := bytex.WrapBuffer(message)
+	defer buf.Release()
+	s.svc.OnConnRead(conn, buf)
+}
+
+// Addr 挂载到外部mux时返回nil
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
 func (s *Server) Stop() {
 	s.listener.Close()
 	s.wg.Wait()
@@ -0,0 +1,71 @@
+package record
+
+import (
+	"hutool/bytex"
+	"net"
+	"server/pkg/net/conn_id"
+	"server/pkg/net/inet"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// conn 回放用的内存连接，收集服务端写出的包
+type conn struct {
+	connId  uint32
+	svc     inet.IService
+	mu      sync.Mutex
+	written [][]byte
+	closed  atomic.Bool
+}
+
+func newConn(svc inet.IService) *conn {
+	return &conn{
+		connId: conn_id.NextId(),
+		svc:    svc,
+	}
+}
+
+func (c *conn) GetConnId() uint32 {
+	return c.connId
+}
+
+func (c *conn) RemoteAddr() string {
+	return "replay"
+}
+
+// Compressed 让服务跳过压缩，写出的包和录制的包可以直接对比
+func (c *conn) Compressed() bool {
+	return true
+}
+
+func (c *conn) Write(data []byte) error {
+	if c.closed.Load() {
+		return net.ErrClosed
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.written = append(c.written, slices.Clone(data))
+	return nil
+}
+
+func (c *conn) WriteLowPriority(data []byte) error {
+	return c.Write(data)
+}
+
+func (c *conn) WriteBuffer(buf *bytex.Buffer) error {
+	defer buf.Release()
+	return c.Write(buf.Bytes())
+}
+
+func (c *conn) Written() [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.written)
+}
+
+func (c *conn) Close() {
+	if c.closed.CompareAndSwap(false, true) {
+		c.svc.OnConnStop(c)
+	}
+}
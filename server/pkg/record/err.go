@@ -0,0 +1,10 @@
+package record
+
+import "errors"
+
+var (
+	// MagicErr 不是录制文件或版本不兼容
+	MagicErr          = errors.New("record file magic mismatch")
+	EntryErr          = errors.New("record entry broken")
+	RecorderClosedErr = errors.New("recorder is closed")
+)
@@ -0,0 +1,144 @@
+package record
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// 文件格式: magic(4) startNano(8) entry...
+// entry: kind(1) connId(uvarint) 距上一条的微秒数(uvarint) dataLen(uvarint) data
+var magic = [4]byte{'R', 'C', 'D', '1'}
+
+const headLen = 12
+
+type Kind uint8
+
+const (
+	KindC2S Kind = iota + 1
+	KindS2C
+	// KindEnd 会话结束，没有数据
+	KindEnd
+)
+
+// maxDataLen 防止损坏的文件申请过大的内存
+const maxDataLen = 1 << 24
+
+type Entry struct {
+	Kind   Kind
+	ConnId uint32
+	Time   time.Time
+	Data   []byte
+}
+
+func appendHead(dst []byte, start time.Time) []byte {
+	dst = append(dst, magic[:]...)
+	return binary.BigEndian.AppendUint64(dst, uint64(start.UnixNano()))
+}
+
+func appendEntry(dst []byte, kind Kind, connId uint32, delta time.Duration, data []byte) []byte {
+	dst = append(dst, byte(kind))
+	dst = binary.AppendUvarint(dst, uint64(connId))
+	dst = binary.AppendUvarint(dst, uint64(max(delta.Microseconds(), 0)))
+	dst = binary.AppendUvarint(dst, uint64(len(data)))
+	return append(dst, data...)
+}
+
+// Reader 按写入顺序读取录制的条目
+type Reader struct {
+	r    *bufio.Reader
+	last time.Time
+}
+
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	head := make([]byte, headLen)
+	_, err := io.ReadFull(br, head)
+	if err != nil {
+		return nil, MagicErr
+	}
+	if [4]byte(head[:4]) != magic {
+		return nil, MagicErr
+	}
+	return &Reader{
+		r:    br,
+		last: time.Unix(0, int64(binary.BigEndian.Uint64(head[4:]))),
+	}, nil
+}
+
+// Next 读完返回io.EOF，文件尾部写了一半的条目返回io.ErrUnexpectedEOF，内容损坏返回EntryErr
+func (r *Reader) Next() (Entry, error) {
+	kind, err := r.r.ReadByte()
+	if err != nil {
+		return Entry{}, err
+	}
+	if kind < byte(KindC2S) || kind > byte(KindEnd) {
+		return Entry{}, EntryErr
+	}
+	connId, err := r.readUvarint()
+	if err != nil {
+		return Entry{}, err
+	}
+	delta, err := r.readUvarint()
+	if err != nil {
+		return Entry{}, err
+	}
+	dataLen, err := r.readUvarint()
+	if err != nil {
+		return Entry{}, err
+	}
+	if dataLen > maxDataLen {
+		return Entry{}, EntryErr
+	}
+	data := make([]byte, dataLen)
+	_, err = io.ReadFull(r.r, data)
+	if err != nil {
+		return Entry{}, io.ErrUnexpectedEOF
+	}
+	r.last = r.last.Add(time.Duration(delta) * time.Microsecond)
+	return Entry{
+		Kind:   Kind(kind),
+		ConnId: uint32(connId),
+		Time:   r.last,
+		Data:   data,
+	}, nil
+}
+
+func (r *Reader) readUvarint() (uint64, error) {
+	v, err := binary.ReadUvarint(r.r)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return 0, EntryErr
+	}
+	return v, nil
+}
+
+// ReadFile 读取整个录制文件，进程崩溃导致的尾部残缺条目会被忽略，
+// 中间的损坏条目返回EntryErr和之前读到的条目
+func ReadFile(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r, err := NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	var entries []Entry
+	for {
+		entry, err := r.Next()
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return entries, err
+		}
+		entries = append(entries, entry)
+	}
+}
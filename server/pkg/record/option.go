@@ -0,0 +1,59 @@
+package record
+
+import "time"
+
+type Config struct {
+	s2c           bool
+	flushInterval time.Duration
+}
+
+type Option func(*Config)
+
+// WithS2C 同时录制服务端发出的包，回放时用于对比
+func WithS2C(enable bool) Option {
+	return func(c *Config) {
+		c.s2c = enable
+	}
+}
+
+// WithFlushInterval 定期刷盘，进程崩溃时最多丢失一个间隔的数据，为0时只在Close时刷盘
+func WithFlushInterval(interval time.Duration) Option {
+	return func(c *Config) {
+		c.flushInterval = interval
+	}
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		s2c:           false,
+		flushInterval: time.Second,
+	}
+}
+
+type ReplayConfig struct {
+	realtime bool
+	settle   time.Duration
+}
+
+type ReplayOption func(*ReplayConfig)
+
+// WithRealtime 按录制时的间隔发送，默认尽快发送
+func WithRealtime(realtime bool) ReplayOption {
+	return func(c *ReplayConfig) {
+		c.realtime = realtime
+	}
+}
+
+// WithSettle 发送完后等待响应写出的时间
+func WithSettle(settle time.Duration) ReplayOption {
+	return func(c *ReplayConfig) {
+		c.settle = settle
+	}
+}
+
+func DefaultReplayConfig() *ReplayConfig {
+	return &ReplayConfig{
+		realtime: false,
+		settle:   200 * time.Millisecond,
+	}
+}
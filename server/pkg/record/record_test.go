@@ -0,0 +1,178 @@
+package record
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"server/app/test"
+	"server/pkg/codec"
+	"server/pkg/net/tcp"
+	router2 "server/pkg/router"
+	"server/pkg/service"
+	session2 "server/pkg/session"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newService(msg string, opts ...service.Option) *service.Service {
+	router := router2.NewRouter()
+	router2.RegisterAskRouter[*test.HelloAsk, *test.HelloRsp](router, uint32(test.RouterId_Hello), func(ctx codec.ReqCtx, req *test.HelloAsk) *test.HelloRsp {
+		return &test.HelloRsp{Msg: msg + req.Msg}
+	})
+	return service.NewService(0, append(opts, service.WithRouter(router))...)
+}
+
+func record(t *testing.T, path string) {
+	recorder, err := CreateRecorder(path, WithS2C(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := newService("hello ", service.WithPlugin(recorder))
+	err = svc.StartTCPServer("127.0.0.1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl := tcp.NewClient(codec.ProtoSerializer{}, time.Second)
+	err = cl.Dial("127.0.0.1", svc.TCPAddr().(*net.TCPAddr).Port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{}, 3)
+	for _, msg := range []string{"a", "b", "c"} {
+		err = tcp.Ask[*test.HelloAsk, *test.HelloRsp](cl, 0, uint32(test.RouterId_Hello), &test.HelloAsk{Msg: msg}, func(rsp *test.HelloRsp) {
+			done <- struct{}{}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		<-done
+	}
+	cl.Close()
+	time.Sleep(100 * time.Millisecond)
+	svc.Stop()
+	err = recorder.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.rcd")
+	record(t, path)
+
+	entries, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := map[Kind]int{}
+	asks := 0
+	for _, entry := range entries {
+		kinds[entry.Kind]++
+		if entry.Kind == KindC2S {
+			packet, err := codec.BytesToC2SPacket(entry.Data)
+			if err == nil && !packet.IsHeartbeatPacket() && packet.ServiceId() != codec.SysServiceId {
+				asks++
+			}
+		}
+	}
+	if asks != 3 || kinds[KindS2C] != kinds[KindC2S] || kinds[KindEnd] != 1 {
+		t.Fatalf("unexpected entries %v", kinds)
+	}
+
+	svc := newService("hello ")
+	defer svc.Stop()
+	result := NewReplayer(svc).Replay(entries)
+	if result.Conns != 1 || result.Sent != kinds[KindC2S] || result.Actual != result.Expected || len(result.Diffs) != 0 {
+		t.Fatalf("unexpected replay result %+v", result)
+	}
+
+	changed := newService("bye ")
+	defer changed.Stop()
+	result = NewReplayer(changed, WithRealtime(true)).Replay(entries)
+	if len(result.Diffs) != 3 || result.Diffs[0].Key != "rsp 2 #0" {
+		t.Fatalf("unexpected replay diffs %+v", result.Diffs)
+	}
+}
+
+func TestReadFileBroken(t *testing.T) {
+	start := time.Now()
+	data := appendHead(nil, start)
+	data = appendEntry(data, KindC2S, 1, 0, []byte("a"))
+	data = appendEntry(data, KindS2C, 1, time.Millisecond, []byte("b"))
+	path := filepath.Join(t.TempDir(), "broken.rcd")
+
+	// 尾部写了一半的条目被忽略
+	err := os.WriteFile(path, appendEntry(data, KindC2S, 1, 0, []byte("cd"))[:len(data)+4], 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := ReadFile(path)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("unexpected truncated read %d %v", len(entries), err)
+	}
+
+	// 中间损坏的条目要报告
+	broken := append(data[:len(data):len(data)], 0xff)
+	broken = appendEntry(broken, KindEnd, 1, 0, nil)
+	err = os.WriteFile(path, broken, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err = ReadFile(path)
+	if !errors.Is(err, EntryErr) || len(entries) != 2 {
+		t.Fatalf("unexpected corrupt read %d %v", len(entries), err)
+	}
+}
+
+// endWatcher 记录每次读到包时已经结束的会话数
+type endWatcher struct {
+	ends atomic.Int32
+	mu   sync.Mutex
+	seen []int32
+}
+
+func (w *endWatcher) SessionEnd(session *session2.Session) {
+	w.ends.Add(1)
+}
+
+func (w *endWatcher) PostReadPacket(session *session2.Session, packet codec.C2SPacket) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.seen = append(w.seen, w.ends.Load())
+}
+
+func TestReplayEnd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.rcd")
+	record(t, path)
+	entries, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 第二个会话在第一个会话结束之后开始
+	offset := entries[len(entries)-1].Time.Sub(entries[0].Time) + 200*time.Millisecond
+	twice := slices.Clone(entries)
+	for _, entry := range entries {
+		entry.ConnId += 1000
+		entry.Time = entry.Time.Add(offset)
+		twice = append(twice, entry)
+	}
+
+	watcher := &endWatcher{}
+	svc := newService("hello ", service.WithPlugin(watcher))
+	defer svc.Stop()
+	result := NewReplayer(svc, WithRealtime(true), WithSettle(50*time.Millisecond)).Replay(twice)
+	if result.Conns != 2 || len(result.Diffs) != 0 {
+		t.Fatalf("unexpected replay result %+v", result)
+	}
+	watcher.mu.Lock()
+	defer watcher.mu.Unlock()
+	if len(watcher.seen) == 0 || watcher.seen[0] != 0 || watcher.seen[len(watcher.seen)-1] != 1 {
+		t.Fatalf("first session not closed on end %v", watcher.seen)
+	}
+}
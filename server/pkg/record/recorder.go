@@ -0,0 +1,150 @@
+package record
+
+import (
+	"bufio"
+	"hutool/logx"
+	"io"
+	"os"
+	"server/pkg/codec"
+	"server/pkg/session"
+	"sync"
+	"time"
+)
+
+// Recorder 作为服务插件录制每个会话的包，多个连接的条目按时间交错写入同一个文件
+type Recorder struct {
+	mu      sync.Mutex
+	w       *bufio.Writer
+	closer  io.Closer
+	cfg     *Config
+	last    time.Time
+	scratch []byte
+	err     error
+	stop    chan struct{}
+	wg      sync.WaitGroup
+	once    sync.Once
+}
+
+// NewRecorder w为文件时Close会一起关闭
+func NewRecorder(w io.Writer, opts ...Option) (*Recorder, error) {
+	cfg := DefaultConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	r := &Recorder{
+		w:    bufio.NewWriter(w),
+		cfg:  cfg,
+		last: time.Now(),
+		stop: make(chan struct{}),
+	}
+	if closer, ok := w.(io.Closer); ok {
+		r.closer = closer
+	}
+	_, err := r.w.Write(appendHead(nil, r.last))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.flushInterval > 0 {
+		r.wg.Add(1)
+		go r.flushLoop()
+	}
+	return r, nil
+}
+
+func CreateRecorder(path string, opts ...Option) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewRecorder(f, opts...)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Recorder) PostReadPacket(session *session.Session, packet codec.C2SPacket) {
+	r.write(KindC2S, session.GetConnId(), packet.Bytes())
+}
+
+func (r *Recorder) PreWritePacket(connId uint32, data []byte) {
+	if r.cfg.s2c {
+		r.write(KindS2C, connId, data)
+	}
+}
+
+func (r *Recorder) SessionEnd(session *session.Session) {
+	r.write(KindEnd, session.GetConnId(), nil)
+}
+
+// write 在锁内取时间，保证文件中的时间单调
+func (r *Recorder) write(kind Kind, connId uint32, data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	now := time.Now()
+	r.scratch = appendEntry(r.scratch[:0], kind, connId, now.Sub(r.last), data)
+	r.last = r.last.Add(now.Sub(r.last).Truncate(time.Microsecond))
+	_, err := r.w.Write(r.scratch)
+	if err != nil {
+		r.fail(err)
+	}
+}
+
+// fail 写失败后停止录制，不影响服务
+func (r *Recorder) fail(err error) {
+	r.err = err
+	logx.Errorf("recorder stop err %+v", err)
+}
+
+func (r *Recorder) flushLoop() {
+	defer r.wg.Done()
+	ticker := time.NewTicker(r.cfg.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			_ = r.Flush()
+		}
+	}
+}
+
+func (r *Recorder) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	err := r.w.Flush()
+	if err != nil {
+		r.fail(err)
+	}
+	return err
+}
+
+// Close 服务停止后调用，之后的包不再录制
+func (r *Recorder) Close() error {
+	err := RecorderClosedErr
+	r.once.Do(func() {
+		close(r.stop)
+		r.wg.Wait()
+		err = r.Flush()
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.err == nil {
+			r.err = RecorderClosedErr
+		}
+		if r.closer != nil {
+			closeErr := r.closer.Close()
+			if err == nil {
+				err = closeErr
+			}
+		}
+	})
+	return err
+}
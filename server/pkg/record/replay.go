@@ -0,0 +1,148 @@
+package record
+
+import (
+	"bytes"
+	"fmt"
+	"hutool/bytex"
+	"server/pkg/codec"
+	"server/pkg/net/inet"
+	"sync"
+	"time"
+)
+
+// Replayer 把录制的C2S包通过内存连接送回服务，并和录制的S2C包对比
+type Replayer struct {
+	svc inet.IService
+	cfg *ReplayConfig
+}
+
+// Diff Expected为空表示多出的包，Actual为空表示缺少的包
+type Diff struct {
+	// ConnId 录制时的连接id
+	ConnId   uint32
+	Key      string
+	Expected []byte
+	Actual   []byte
+}
+
+type Result struct {
+	Conns    int
+	Sent     int
+	Expected int
+	Actual   int
+	Diffs    []Diff
+}
+
+func NewReplayer(svc inet.IService, opts ...ReplayOption) *Replayer {
+	cfg := DefaultReplayConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return &Replayer{
+		svc: svc,
+		cfg: cfg,
+	}
+}
+
+// Replay 会话在第一条记录时建立，在结束记录后等待响应写出再关闭，
+// 避免写协程中还没写出的响应因为会话关闭而丢失，没有结束记录的会话在全部发送后统一关闭
+func (r *Replayer) Replay(entries []Entry) Result {
+	result := Result{}
+	conns := map[uint32]*conn{}
+	ended := map[uint32]bool{}
+	expected := map[uint32][][]byte{}
+	var order []uint32
+	var wg sync.WaitGroup
+	start := time.Now()
+	for _, entry := range entries {
+		if r.cfg.realtime {
+			time.Sleep(time.Until(start.Add(entry.Time.Sub(entries[0].Time))))
+		}
+		if ended[entry.ConnId] {
+			continue
+		}
+		c, ok := conns[entry.ConnId]
+		if !ok {
+			c = newConn(r.svc)
+			conns[entry.ConnId] = c
+			order = append(order, entry.ConnId)
+			r.svc.OnConnStart(c)
+		}
+		switch entry.Kind {
+		case KindC2S:
+			result.Sent++
+			buf := bytex.WrapBuffer(entry.Data)
+			r.svc.OnConnRead(c, buf)
+			buf.Release()
+		case KindS2C:
+			expected[entry.ConnId] = append(expected[entry.ConnId], entry.Data)
+		case KindEnd:
+			ended[entry.ConnId] = true
+			wg.Add(1)
+			time.AfterFunc(r.cfg.settle, func() {
+				defer wg.Done()
+				c.Close()
+			})
+		}
+	}
+	time.Sleep(r.cfg.settle)
+	wg.Wait()
+
+	result.Conns = len(conns)
+	for _, connId := range order {
+		c := conns[connId]
+		c.Close()
+		actual := c.Written()
+		result.Expected += len(expected[connId])
+		result.Actual += len(actual)
+		result.Diffs = append(result.Diffs, diff(connId, expected[connId], actual)...)
+	}
+	return result
+}
+
+// diff 响应按reqId对比，推送按路由内的顺序对比，不要求不同路由之间的顺序一致
+func diff(connId uint32, expected [][]byte, actual [][]byte) []Diff {
+	expectedByKey, keys := groupByKey(expected)
+	actualByKey, actualKeys := groupByKey(actual)
+	for _, key := range actualKeys {
+		if _, ok := expectedByKey[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	var diffs []Diff
+	for _, key := range keys {
+		e, a := expectedByKey[key], actualByKey[key]
+		if !bytes.Equal(e, a) {
+			diffs = append(diffs, Diff{ConnId: connId, Key: key, Expected: e, Actual: a})
+		}
+	}
+	return diffs
+}
+
+func groupByKey(packets [][]byte) (map[string][]byte, []string) {
+	byKey := map[string][]byte{}
+	keys := make([]string, 0, len(packets))
+	seen := map[string]int{}
+	for _, data := range packets {
+		base := packetKey(data)
+		key := fmt.Sprintf("%s #%d", base, seen[base])
+		seen[base]++
+		byKey[key] = data
+		keys = append(keys, key)
+	}
+	return byKey, keys
+}
+
+func packetKey(data []byte) string {
+	packet, err := codec.BytesToS2CPacket(data)
+	switch {
+	case err != nil:
+		return "raw"
+	case packet.IsAskPacket():
+		return fmt.Sprintf("ask %d/%d", packet.ServiceId(), packet.RouterId())
+	case packet.IsPushPacket():
+		return fmt.Sprintf("push %d/%d", packet.ServiceId(), packet.RouterId())
+	default:
+		return fmt.Sprintf("rsp %d", packet.ReqId())
+	}
+}
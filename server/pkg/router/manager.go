@@ -1,21 +1,102 @@
 package router
 
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// Manager 持有路由表的只读快照，修改时复制后整体替换，处理中的请求不受影响
 type Manager struct {
-	registry *Registry
+	// 串行化修改，读取不加锁
+	mu       sync.Mutex
+	registry atomic.Pointer[Registry]
 }
 
+// NewManager 复制一份注册表，之后对registry的修改不会生效，需要通过Merge更新
 func NewManager(registry *Registry) *Manager {
-	return &Manager{
-		registry: registry,
-	}
+	m := &Manager{}
+	m.registry.Store(registry.clone())
+	return m
 }
 
 func (r *Manager) GetAskRouter(routerId uint32) (AskRouter, bool) {
-	router, ok := r.registry.askRouterMap[routerId]
+	router, ok := r.registry.Load().askRouterMap[routerId]
 	return router, ok
 }
 
 func (r *Manager) GetTellRouter(routerId uint32) (TellRouter, bool) {
-	router, ok := r.registry.tellRouterMap[routerId]
+	router, ok := r.registry.Load().tellRouterMap[routerId]
 	return router, ok
 }
+
+// Merge 新增或替换registry中的路由，其余路由保持不变
+// 替换时同id另一种类型的旧路由一并删除，避免ask和tell同时生效
+func (r *Manager) Merge(registry *Registry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	next := r.registry.Load().clone()
+	for id := range registry.askRouterMap {
+		delete(next.tellRouterMap, id)
+	}
+	for id := range registry.tellRouterMap {
+		delete(next.askRouterMap, id)
+	}
+	maps.Copy(next.askRouterMap, registry.askRouterMap)
+	maps.Copy(next.tellRouterMap, registry.tellRouterMap)
+	r.registry.Store(next)
+}
+
+// Reset 用registry整体替换路由表
+func (r *Manager) Reset(registry *Registry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.registry.Store(registry.clone())
+}
+
+// Remove 同时删除同id的ask和tell路由
+func (r *Manager) Remove(routerIds ...uint32) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	next := r.registry.Load().clone()
+	for _, routerId := range routerIds {
+		delete(next.askRouterMap, routerId)
+		delete(next.tellRouterMap, routerId)
+	}
+	r.registry.Store(next)
+}
+
+// Info 路由的描述信息，用于调试展示，Serializer由服务按序列化注册表中的路由绑定填充
+type Info struct {
+	RouterId   uint32 `json:"routerId"`
+	OneWay     bool   `json:"oneWay"`
+	ReqType    string `json:"reqType"`
+	RspType    string `json:"rspType,omitempty"`
+	Serializer uint8  `json:"serializer,omitempty"`
+}
+
+// Infos 所有已注册路由，按路由id排序
+func (r *Manager) Infos() []Info {
+	registry := r.registry.Load()
+	infos := make([]Info, 0, len(registry.askRouterMap)+len(registry.tellRouterMap))
+	for id, router := range registry.askRouterMap {
+		infos = append(infos, Info{
+			RouterId: id,
+			ReqType:  router.ReqType.String(),
+			RspType:  router.RspType.String(),
+		})
+	}
+	for id, router := range registry.tellRouterMap {
+		infos = append(infos, Info{
+			RouterId: id,
+			OneWay:   true,
+			ReqType:  router.ReqType.String(),
+		})
+	}
+	slices.SortFunc(infos, func(a, b Info) int {
+		return cmp.Compare(a.RouterId, b.RouterId)
+	})
+	return infos
+}
@@ -0,0 +1,95 @@
+package router
+
+import (
+	"server/pkg/codec"
+	"sync"
+	"testing"
+)
+
+func TestManagerMergeRemove(t *testing.T) {
+	registry := NewRouter()
+	RegisterAskRouter[string, string](registry, 1, func(ctx codec.ReqCtx, req string) string {
+		return "v1"
+	})
+	m := NewManager(registry)
+	// 构造后修改原注册表不生效
+	RegisterTellRouter[string](registry, 2, func(ctx codec.ReqCtx, req string) {})
+	if _, ok := m.GetTellRouter(2); ok {
+		t.Fatal("registry change after NewManager should not take effect")
+	}
+
+	old, _ := m.GetAskRouter(1)
+	update := NewRouter()
+	RegisterAskRouter[string, string](update, 1, func(ctx codec.ReqCtx, req string) string {
+		return "v2"
+	})
+	RegisterTellRouter[string](update, 3, func(ctx codec.ReqCtx, req string) {})
+	m.Merge(update)
+
+	router, _ := m.GetAskRouter(1)
+	if router.Handler(codec.ReqCtx{}, "") != "v2" || old.Handler(codec.ReqCtx{}, "") != "v1" {
+		t.Fatal("merge should replace router and keep old snapshot")
+	}
+	if _, ok := m.GetTellRouter(3); !ok {
+		t.Fatal("merge should add new router")
+	}
+
+	m.Remove(1, 3)
+	if len(m.Infos()) != 0 {
+		t.Fatalf("unexpected routers %v", m.Infos())
+	}
+}
+
+func TestManagerMergeChangeKind(t *testing.T) {
+	registry := NewRouter()
+	RegisterTellRouter[string](registry, 1, func(ctx codec.ReqCtx, req string) {})
+	RegisterAskRouter[string, string](registry, 2, func(ctx codec.ReqCtx, req string) string {
+		return ""
+	})
+	m := NewManager(registry)
+
+	update := NewRouter()
+	RegisterAskRouter[string, string](update, 1, func(ctx codec.ReqCtx, req string) string {
+		return ""
+	})
+	RegisterTellRouter[string](update, 2, func(ctx codec.ReqCtx, req string) {})
+	m.Merge(update)
+
+	if _, ok := m.GetTellRouter(1); ok {
+		t.Fatal("tell router should be replaced by ask router")
+	}
+	if _, ok := m.GetAskRouter(2); ok {
+		t.Fatal("ask router should be replaced by tell router")
+	}
+	infos := m.Infos()
+	if len(infos) != 2 || infos[0].OneWay || !infos[1].OneWay {
+		t.Fatalf("unexpected routers %v", infos)
+	}
+}
+
+func TestManagerConcurrentUpdate(t *testing.T) {
+	m := NewManager(NewRouter())
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(id uint32) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				update := NewRouter()
+				RegisterTellRouter[string](update, id, func(ctx codec.ReqCtx, req string) {})
+				m.Merge(update)
+			}
+		}(uint32(i))
+		go func(id uint32) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				m.GetTellRouter(id)
+				m.Infos()
+			}
+		}(uint32(i))
+	}
+	wg.Wait()
+	if len(m.Infos()) != 4 {
+		t.Fatalf("unexpected routers %v", m.Infos())
+	}
+}
@@ -2,6 +2,7 @@ package router
 
 import (
 	"hutool/reflectx"
+	"maps"
 	"reflect"
 	"server/pkg/codec"
 )
@@ -25,6 +26,16 @@ func NewRouter() *Registry {
 	}
 }
 
+// clone nil注册表复制为空表
+func (r *Registry) clone() *Registry {
+	c := NewRouter()
+	if r != nil {
+		maps.Copy(c.askRouterMap, r.askRouterMap)
+		maps.Copy(c.tellRouterMap, r.tellRouterMap)
+	}
+	return c
+}
+
 type AskRouter struct {
 	ReqType reflect.Type
 	RspType reflect.Type
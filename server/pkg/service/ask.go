@@ -0,0 +1,145 @@
+package service
+
+import (
+	"hutool/logx"
+	"hutool/reflectx"
+	"hutool/timewheel"
+	"reflect"
+	"server/pkg/codec"
+	session2 "server/pkg/session"
+	"sync"
+	"time"
+)
+
+type pendingAsk struct {
+	connId  uint32
+	rspType reflect.Type
+	handler func(rsp any, err error)
+	timer   *timewheel.Timer
+	// serializer 响应按发起请求时的序列化解析
+	serializer codec.ISerializer
+}
+
+// askTable 服务端向客户端发起的请求，响应和超时只会回调一次
+type askTable struct {
+	mu      sync.Mutex
+	nextId  uint32
+	pending map[uint32]*pendingAsk
+	wheel   *timewheel.TimingWheel
+}
+
+func newAskTable(wheel *timewheel.TimingWheel) *askTable {
+	return &askTable{
+		pending: make(map[uint32]*pendingAsk),
+		wheel:   wheel,
+	}
+}
+
+func (t *askTable) begin(ask *pendingAsk, timeout time.Duration) uint32 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for {
+		t.nextId++
+		if t.nextId == 0 {
+			continue
+		}
+		if _, ok := t.pending[t.nextId]; !ok {
+			break
+		}
+	}
+	reqId := t.nextId
+	ask.timer = t.wheel.AfterFunc(timeout, func() {
+		if t.take(reqId, ask.connId) != nil {
+			ask.handler(nil, AskTimeoutErr)
+		}
+	})
+	t.pending[reqId] = ask
+	return reqId
+}
+
+// take 只有发起请求的连接的响应才有效
+func (t *askTable) take(reqId uint32, connId uint32) *pendingAsk {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ask, ok := t.pending[reqId]
+	if !ok || ask.connId != connId {
+		return nil
+	}
+	delete(t.pending, reqId)
+	ask.timer.Stop()
+	return ask
+}
+
+func (t *askTable) takeConn(connId uint32) []*pendingAsk {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	asks := make([]*pendingAsk, 0)
+	for reqId, ask := range t.pending {
+		if ask.connId == connId {
+			delete(t.pending, reqId)
+			ask.timer.Stop()
+			asks = append(asks, ask)
+		}
+	}
+	return asks
+}
+
+func (s *Service) onClientRsp(session *session2.Session, rspPacket codec.C2SPacket) {
+	ask := s.asks.take(rspPacket.ReqId(), session.GetConnId())
+	if ask == nil {
+		return
+	}
+	rsp := reflectx.NewPointerIns(ask.rspType)
+	err := ask.serializer.Unmarshal(rspPacket.Body(), rsp)
+	if err != nil {
+		logx.Errorf("unmashal client rsp err %+v", err)
+		ask.handler(nil, AskRspBodyErr)
+		return
+	}
+	ask.handler(rsp, nil)
+}
+
+// failAsks 连接断开时结束所有等待中的请求
+func (s *Service) failAsks(connId uint32) {
+	for _, ask := range s.asks.takeConn(connId) {
+		ask.handler(nil, AskConnStopErr)
+	}
+}
+
+func (s *Service) askClient(session *session2.Session, routerId uint32, req any, rspType reflect.Type, timeout time.Duration, handler func(rsp any, err error)) error {
+	if hs, ok := handshakeKey.Get(session); ok && hs.Caps&codec.CapServerAsk == 0 {
+		return AskUnsupportedErr
+	}
+	serializer := s.serializerFor(session, routerId)
+	reqBodyBytes, err := serializer.Marshal(req)
+	if err != nil {
+		return err
+	}
+	connId := session.GetConnId()
+	reqId := s.asks.begin(&pendingAsk{
+		connId:     connId,
+		rspType:    rspType,
+		handler:    handler,
+		serializer: serializer,
+	}, timeout)
+	askPacket := codec.NewS2CAskPacket(s.svcId, routerId, reqId, reqBodyBytes)
+	err = s.writeAsync(connId, askPacket.Bytes(), false)
+	if err != nil {
+		s.asks.take(reqId, connId)
+		return err
+	}
+	return nil
+}
+
+// AskClient 向客户端发起请求，handler在响应、超时或连接断开时回调一次，不能在其中阻塞等待
+func AskClient[Req any, Rsp any](s *Service, session *session2.Session, routerId uint32, req Req, timeout time.Duration, handler func(rsp Rsp, err error)) error {
+	rspType := reflectx.GenericTypeOf[Rsp]()
+	return s.askClient(session, routerId, req, rspType, timeout, func(rsp any, err error) {
+		if err != nil {
+			var zero Rsp
+			handler(zero, err)
+			return
+		}
+		handler(rsp.(Rsp), nil)
+	})
+}
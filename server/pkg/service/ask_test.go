@@ -0,0 +1,139 @@
+package service
+
+import (
+	"errors"
+	"server/app/test"
+	"server/pkg/codec"
+	"server/pkg/net/tcp"
+	router2 "server/pkg/router"
+	session2 "server/pkg/session"
+	"testing"
+	"time"
+)
+
+const (
+	clientAskRouterId    = uint32(60)
+	clientSilentRouterId = uint32(61)
+)
+
+type askResult struct {
+	rsp *test.HelloRsp
+	err error
+}
+
+// loginClient 连上服务并通过hello请求拿到服务端的会话
+func loginClient(t *testing.T, svc *Service, sessions chan *session2.Session) (*tcp.Client, *session2.Session) {
+	cl := tcp.NewClient(codec.ProtoSerializer{}, time.Second)
+	tcp.RegisterAskHandler[*test.HelloAsk, *test.HelloRsp](cl, 0, clientAskRouterId, func(req *test.HelloAsk) *test.HelloRsp {
+		return &test.HelloRsp{Msg: "client " + req.Msg}
+	})
+	err := cl.Dial("127.0.0.1", addrPort(svc.TCPAddr()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	askHello(t, func(handler func(rsp *test.HelloRsp)) error {
+		return tcp.Ask[*test.HelloAsk, *test.HelloRsp](cl, 0, uint32(test.RouterId_Hello), &test.HelloAsk{Msg: "login"}, handler)
+	}, "login")
+	return cl, <-sessions
+}
+
+func askClient(t *testing.T, svc *Service, session *session2.Session, routerId uint32, timeout time.Duration) chan askResult {
+	results := make(chan askResult, 1)
+	err := AskClient[*test.HelloAsk, *test.HelloRsp](svc, session, routerId, &test.HelloAsk{Msg: "ping"}, timeout, func(rsp *test.HelloRsp, err error) {
+		results <- askResult{rsp: rsp, err: err}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return results
+}
+
+func waitAsk(t *testing.T, results chan askResult) askResult {
+	select {
+	case r := <-results:
+		return r
+	case <-time.After(2 * time.Second):
+		t.Fatal("ask handler not called")
+		return askResult{}
+	}
+}
+
+func pendingAskIds(svc *Service) []uint32 {
+	svc.asks.mu.Lock()
+	defer svc.asks.mu.Unlock()
+	ids := make([]uint32, 0, len(svc.asks.pending))
+	for reqId := range svc.asks.pending {
+		ids = append(ids, reqId)
+	}
+	return ids
+}
+
+func TestAskClient(t *testing.T) {
+	sessions := make(chan *session2.Session, 2)
+	router := router2.NewRouter()
+	router2.RegisterAskRouter[*test.HelloAsk, *test.HelloRsp](router, uint32(test.RouterId_Hello), func(ctx codec.ReqCtx, req *test.HelloAsk) *test.HelloRsp {
+		sessions <- ctx.GetSession()
+		return &test.HelloRsp{Msg: req.Msg}
+	})
+	svc := NewService(0, WithRouter(router))
+	err := svc.StartTCPServer("127.0.0.1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer svc.Stop()
+	clA, sessionA := loginClient(t, svc, sessions)
+	defer clA.Close()
+	clB, sessionB := loginClient(t, svc, sessions)
+	defer clB.Close()
+
+	// 客户端处理器的返回值作为响应
+	r := waitAsk(t, askClient(t, svc, sessionA, clientAskRouterId, time.Second))
+	if r.err != nil || r.rsp.Msg != "client ping" {
+		t.Fatalf("unexpected ask result %+v", r)
+	}
+
+	// 客户端没有处理器时不响应，由服务端超时
+	r = waitAsk(t, askClient(t, svc, sessionA, clientSilentRouterId, 50*time.Millisecond))
+	if !errors.Is(r.err, AskTimeoutErr) {
+		t.Fatalf("silent ask err %v", r.err)
+	}
+
+	// 其它连接带着相同reqId的响应被忽略
+	results := askClient(t, svc, sessionA, clientSilentRouterId, 10*time.Second)
+	ids := pendingAskIds(svc)
+	if len(ids) != 1 {
+		t.Fatalf("pending asks %v", ids)
+	}
+	body, _ := codec.ProtoSerializer{}.Marshal(&test.HelloRsp{Msg: "forged"})
+	forged, err := codec.BytesToC2SPacket(codec.NewC2SRspPacket(ids[0], body).Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc.onClientRsp(sessionB, forged)
+	select {
+	case r = <-results:
+		t.Fatalf("response from other conn accepted %+v", r)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// 连接断开时结束等待中的请求
+	clA.Close()
+	r = waitAsk(t, results)
+	if !errors.Is(r.err, AskConnStopErr) {
+		t.Fatalf("conn stop err %v", r.err)
+	}
+	if ids = pendingAskIds(svc); len(ids) != 0 {
+		t.Fatalf("pending asks after conn stop %v", ids)
+	}
+
+	// 握手时没有声明支持服务端请求
+	hs, _ := handshakeKey.Get(sessionB)
+	hs.Caps &^= codec.CapServerAsk
+	handshakeKey.Set(sessionB, hs)
+	err = AskClient[*test.HelloAsk, *test.HelloRsp](svc, sessionB, clientAskRouterId, &test.HelloAsk{}, time.Second, func(rsp *test.HelloRsp, err error) {
+		t.Errorf("handler called for refused ask %v", err)
+	})
+	if !errors.Is(err, AskUnsupportedErr) {
+		t.Fatalf("ask without cap err %v", err)
+	}
+}
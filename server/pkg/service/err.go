@@ -0,0 +1,16 @@
+package service
+
+import "errors"
+
+var (
+	AskTimeoutErr  = errors.New("ask client timeout")
+	AskConnStopErr = errors.New("conn stopped before client responded")
+	AskRspBodyErr  = errors.New("client response body error")
+	// AskUnsupportedErr 客户端握手时没有声明支持服务端请求
+	AskUnsupportedErr = errors.New("client not support server ask")
+	RouterNotFoundErr = errors.New("router not found")
+	// RouterRejectedErr 路由被关闭或维护中
+	RouterRejectedErr = errors.New("router rejected by feature flags")
+	// MaintenanceErr 维护期间不允许新的用户登录
+	MaintenanceErr = errors.New("service in maintenance")
+)
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"maps"
+	"server/pkg/codec"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// FeatureFlags 运行时关闭路由或进入维护模式，状态写时复制，请求处理中只读取快照
+type FeatureFlags struct {
+	// 串行化修改，读取不加锁
+	mu    sync.Mutex
+	state atomic.Pointer[featureState]
+}
+
+type featureState struct {
+	disabled    map[uint32]struct{}
+	maintenance bool
+	// whitelist 维护期间仍然放行的路由
+	whitelist map[uint32]struct{}
+	msg       string
+}
+
+// FeatureStatus 当前开关状态，用于展示
+type FeatureStatus struct {
+	DisabledRouters []uint32 `json:"disabledRouters"`
+	Maintenance     bool     `json:"maintenance"`
+	Whitelist       []uint32 `json:"whitelist"`
+	Msg             string   `json:"msg,omitempty"`
+}
+
+func NewFeatureFlags() *FeatureFlags {
+	f := &FeatureFlags{}
+	f.state.Store(&featureState{
+		disabled:  map[uint32]struct{}{},
+		whitelist: map[uint32]struct{}{},
+	})
+	return f
+}
+
+func (f *FeatureFlags) DisableRouter(routerIds ...uint32) {
+	f.update(func(state *featureState) {
+		for _, routerId := range routerIds {
+			state.disabled[routerId] = struct{}{}
+		}
+	})
+}
+
+func (f *FeatureFlags) EnableRouter(routerIds ...uint32) {
+	f.update(func(state *featureState) {
+		for _, routerId := range routerIds {
+			delete(state.disabled, routerId)
+		}
+	})
+}
+
+// EnterMaintenance 拒绝新的用户绑定，未绑定用户的会话只放行白名单中的路由，已登录的玩家不受影响，msg随错误响应返回
+func (f *FeatureFlags) EnterMaintenance(msg string, whitelist ...uint32) {
+	f.update(func(state *featureState) {
+		state.maintenance = true
+		state.msg = msg
+		state.whitelist = map[uint32]struct{}{}
+		for _, routerId := range whitelist {
+			state.whitelist[routerId] = struct{}{}
+		}
+	})
+}
+
+func (f *FeatureFlags) ExitMaintenance() {
+	f.update(func(state *featureState) {
+		state.maintenance = false
+		state.msg = ""
+		state.whitelist = map[uint32]struct{}{}
+	})
+}
+
+func (f *FeatureFlags) InMaintenance() bool {
+	return f.state.Load().maintenance
+}
+
+func (f *FeatureFlags) Status() FeatureStatus {
+	state := f.state.Load()
+	return FeatureStatus{
+		DisabledRouters: sortedIds(state.disabled),
+		Maintenance:     state.maintenance,
+		Whitelist:       sortedIds(state.whitelist),
+		Msg:             state.msg,
+	}
+}
+
+// Check 路由被关闭，或维护中未绑定用户的会话请求不在白名单的路由时返回对应错误码
+func (f *FeatureFlags) Check(routerId uint32, bound bool) (codec.ErrCode, string) {
+	state := f.state.Load()
+	if _, ok := state.disabled[routerId]; ok {
+		return codec.ErrCodeRouterDisabled, "router disabled"
+	}
+	if state.maintenance && !bound {
+		if _, ok := state.whitelist[routerId]; !ok {
+			return codec.ErrCodeMaintenance, state.msg
+		}
+	}
+	return codec.ErrCodeOk, ""
+}
+
+func (f *FeatureFlags) update(fn func(state *featureState)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	old := f.state.Load()
+	next := &featureState{
+		disabled:    maps.Clone(old.disabled),
+		maintenance: old.maintenance,
+		whitelist:   maps.Clone(old.whitelist),
+		msg:         old.msg,
+	}
+	fn(next)
+	f.state.Store(next)
+}
+
+func sortedIds(ids map[uint32]struct{}) []uint32 {
+	sorted := slices.AppendSeq(make([]uint32, 0, len(ids)), maps.Keys(ids))
+	slices.Sort(sorted)
+	return sorted
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"errors"
+	"server/app/test"
+	"server/pkg/codec"
+	"server/pkg/net/tcp"
+	router2 "server/pkg/router"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestFeatureFlags(t *testing.T) {
+	f := NewFeatureFlags()
+	if code, _ := f.Check(1, false); code != codec.ErrCodeOk {
+		t.Fatalf("default code %d", code)
+	}
+
+	f.DisableRouter(1, 2)
+	if code, _ := f.Check(1, false); code != codec.ErrCodeRouterDisabled {
+		t.Fatalf("disabled code %d", code)
+	}
+	f.EnableRouter(2)
+	if code, _ := f.Check(2, false); code != codec.ErrCodeOk {
+		t.Fatalf("enabled code %d", code)
+	}
+
+	f.EnterMaintenance("upgrading", 3)
+	if !f.InMaintenance() {
+		t.Fatal("should be in maintenance")
+	}
+	if code, msg := f.Check(2, false); code != codec.ErrCodeMaintenance || msg != "upgrading" {
+		t.Fatalf("maintenance code %d %s", code, msg)
+	}
+	if code, _ := f.Check(3, false); code != codec.ErrCodeOk {
+		t.Fatalf("whitelist code %d", code)
+	}
+	// 已登录的玩家不受维护影响
+	if code, _ := f.Check(2, true); code != codec.ErrCodeOk {
+		t.Fatalf("bound session code %d", code)
+	}
+	// 关闭的路由即使在白名单中也拒绝
+	f.EnterMaintenance("upgrading", 1, 3)
+	if code, _ := f.Check(1, false); code != codec.ErrCodeRouterDisabled {
+		t.Fatalf("disabled whitelist code %d", code)
+	}
+	status := f.Status()
+	if !slices.Equal(status.DisabledRouters, []uint32{1}) || !slices.Equal(status.Whitelist, []uint32{1, 3}) || status.Msg != "upgrading" {
+		t.Fatalf("unexpected status %+v", status)
+	}
+
+	f.ExitMaintenance()
+	if code, _ := f.Check(2, false); code != codec.ErrCodeOk || f.InMaintenance() {
+		t.Fatalf("exit maintenance code %d", code)
+	}
+}
+
+func TestRejectedRequest(t *testing.T) {
+	const tellRouterId = uint32(50)
+	router := router2.NewRouter()
+	router2.RegisterAskRouter[*test.HelloAsk, *test.HelloRsp](router, uint32(test.RouterId_Hello), func(ctx codec.ReqCtx, req *test.HelloAsk) *test.HelloRsp {
+		return &test.HelloRsp{Msg: "hello " + req.Msg}
+	})
+	told := make(chan struct{}, 1)
+	router2.RegisterTellRouter[*test.HelloAsk](router, tellRouterId, func(ctx codec.ReqCtx, req *test.HelloAsk) {
+		told <- struct{}{}
+	})
+	svc := NewService(0, WithRouter(router))
+	err := svc.StartTCPServer("127.0.0.1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer svc.Stop()
+	cl := tcp.NewClient(codec.ProtoSerializer{}, time.Second)
+	err = cl.Dial("127.0.0.1", addrPort(svc.TCPAddr()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+
+	ask := func() (string, error) {
+		rspCh := make(chan string, 1)
+		errCh := make(chan error, 1)
+		err := tcp.AskWithErr[*test.HelloAsk, *test.HelloRsp](cl, 0, uint32(test.RouterId_Hello), &test.HelloAsk{Msg: "a"}, func(rsp *test.HelloRsp) {
+			rspCh <- rsp.Msg
+		}, func(err error) {
+			errCh <- err
+		})
+		if err != nil {
+			return "", err
+		}
+		select {
+		case msg := <-rspCh:
+			return msg, nil
+		case err := <-errCh:
+			return "", err
+		case <-time.After(3 * time.Second):
+			t.Fatal("ask got neither rsp nor err")
+			return "", nil
+		}
+	}
+	expectCode := func(code codec.ErrCode) {
+		_, err := ask()
+		var rspErr *codec.RspErr
+		if !errors.As(err, &rspErr) || rspErr.Code != code {
+			t.Fatalf("expected code %d, got %v", code, err)
+		}
+	}
+
+	svc.FeatureFlags().DisableRouter(uint32(test.RouterId_Hello), tellRouterId)
+	expectCode(codec.ErrCodeRouterDisabled)
+	err = tcp.Tell[*test.HelloAsk](cl, 0, tellRouterId, &test.HelloAsk{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-told:
+		t.Fatal("disabled tell should not be handled")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	svc.FeatureFlags().EnableRouter(uint32(test.RouterId_Hello), tellRouterId)
+	svc.FeatureFlags().EnterMaintenance("upgrading", tellRouterId)
+	expectCode(codec.ErrCodeMaintenance)
+	err = tcp.Tell[*test.HelloAsk](cl, 0, tellRouterId, &test.HelloAsk{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-told:
+	case <-time.After(3 * time.Second):
+		t.Fatal("whitelisted tell not handled")
+	}
+
+	// 元数据处理器也能拿到错误码
+	mdCh := make(chan codec.Metadata, 1)
+	err = tcp.AskContext[*test.HelloAsk, *test.HelloRsp](t.Context(), cl, 0, uint32(test.RouterId_Hello), &test.HelloAsk{}, func(rsp *test.HelloRsp, md codec.Metadata) {
+		mdCh <- md
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case md := <-mdCh:
+		if rspErr := md.Err(); rspErr == nil || rspErr.Code != codec.ErrCodeMaintenance || rspErr.Msg != "upgrading" {
+			t.Fatalf("unexpected md %v", md)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("ask context not called")
+	}
+
+	svc.FeatureFlags().ExitMaintenance()
+	msg, err := ask()
+	if err != nil || msg != "hello a" {
+		t.Fatalf("ask after maintenance %q %v", msg, err)
+	}
+}
+
+func TestMaintenanceKeepsBoundSessions(t *testing.T) {
+	const loginRouterId = uint32(51)
+	var svc *Service
+	router := router2.NewRouter()
+	router2.RegisterAskRouter[*test.HelloAsk, *test.HelloRsp](router, uint32(test.RouterId_Hello), func(ctx codec.ReqCtx, req *test.HelloAsk) *test.HelloRsp {
+		return &test.HelloRsp{Msg: "hello " + req.Msg}
+	})
+	router2.RegisterAskRouter[*test.HelloAsk, *test.HelloRsp](router, loginRouterId, func(ctx codec.ReqCtx, req *test.HelloAsk) *test.HelloRsp {
+		if err := svc.BindUser(ctx.GetSession(), req.Msg); err != nil {
+			return &test.HelloRsp{Msg: err.Error()}
+		}
+		return &test.HelloRsp{Msg: "login " + req.Msg}
+	})
+	svc = NewService(0, WithRouter(router))
+	err := svc.StartTCPServer("127.0.0.1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer svc.Stop()
+	dial := func() *tcp.Client {
+		cl := tcp.NewClient(codec.ProtoSerializer{}, time.Second)
+		if err := cl.Dial("127.0.0.1", addrPort(svc.TCPAddr())); err != nil {
+			t.Fatal(err)
+		}
+		return cl
+	}
+	askErr := func(cl *tcp.Client, routerId uint32, msg string) (string, error) {
+		rspCh := make(chan string, 1)
+		errCh := make(chan error, 1)
+		err := tcp.AskWithErr[*test.HelloAsk, *test.HelloRsp](cl, 0, routerId, &test.HelloAsk{Msg: msg}, func(rsp *test.HelloRsp) {
+			rspCh <- rsp.Msg
+		}, func(err error) {
+			errCh <- err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case msg := <-rspCh:
+			return msg, nil
+		case err := <-errCh:
+			return "", err
+		case <-time.After(3 * time.Second):
+			t.Fatal("ask got neither rsp nor err")
+			return "", nil
+		}
+	}
+
+	player := dial()
+	defer player.Close()
+	if msg, err := askErr(player, loginRouterId, "a"); err != nil || msg != "login a" {
+		t.Fatalf("login before maintenance %q %v", msg, err)
+	}
+	svc.FeatureFlags().EnterMaintenance("upgrading", loginRouterId)
+
+	// 已登录的玩家继续游戏
+	if msg, err := askErr(player, uint32(test.RouterId_Hello), "a"); err != nil || msg != "hello a" {
+		t.Fatalf("bound session during maintenance %q %v", msg, err)
+	}
+	// 新连接只能访问白名单路由，且不能登录
+	newcomer := dial()
+	defer newcomer.Close()
+	_, err = askErr(newcomer, uint32(test.RouterId_Hello), "b")
+	var rspErr *codec.RspErr
+	if !errors.As(err, &rspErr) || rspErr.Code != codec.ErrCodeMaintenance {
+		t.Fatalf("unbound session during maintenance %v", err)
+	}
+	if msg, err := askErr(newcomer, loginRouterId, "b"); err != nil || msg != MaintenanceErr.Error() {
+		t.Fatalf("login during maintenance %q %v", msg, err)
+	}
+
+	svc.FeatureFlags().ExitMaintenance()
+	if msg, err := askErr(newcomer, loginRouterId, "b"); err != nil || msg != "login b" {
+		t.Fatalf("login after maintenance %q %v", msg, err)
+	}
+}
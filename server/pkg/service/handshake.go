@@ -0,0 +1,140 @@
+package service
+
+import (
+	"hutool/logx"
+	"server/pkg/codec"
+	"server/pkg/net/udp"
+	session2 "server/pkg/session"
+	zip2 "server/pkg/zip"
+)
+
+var handshakeKey = session2.NewKey[codec.HandshakeRsp]("sys.handshake")
+
+// ClientHandshake 会话协商的结果，老客户端没有握手
+func (s *Service) ClientHandshake(session *session2.Session) (codec.HandshakeRsp, bool) {
+	return handshakeKey.Get(session)
+}
+
+func (s *Service) handleSysRequest(session *session2.Session, reqPacket codec.C2SPacket) {
+	switch reqPacket.RouterId() {
+	case codec.SysRouterHandshake:
+		s.handleHandshake(session, reqPacket)
+	default:
+		logx.Warnf("unknown sys router %d conn %d", reqPacket.RouterId(), session.GetConnId())
+	}
+}
+
+func (s *Service) handleHandshake(session *session2.Session, reqPacket codec.C2SPacket) {
+	connId := session.GetConnId()
+	hs, err := codec.DecodeHandshake(reqPacket.Body())
+	if err != nil {
+		logx.Errorf("decode handshake err %d %+v", connId, err)
+		s.RemoveSession(connId)
+		return
+	}
+	rsp := s.negotiate(session, hs)
+	if rsp.Code == codec.HandshakeOk && hs.Serializer != codec.SerializerIdUnknown {
+		// 之后的请求在读协程中按协商的序列化解析，需要立即生效
+		serializer, _ := s.serializers.Get(rsp.Serializer)
+		serializerKey.Set(session, serializer)
+	}
+	// 握手响应不经过encodeFor，客户端收到后才按协商结果解压
+	rspPacket := codec.NewS2CRspPacket(reqPacket.ReqId(), rsp.Encode())
+	// 协商结果在写协程中生效，之前排队的数据仍按未握手处理
+	err = s.writerPool.Add(func() struct{} {
+		if rsp.Code == codec.HandshakeOk {
+			handshakeKey.Set(session, rsp)
+		}
+		s.pluginContainer.doPreWritePacket(connId, rspPacket.Bytes())
+		err := s.sessionManger.Push(connId, rspPacket.Bytes())
+		if err != nil {
+			logx.Errorf("push handshake err conn %d %+v", connId, err)
+		}
+		if rsp.Code != codec.HandshakeOk {
+			logx.Warnf("reject conn %d handshake %+v code %d", connId, hs, rsp.Code)
+			s.sessionManger.RemoveSession(connId)
+		}
+		return struct{}{}
+	}, nil, connId)
+	if err != nil {
+		logx.Errorf("handshake err %d %+v", connId, err)
+		return
+	}
+	if rsp.Code == codec.HandshakeOk && s.udpChannel != nil && s.handshakeRequired && rsp.Caps&codec.CapUdp != 0 {
+		s.bindUdp(connId)
+	}
+}
+
+// negotiate 版本取双方较小值，序列化跟随客户端，其余取交集，传输层已压缩时不再压缩
+func (s *Service) negotiate(session *session2.Session, hs codec.Handshake) codec.HandshakeRsp {
+	rsp := codec.HandshakeRsp{
+		Code: codec.HandshakeOk,
+		Handshake: codec.Handshake{
+			Version:      min(hs.Version, codec.ProtocolVersion),
+			Serializer:   s.serializers.IdOf(s.serializer),
+			Compression:  zip2.IdNone,
+			MaxPacketLen: min(hs.MaxPacketLen, s.maxPacketLen),
+			Caps:         hs.Caps & codec.CapAll,
+		},
+	}
+	if hs.Version < codec.MinProtocolVersion {
+		rsp.Code = codec.HandshakeVersionUnsupported
+		return rsp
+	}
+	if hs.Serializer != codec.SerializerIdUnknown {
+		if _, ok := s.serializers.Get(hs.Serializer); !ok {
+			rsp.Code = codec.HandshakeSerializerUnsupported
+			return rsp
+		}
+		rsp.Serializer = hs.Serializer
+	}
+	// 与encodeFor保持一致，否则客户端会解压未压缩的数据
+	if session.NativeCompressed() || session.TextMode() {
+		return rsp
+	}
+	// 只选客户端声明支持的算法，自定义压缩无法声明，握手后不压缩
+	if hs.Compression&zip2.MaskOf(s.zip) != 0 {
+		rsp.Compression = zip2.IdOf(s.zip)
+	}
+	return rsp
+}
+
+// encodeFor 按连接协商的结果压缩，并丢弃超过对端包长的数据
+func (s *Service) encodeFor(connId uint32, data []byte) ([]byte, error) {
+	session, ok := s.sessionManger.GetSession(connId)
+	if !ok {
+		return s.zip.Zip(data)
+	}
+	hs, handshaken := handshakeKey.Get(session)
+	var err error
+	switch {
+	// 文本连接在写出时要解析包转成json，不能压缩
+	case session.NativeCompressed() || session.TextMode():
+	case handshaken && hs.Compression == zip2.IdNone:
+	case !handshaken && (s.handshakeRequired || isSysPush(data)):
+	default:
+		data, err = s.zip.Zip(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if handshaken && uint32(len(data)) > hs.MaxPacketLen {
+		return nil, codec.PacketTooLargeErr
+	}
+	return data, nil
+}
+
+// isSysPush 握手前的系统推送不压缩，客户端此时还不知道压缩算法
+func isSysPush(data []byte) bool {
+	packet, err := codec.BytesToS2CPacket(data)
+	return err == nil && packet.IsPushPacket() && packet.ServiceId() == codec.SysServiceId
+}
+
+func (s *Service) bindUdp(connId uint32) {
+	token := s.udpChannel.Bind(connId)
+	bindPacket := codec.NewS2CPushPacket(codec.SysServiceId, codec.SysRouterUdpBind, udp.EncodeBind(connId, token))
+	err := s.writeAsync(connId, bindPacket.Bytes(), false)
+	if err != nil {
+		logx.Errorf("push udp bind err %d %+v", connId, err)
+	}
+}
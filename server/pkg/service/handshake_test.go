@@ -0,0 +1,157 @@
+package service
+
+import (
+	"compress/flate"
+	"net"
+	"server/app/test"
+	"server/pkg/codec"
+	"server/pkg/net/tcp"
+	"server/pkg/net/ws"
+	router2 "server/pkg/router"
+	session2 "server/pkg/session"
+	zip2 "server/pkg/zip"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func newHelloService(opts ...Option) *Service {
+	router := router2.NewRouter()
+	router2.RegisterAskRouter[*test.HelloAsk, *test.HelloRsp](router, uint32(test.RouterId_Hello), func(ctx codec.ReqCtx, req *test.HelloAsk) *test.HelloRsp {
+		return &test.HelloRsp{Msg: "hello " + req.Msg}
+	})
+	return NewService(0, append(opts, WithRouter(router))...)
+}
+
+// addrPort 服务监听0端口，取系统分配的端口
+func addrPort(addr net.Addr) int {
+	return addr.(*net.TCPAddr).Port
+}
+
+func TestHandshakeCompression(t *testing.T) {
+	svc := newHelloService(WithZip(zip2.GZIP{}))
+	err := svc.StartTCPServer("127.0.0.1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = svc.StartWsServer("127.0.0.1", 0, websocket.Upgrader{}, ws.WithCompression(flate.BestSpeed, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer svc.Stop()
+
+	tcpClient := tcp.NewClient(codec.ProtoSerializer{}, time.Second, tcp.WithZip(zip2.GZIP{}))
+	err = tcpClient.Dial("127.0.0.1", addrPort(svc.TCPAddr()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tcpClient.Close()
+	rsp, ok := tcpClient.Handshake()
+	if !ok || rsp.Compression != zip2.IdGzip {
+		t.Fatalf("tcp handshake %+v", rsp)
+	}
+	askHello(t, func(handler func(rsp *test.HelloRsp)) error {
+		return tcp.Ask[*test.HelloAsk, *test.HelloRsp](tcpClient, 0, uint32(test.RouterId_Hello), &test.HelloAsk{Msg: "tcp"}, handler)
+	}, "hello tcp")
+
+	// permessage-deflate已压缩，握手不能再声明gzip
+	wsClient := ws.NewClient(codec.ProtoSerializer{}, time.Second, ws.WithZip(zip2.GZIP{}), ws.WithClientCompression(flate.BestSpeed, 0))
+	err = wsClient.Dial("127.0.0.1", addrPort(svc.WsAddr()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wsClient.Close()
+	rsp, ok = wsClient.Handshake()
+	if !ok || rsp.Compression != zip2.IdNone {
+		t.Fatalf("ws handshake %+v", rsp)
+	}
+	askHello(t, func(handler func(rsp *test.HelloRsp)) error {
+		return ws.Ask[*test.HelloAsk, *test.HelloRsp](wsClient, 0, uint32(test.RouterId_Hello), &test.HelloAsk{Msg: "ws"}, handler)
+	}, "hello ws")
+}
+
+func askHello(t *testing.T, ask func(handler func(rsp *test.HelloRsp)) error, want string) {
+	done := make(chan string, 1)
+	err := ask(func(rsp *test.HelloRsp) {
+		done <- rsp.Msg
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-done:
+		if msg != want {
+			t.Fatalf("unexpected rsp %q", msg)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("ask %q timeout", want)
+	}
+}
+
+// tagZip 自定义压缩，客户端无法在握手中声明
+type tagZip struct{}
+
+func (tagZip) Zip(data []byte) ([]byte, error) {
+	return append([]byte{'z'}, data...), nil
+}
+
+func (tagZip) Unzip(data []byte) ([]byte, error) {
+	if len(data) == 0 || data[0] != 'z' {
+		return nil, codec.PacketBytesErr
+	}
+	return data[1:], nil
+}
+
+func TestHandshakeOptionalCustomZip(t *testing.T) {
+	svc := newHelloService(WithHandshakeRequired(false), WithZip(tagZip{}))
+	err := svc.StartTCPServer("127.0.0.1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer svc.Stop()
+
+	// 客户端只声明了gzip，不能选自定义压缩
+	client := tcp.NewClient(codec.ProtoSerializer{}, time.Second, tcp.WithZip(zip2.GZIP{}))
+	err = client.Dial("127.0.0.1", addrPort(svc.TCPAddr()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	rsp, ok := client.Handshake()
+	if !ok || rsp.Compression != zip2.IdNone {
+		t.Fatalf("handshake %+v", rsp)
+	}
+	askHello(t, func(handler func(rsp *test.HelloRsp)) error {
+		return tcp.Ask[*test.HelloAsk, *test.HelloRsp](client, 0, uint32(test.RouterId_Hello), &test.HelloAsk{Msg: "tcp"}, handler)
+	}, "hello tcp")
+
+	// 握手前的系统推送不压缩
+	legacy := tcp.NewClient(codec.ProtoSerializer{}, time.Second, tcp.WithHandshake(false))
+	kicked := make(chan codec.KickReason, 1)
+	legacy.OnKick(func(reason codec.KickReason, msg string) {
+		kicked <- reason
+	})
+	err = legacy.Dial("127.0.0.1", addrPort(svc.TCPAddr()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer legacy.Close()
+	for i := 0; i < 100 && svc.sessionManger.Count() < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	svc.sessionManger.Range(func(session *session2.Session) bool {
+		if _, ok := handshakeKey.Get(session); !ok {
+			svc.Kick(session.GetConnId(), codec.KickReasonAdmin, "")
+		}
+		return true
+	})
+	select {
+	case reason := <-kicked:
+		if reason != codec.KickReasonAdmin {
+			t.Fatalf("kick reason %d", reason)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("kick before handshake not received")
+	}
+}
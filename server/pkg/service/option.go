@@ -5,16 +5,22 @@ import (
 	"server/pkg/codec"
 	router2 "server/pkg/router"
 	"server/pkg/session"
+	"server/pkg/trace"
 	zip2 "server/pkg/zip"
 )
 
 type Config struct {
-	router            *router2.Registry
-	serializer        codec.ISerializer
-	sessionOpts       []session.Option
-	plugins           []any
-	writerPoolOptions []taskx.TaskPoolOption
-	zip               zip2.IZip
+	router              *router2.Registry
+	serializer          codec.ISerializer
+	serializers         *codec.SerializerRegistry
+	sessionOpts         []session.Option
+	plugins             []any
+	writerPoolOptions   []taskx.TaskPoolOption
+	datagramPoolOptions []taskx.TaskPoolOption
+	zip                 zip2.IZip
+	maxPacketLen        uint32
+	handshakeRequired   bool
+	tracer              *trace.Tracer
 }
 
 type Option func(*Config)
@@ -31,6 +37,13 @@ func WithSerializer(serializer codec.ISerializer) Option {
 	}
 }
 
+// WithSerializerRegistry 握手时客户端可以选择注册表中的序列化，默认内置proto、json和msgpack
+func WithSerializerRegistry(registry *codec.SerializerRegistry) Option {
+	return func(c *Config) {
+		c.serializers = registry
+	}
+}
+
 func WithSessionOpts(opts ...session.Option) Option {
 	return func(c *Config) {
 		c.sessionOpts = opts
@@ -55,13 +68,45 @@ func WithWriterPoolOptions(options ...taskx.TaskPoolOption) Option {
 	}
 }
 
+// WithDatagramPoolOptions 不可靠通道的处理协程池，默认队列满时丢包
+func WithDatagramPoolOptions(options ...taskx.TaskPoolOption) Option {
+	return func(c *Config) {
+		c.datagramPoolOptions = options
+	}
+}
+
+// WithMaxPacketLen 握手时声明的最大包长，不能超过传输层的限制
+func WithMaxPacketLen(maxPacketLen uint32) Option {
+	return func(c *Config) {
+		c.maxPacketLen = maxPacketLen
+	}
+}
+
+// WithHandshakeRequired 拒绝没有握手的老客户端
+func WithHandshakeRequired(required bool) Option {
+	return func(c *Config) {
+		c.handshakeRequired = required
+	}
+}
+
+// WithTracer 每个请求一个服务端span，上游通过请求元数据传递trace
+func WithTracer(tracer *trace.Tracer) Option {
+	return func(c *Config) {
+		c.tracer = tracer
+	}
+}
+
 func DefaultConfig() *Config {
 	return &Config{
-		router:            router2.NewRouter(),
-		serializer:        codec.ProtoSerializer{},
-		sessionOpts:       []session.Option{},
-		plugins:           []any{},
-		writerPoolOptions: make([]taskx.TaskPoolOption, 0),
-		zip:               zip2.None{},
+		router:              router2.NewRouter(),
+		serializer:          codec.ProtoSerializer{},
+		serializers:         codec.DefaultSerializerRegistry(),
+		sessionOpts:         []session.Option{},
+		plugins:             []any{},
+		writerPoolOptions:   make([]taskx.TaskPoolOption, 0),
+		datagramPoolOptions: []taskx.TaskPoolOption{taskx.WithCanDropTask(true)},
+		zip:                 zip2.None{},
+		maxPacketLen:        codec.MaxPacketLen,
+		handshakeRequired:   false,
 	}
 }
@@ -1,16 +1,24 @@
 package service
 
 import (
+	"reflect"
 	"server/pkg/codec"
 	session2 "server/pkg/session"
+	"slices"
+	"sync"
+	"sync/atomic"
 )
 
+// PluginContainer 插件列表写时复制，每次调用遍历一份快照，运行中增删插件不影响处理中的请求
 type PluginContainer struct {
-	plugins []any
+	// 串行化修改，读取不加锁
+	mu      sync.Mutex
+	plugins atomic.Pointer[[]any]
 }
 
 func NewPluginContainer(plugins []any) *PluginContainer {
 	p := &PluginContainer{}
+	p.plugins.Store(&[]any{})
 	for _, plugin := range plugins {
 		p.Register(plugin)
 	}
@@ -30,6 +38,14 @@ type (
 		HeartBeat(session *session2.Session)
 	}
 
+	PostUserBindPlugin interface {
+		PostUserBind(session *session2.Session, identity any)
+	}
+
+	SessionEndPlugin interface {
+		SessionEnd(session *session2.Session)
+	}
+
 	PreSvcStopPlugin interface {
 		PreSvcStop(svc *Service)
 	}
@@ -37,27 +53,73 @@ type (
 	PostSvcStopPlugin interface {
 		PostSvcStop(svc *Service)
 	}
+
+	// PostReadPacketPlugin 连接上收到的每个包，包括心跳和系统请求，packet只在调用期间有效
+	PostReadPacketPlugin interface {
+		PostReadPacket(session *session2.Session, packet codec.C2SPacket)
+	}
+
+	// PreWritePacketPlugin 写协程中压缩前的包，data不能修改和持有
+	PreWritePacketPlugin interface {
+		PreWritePacket(connId uint32, data []byte)
+	}
 )
 
 func (p *PluginContainer) Register(plugin any) {
-	p.plugins = append(p.plugins, plugin)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	plugins := append(slices.Clone(p.load()), plugin)
+	p.plugins.Store(&plugins)
 }
 
+// Remove 按同一个插件实例删除，插件应使用指针注册，不可比较的值无法删除
 func (p *PluginContainer) Remove(plugin any) {
-	if p.plugins == nil {
-		return
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	plugins := slices.DeleteFunc(slices.Clone(p.load()), func(v any) bool {
+		return samePlugin(v, plugin)
+	})
+	p.plugins.Store(&plugins)
+}
+
+// Replace 原位置替换插件保持调用顺序，old不存在时追加到末尾
+func (p *PluginContainer) Replace(old any, plugin any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	plugins := slices.Clone(p.load())
+	i := slices.IndexFunc(plugins, func(v any) bool {
+		return samePlugin(v, old)
+	})
+	if i < 0 {
+		plugins = append(plugins, plugin)
+	} else {
+		plugins[i] = plugin
 	}
-	plugins := make([]any, 0, len(p.plugins))
-	for _, p := range p.plugins {
-		if p != plugin {
-			plugins = append(plugins, p)
-		}
+	p.plugins.Store(&plugins)
+}
+
+// samePlugin 直接用==比较包含切片、map等字段的值会panic
+func samePlugin(a any, b any) bool {
+	if reflect.TypeOf(a) != reflect.TypeOf(b) {
+		return false
 	}
-	p.plugins = plugins
+	if !reflect.ValueOf(a).Comparable() || !reflect.ValueOf(b).Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// Plugins 当前插件列表的副本
+func (p *PluginContainer) Plugins() []any {
+	return slices.Clone(p.load())
+}
+
+func (p *PluginContainer) load() []any {
+	return *p.plugins.Load()
 }
 
 func (p *PluginContainer) doPreReadRequest(session *session2.Session, reqPacket codec.C2SPacket) bool {
-	for _, plugin := range p.plugins {
+	for _, plugin := range p.load() {
 		if plugin, ok := plugin.(PreReadRequestPlugin); ok {
 			if !plugin.PreReadReadRequest(session, reqPacket) {
 				return false
@@ -68,7 +130,7 @@ func (p *PluginContainer) doPreReadRequest(session *session2.Session, reqPacket
 }
 
 func (p *PluginContainer) doPostReadRequest(session *session2.Session, req any) {
-	for _, plugin := range p.plugins {
+	for _, plugin := range p.load() {
 		if plugin, ok := plugin.(PostReadRequestPlugin); ok {
 			plugin.PostReadRequest(session, req)
 		}
@@ -76,15 +138,31 @@ func (p *PluginContainer) doPostReadRequest(session *session2.Session, req any)
 }
 
 func (p *PluginContainer) doHeartBeat(session *session2.Session) {
-	for _, plugin := range p.plugins {
+	for _, plugin := range p.load() {
 		if plugin, ok := plugin.(HeartBeatPlugin); ok {
 			plugin.HeartBeat(session)
 		}
 	}
 }
 
+func (p *PluginContainer) doPostUserBind(session *session2.Session, identity any) {
+	for _, plugin := range p.load() {
+		if plugin, ok := plugin.(PostUserBindPlugin); ok {
+			plugin.PostUserBind(session, identity)
+		}
+	}
+}
+
+func (p *PluginContainer) doSessionEnd(session *session2.Session) {
+	for _, plugin := range p.load() {
+		if plugin, ok := plugin.(SessionEndPlugin); ok {
+			plugin.SessionEnd(session)
+		}
+	}
+}
+
 func (p *PluginContainer) doPreSvcStop(svc *Service) {
-	for _, plugin := range p.plugins {
+	for _, plugin := range p.load() {
 		if plugin, ok := plugin.(PreSvcStopPlugin); ok {
 			plugin.PreSvcStop(svc)
 		}
@@ -92,9 +170,25 @@ func (p *PluginContainer) doPreSvcStop(svc *Service) {
 }
 
 func (p *PluginContainer) doPostSvcStop(svc *Service) {
-	for _, plugin := range p.plugins {
+	for _, plugin := range p.load() {
 		if plugin, ok := plugin.(PostSvcStopPlugin); ok {
 			plugin.PostSvcStop(svc)
 		}
 	}
 }
+
+func (p *PluginContainer) doPostReadPacket(session *session2.Session, packet codec.C2SPacket) {
+	for _, plugin := range p.load() {
+		if plugin, ok := plugin.(PostReadPacketPlugin); ok {
+			plugin.PostReadPacket(session, packet)
+		}
+	}
+}
+
+func (p *PluginContainer) doPreWritePacket(connId uint32, data []byte) {
+	for _, plugin := range p.load() {
+		if plugin, ok := plugin.(PreWritePacketPlugin); ok {
+			plugin.PreWritePacket(connId, data)
+		}
+	}
+}
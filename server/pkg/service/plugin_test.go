@@ -0,0 +1,50 @@
+package service
+
+import (
+	session2 "server/pkg/session"
+	"testing"
+)
+
+type countPlugin struct {
+	n int
+}
+
+func (p *countPlugin) HeartBeat(session *session2.Session) {
+	p.n++
+}
+
+// slicePlugin 值类型插件，包含不可比较的字段
+type slicePlugin struct {
+	ids []uint32
+}
+
+func (p slicePlugin) HeartBeat(session *session2.Session) {}
+
+func TestPluginContainerIdentity(t *testing.T) {
+	a, b, c := &countPlugin{}, &countPlugin{}, &countPlugin{}
+	value := slicePlugin{ids: []uint32{1}}
+	p := NewPluginContainer([]any{a, value, b})
+
+	// 不可比较的值不能panic
+	p.Remove(slicePlugin{ids: []uint32{1}})
+	p.Replace(value, c)
+	if len(p.Plugins()) != 4 {
+		t.Fatalf("unexpected plugins %d", len(p.Plugins()))
+	}
+
+	p.Replace(b, &countPlugin{n: 10})
+	p.Remove(a)
+	p.Remove(c)
+	plugins := p.Plugins()
+	if len(plugins) != 2 {
+		t.Fatalf("unexpected plugins %d", len(plugins))
+	}
+	if plugin, ok := plugins[1].(*countPlugin); !ok || plugin.n != 10 {
+		t.Fatalf("replace should keep position %+v", plugins)
+	}
+
+	p.doHeartBeat(nil)
+	if plugins[1].(*countPlugin).n != 11 || b.n != 0 {
+		t.Fatal("removed or replaced plugin still called")
+	}
+}
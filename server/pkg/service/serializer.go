@@ -0,0 +1,56 @@
+package service
+
+import (
+	"server/pkg/codec"
+	session2 "server/pkg/session"
+)
+
+// serializerKey 握手时客户端选择的序列化
+var serializerKey = session2.NewKey[codec.ISerializer]("sys.serializer")
+
+// SerializerRegistry 客户端和服务端在同一进程时可以共用
+func (s *Service) SerializerRegistry() *codec.SerializerRegistry {
+	return s.serializers
+}
+
+// serializerFor 优先级: 注册表中的路由绑定 > 连接协商 > 服务默认
+// 路由绑定只放在注册表中，客户端共用注册表时两端一致
+func (s *Service) serializerFor(session *session2.Session, routerId uint32) codec.ISerializer {
+	if serializer, ok := s.serializers.ForRoute(s.svcId, routerId); ok {
+		return serializer
+	}
+	return s.SessionSerializer(session)
+}
+
+// SessionSerializer 连接协商的序列化，不考虑路由绑定，会话不存在时按服务默认
+func (s *Service) SessionSerializer(session *session2.Session) codec.ISerializer {
+	if session == nil {
+		return s.serializer
+	}
+	if serializer, ok := serializerKey.Get(session); ok {
+		return serializer
+	}
+	if session.TextMode() {
+		if serializer, ok := s.serializers.Get(codec.SerializerIdJson); ok {
+			return serializer
+		}
+	}
+	return s.serializer
+}
+
+// SerializerById 其它节点上的连接按记录的序列化id编码，路由绑定优先，未注册的id按服务默认
+func (s *Service) SerializerById(routerId uint32, id uint8) codec.ISerializer {
+	if serializer, ok := s.serializers.ForRoute(s.svcId, routerId); ok {
+		return serializer
+	}
+	if serializer, ok := s.serializers.Get(id); ok {
+		return serializer
+	}
+	return s.serializer
+}
+
+// serializerForConn 推送和服务端请求没有路由配置，会话不存在时按服务默认
+func (s *Service) serializerForConn(connId uint32, routerId uint32) codec.ISerializer {
+	session, _ := s.sessionManger.GetSession(connId)
+	return s.serializerFor(session, routerId)
+}
@@ -1,33 +1,49 @@
 package service
 
 import (
+	"errors"
+	"hutool/bytex"
 	"hutool/logx"
 	"hutool/reflectx"
 	"hutool/taskx"
+	"hutool/timewheel"
+	"net"
 	"server/pkg/codec"
 	"server/pkg/net/inet"
 	"server/pkg/net/kcp"
 	"server/pkg/net/tcp"
+	"server/pkg/net/udp"
 	"server/pkg/net/ws"
 	router2 "server/pkg/router"
 	session2 "server/pkg/session"
+	"server/pkg/trace"
 	zip2 "server/pkg/zip"
 
 	"github.com/gorilla/websocket"
 )
 
+// rspBufferSize 响应缓冲的初始大小，不够时append扩容
+const rspBufferSize = 256
+
 type Service struct {
 	svcId uint32
 	// zips
 	zip zip2.IZip
+	// 协议协商
+	maxPacketLen      uint32
+	handshakeRequired bool
+	tracer            *trace.Tracer
 
 	// net
 	tcpServer *tcp.Server
 	wsServer  *ws.Server
 	kcpServer *kcp.Server
+	// 不可靠通道
+	udpChannel *udp.Channel
 
 	// codec
-	serializer codec.ISerializer
+	serializer  codec.ISerializer
+	serializers *codec.SerializerRegistry
 
 	// router
 	routerManager *router2.Manager
@@ -38,7 +54,16 @@ type Service struct {
 	// plugin
 	pluginContainer *PluginContainer
 
+	features *FeatureFlags
+
 	writerPool *taskx.TaskPool[struct{}]
+	// 不可靠通道的包按connId分发，避免慢handler阻塞udp读协程
+	datagramPool        *taskx.TaskPool[struct{}]
+	datagramPoolOptions []taskx.TaskPoolOption
+
+	// 会话过期和服务端请求超时共用，会话显式设置检查间隔时另建时间轮
+	wheel *timewheel.TimingWheel
+	asks  *askTable
 }
 
 func NewService(svcId uint32, opts ...Option) *Service {
@@ -47,22 +72,34 @@ func NewService(svcId uint32, opts ...Option) *Service {
 		opt(cfg)
 	}
 
+	pluginContainer := NewPluginContainer(cfg.plugins)
+	wheel := timewheel.NewTimingWheel()
+	sessionOpts := append([]session2.Option{session2.WithTimingWheel(wheel)}, cfg.sessionOpts...)
+	sessionOpts = append(sessionOpts, session2.WithOnSessionEnd(pluginContainer.doSessionEnd))
 	s := &Service{
-		svcId:           svcId,
-		tcpServer:       nil,
-		serializer:      cfg.serializer,
-		routerManager:   router2.NewManager(cfg.router),
-		sessionManger:   session2.NewManager(cfg.sessionOpts...),
-		pluginContainer: NewPluginContainer(cfg.plugins),
-		writerPool:      taskx.NewTaskPool[struct{}](cfg.writerPoolOptions...),
-		zip:             cfg.zip,
+		svcId:               svcId,
+		tcpServer:           nil,
+		serializer:          cfg.serializer,
+		serializers:         cfg.serializers,
+		routerManager:       router2.NewManager(cfg.router),
+		sessionManger:       session2.NewManager(sessionOpts...),
+		pluginContainer:     pluginContainer,
+		features:            NewFeatureFlags(),
+		writerPool:          taskx.NewTaskPool[struct{}](cfg.writerPoolOptions...),
+		datagramPoolOptions: cfg.datagramPoolOptions,
+		zip:                 cfg.zip,
+		maxPacketLen:        cfg.maxPacketLen,
+		handshakeRequired:   cfg.handshakeRequired,
+		tracer:              cfg.tracer,
+		wheel:               wheel,
+		asks:                newAskTable(wheel),
 	}
 
 	return s
 }
 
-func (s *Service) StartTCPServer(host string, port int) error {
-	s.tcpServer = tcp.NewServer()
+func (s *Service) StartTCPServer(host string, port int, opts ...tcp.Option) error {
+	s.tcpServer = tcp.NewServer(opts...)
 	err := s.tcpServer.ListenAndServe(host, port, s)
 	if err != nil {
 		return err
@@ -70,8 +107,8 @@ func (s *Service) StartTCPServer(host string, port int) error {
 	return nil
 }
 
-func (s *Service) StartKcpServer(host string, port int) error {
-	s.kcpServer = kcp.NewServer()
+func (s *Service) StartKcpServer(host string, port int, opts ...kcp.Option) error {
+	s.kcpServer = kcp.NewServer(opts...)
 	err := s.kcpServer.ListenAndServe(host, port, s)
 	if err != nil {
 		return err
@@ -79,8 +116,8 @@ func (s *Service) StartKcpServer(host string, port int) error {
 	return nil
 }
 
-func (s *Service) StartWsServer(host string, port int, upgrader websocket.Upgrader) error {
-	s.wsServer = ws.NewServer()
+func (s *Service) StartWsServer(host string, port int, upgrader websocket.Upgrader, opts ...ws.Option) error {
+	s.wsServer = ws.NewServer(opts...)
 	err := s.wsServer.ListenAndServe(host, port, s, upgrader)
 	if err != nil {
 		return err
@@ -88,6 +125,34 @@ func (s *Service) StartWsServer(host string, port int, upgrader websocket.Upgrad
 	return nil
 }
 
+// TCPAddr tcp服务实际监听的地址，端口传0时由系统分配，没有启动时返回nil
+func (s *Service) TCPAddr() net.Addr {
+	if s.tcpServer == nil {
+		return nil
+	}
+	return s.tcpServer.Addr()
+}
+
+// WsAddr ws服务实际监听的地址，挂载到外部mux或没有启动时返回nil
+func (s *Service) WsAddr() net.Addr {
+	if s.wsServer == nil {
+		return nil
+	}
+	return s.wsServer.Addr()
+}
+
+// StartUdpChannel 开启与可靠连接配对的不可靠通道，需要在接受连接前调用
+func (s *Service) StartUdpChannel(host string, port int) error {
+	s.udpChannel = udp.NewChannel()
+	s.datagramPool = taskx.NewTaskPool[struct{}](s.datagramPoolOptions...)
+	err := s.udpChannel.Listen(host, port, s)
+	if err != nil {
+		s.datagramPool.Stop()
+		return err
+	}
+	return nil
+}
+
 func (s *Service) Stop() {
 	s.pluginContainer.doPreSvcStop(s)
 	if s.tcpServer != nil {
@@ -99,18 +164,50 @@ func (s *Service) Stop() {
 	if s.kcpServer != nil {
 		s.kcpServer.Stop()
 	}
+	if s.udpChannel != nil {
+		s.udpChannel.Close()
+		s.datagramPool.Stop()
+	}
 	s.sessionManger.Stop()
 	s.writerPool.Stop()
+	s.wheel.Stop()
 	s.pluginContainer.doPostSvcStop(s)
 }
 
 func (s *Service) OnConnStart(conn inet.IConn) {
 	s.sessionManger.BindSession(conn)
 	logx.Debugf("bind session: %d", conn.GetConnId())
+	// 需要握手时等握手成功后再绑定
+	if s.udpChannel != nil && !s.handshakeRequired {
+		s.bindUdp(conn.GetConnId())
+	}
 }
 
-func (s *Service) OnConnRead(conn inet.IConn, readData []byte) {
-	reqPacket, err := codec.BytesToC2SPacket(readData)
+// OnDatagram 不可靠通道只处理tell
+func (s *Service) OnDatagram(connId uint32, data []byte) {
+	reqPacket, err := codec.BytesToC2SPacket(data)
+	if err != nil {
+		logx.Errorf("unmashal err %+v", err)
+		return
+	}
+	if reqPacket.IsHeartbeatPacket() || reqPacket.IsRspPacket() || !reqPacket.IsOneWay() {
+		return
+	}
+	session, ok := s.sessionManger.GetSession(connId)
+	if !ok {
+		return
+	}
+	err = s.datagramPool.Add(func() struct{} {
+		s.handleOnPacket(session, reqPacket)
+		return struct{}{}
+	}, nil, connId)
+	if err != nil {
+		logx.Debugf("drop datagram conn %d %+v", connId, err)
+	}
+}
+
+func (s *Service) OnConnRead(conn inet.IConn, readBuf *bytex.Buffer) {
+	reqPacket, err := codec.BufferToC2SPacket(readBuf)
 	if err != nil {
 		logx.Errorf("unmashal err %+v", err)
 		return
@@ -121,10 +218,26 @@ func (s *Service) OnConnRead(conn inet.IConn, readData []byte) {
 		logx.Warnf("session not found: %d", conn.GetConnId())
 		return
 	}
+	s.pluginContainer.doPostReadPacket(session, reqPacket)
 
 	if reqPacket.IsHeartbeatPacket() {
 		s.sessionManger.KeepAlive(conn.GetConnId())
 		s.pluginContainer.doHeartBeat(session)
+		return
+	}
+	if !reqPacket.IsRspPacket() && reqPacket.ServiceId() == codec.SysServiceId {
+		s.handleSysRequest(session, reqPacket)
+		return
+	}
+	if s.handshakeRequired {
+		if _, ok := handshakeKey.Get(session); !ok {
+			logx.Warnf("conn %d send packet before handshake", conn.GetConnId())
+			s.RemoveSession(conn.GetConnId())
+			return
+		}
+	}
+	if reqPacket.IsRspPacket() {
+		s.onClientRsp(session, reqPacket)
 	} else {
 		s.handleOnPacket(session, reqPacket)
 	}
@@ -143,16 +256,36 @@ func (s *Service) handleOnPacket(session *session2.Session, reqPacket codec.C2SP
 	reqBodyBytes := reqPacket.Body()
 	routerId := reqPacket.RouterId()
 	reqCtx := codec.NewReqCtx(reqPacket, session)
+	defer reqCtx.Release()
+	reqCtx, span := s.startSpan(reqCtx, reqPacket)
+	defer span.End()
+	// 排队期间已经超时或连接已经断开，调用方不再等待结果
+	if reqCtx.Err() != nil {
+		span.SetError(reqCtx.Err())
+		logx.Debugf("skip request conn %d router %d %+v", session.GetConnId(), routerId, reqCtx.Err())
+		return
+	}
+	_, bound := s.sessionManger.Identity(session)
+	if code, msg := s.features.Check(routerId, bound); code != codec.ErrCodeOk {
+		span.SetError(RouterRejectedErr)
+		if !isOneWay {
+			s.writeErrRsp(session.GetConnId(), reqPacket.ReqId(), code, msg)
+		}
+		return
+	}
 
 	if isOneWay {
 		router, ok := s.routerManager.GetTellRouter(routerId)
 		if !ok {
+			span.SetError(RouterNotFoundErr)
 			return
 		}
 
+		serializer := s.serializerFor(session, routerId)
 		reqBody := reflectx.NewPointerIns(router.ReqType)
-		err := s.serializer.Unmarshal(reqBodyBytes, reqBody)
+		err := serializer.Unmarshal(reqBodyBytes, reqBody)
 		if err != nil {
+			span.SetError(err)
 			logx.Errorf("unmashal err %+v", err)
 			return
 		}
@@ -164,26 +297,35 @@ func (s *Service) handleOnPacket(session *session2.Session, reqPacket codec.C2SP
 
 		router, ok := s.routerManager.GetAskRouter(routerId)
 		if !ok {
+			span.SetError(RouterNotFoundErr)
 			return
 		}
 
+		serializer := s.serializerFor(session, routerId)
 		reqBody := reflectx.NewPointerIns(router.ReqType)
-		err := s.serializer.Unmarshal(reqBodyBytes, reqBody)
+		err := serializer.Unmarshal(reqBodyBytes, reqBody)
 		if err != nil {
+			span.SetError(err)
 			logx.Errorf("unmashal err %+v", err)
 			return
 		}
 
 		s.pluginContainer.doPostReadRequest(session, reqBody)
 		rspBody := router.Handler(reqCtx, reqBody)
-		rspBodyBytes, err := s.serializer.Marshal(rspBody)
+		// 响应直接序列化到池化缓冲，写出后归还
+		rspBuf := bytex.AllocateBuffer(rspBufferSize)
+		rspBytes, err := codec.AppendS2CMetadata(codec.AppendS2CRspHead(rspBuf.Bytes()[:0], reqId), reqCtx.RspMetadata())
+		if err == nil {
+			rspBytes, err = codec.MarshalAppend(serializer, rspBytes, rspBody)
+		}
 		if err != nil {
+			rspBuf.Release()
+			span.SetError(err)
 			logx.Errorf("marshal err %+v", err)
 			return
 		}
-
-		rspPacket := codec.NewS2CRspPacket(reqId, rspBodyBytes)
-		err = s.writeAsync(session.GetConnId(), rspPacket.Bytes())
+		rspBuf.SetBytes(rspBytes)
+		err = s.writeBufferAsync(session.GetConnId(), rspBuf)
 		if err != nil {
 			logx.Infof("push err %d %+v", session.GetConnId(), err)
 		}
@@ -191,18 +333,50 @@ func (s *Service) handleOnPacket(session *session2.Session, reqPacket codec.C2SP
 }
 
 func (s *Service) OnConnStop(conn inet.IConn) {
+	if s.udpChannel != nil {
+		s.udpChannel.Unbind(conn.GetConnId())
+	}
 	s.sessionManger.RemoveSession(conn.GetConnId())
+	s.failAsks(conn.GetConnId())
 }
 
 func (s *Service) Push(connId uint32, routerId uint32, data any) error {
-	pushBodyBytes, err := s.serializer.Marshal(data)
+	pushBodyBytes, err := s.serializerForConn(connId, routerId).Marshal(data)
+	if err != nil {
+		logx.Errorf("marshal err %+v", err)
+		return err
+	}
+	pushPacket := codec.NewS2CPushPacket(s.svcId, routerId, pushBodyBytes)
+
+	err = s.writeAsync(connId, pushPacket.Bytes(), false)
+	if err != nil {
+		logx.Errorf("push err %d %+v", connId, err)
+		return err
+	}
+	return nil
+}
+
+// PushRaw 推送已经序列化好的消息体，用于转发，消息体需要按目标连接的序列化编码
+func (s *Service) PushRaw(connId uint32, routerId uint32, body []byte) error {
+	pushPacket := codec.NewS2CPushPacket(s.svcId, routerId, body)
+	err := s.writeAsync(connId, pushPacket.Bytes(), false)
+	if err != nil {
+		logx.Errorf("push err %d %+v", connId, err)
+		return err
+	}
+	return nil
+}
+
+// PushLowPriority 客户端积压过多时会被丢弃
+func (s *Service) PushLowPriority(connId uint32, routerId uint32, data any) error {
+	pushBodyBytes, err := s.serializerForConn(connId, routerId).Marshal(data)
 	if err != nil {
 		logx.Errorf("marshal err %+v", err)
 		return err
 	}
 	pushPacket := codec.NewS2CPushPacket(s.svcId, routerId, pushBodyBytes)
 
-	err = s.writeAsync(connId, pushPacket.Bytes())
+	err = s.writeAsync(connId, pushPacket.Bytes(), true)
 	if err != nil {
 		logx.Errorf("push err %d %+v", connId, err)
 		return err
@@ -210,14 +384,46 @@ func (s *Service) Push(connId uint32, routerId uint32, data any) error {
 	return nil
 }
 
-func (s *Service) writeAsync(connId uint32, data []byte) error {
+// PushUnreliable 通过不可靠通道推送，客户端未绑定通道或超过单个数据报长度时退回可靠连接
+func (s *Service) PushUnreliable(connId uint32, routerId uint32, data any) error {
+	if s.udpChannel == nil {
+		return s.Push(connId, routerId, data)
+	}
+	pushBodyBytes, err := s.serializerForConn(connId, routerId).Marshal(data)
+	if err != nil {
+		logx.Errorf("marshal err %+v", err)
+		return err
+	}
+	pushPacket := codec.NewS2CPushPacket(s.svcId, routerId, pushBodyBytes)
+	err = s.udpChannel.Write(connId, pushPacket.Bytes())
+	if errors.Is(err, udp.NotBoundErr) || errors.Is(err, udp.DatagramLenErr) {
+		err = s.writeAsync(connId, pushPacket.Bytes(), true)
+	}
+	if err != nil {
+		logx.Errorf("push unreliable err %d %+v", connId, err)
+		return err
+	}
+	return nil
+}
+
+// writeAsync 连接的Write只是入队，不会因为单个慢连接阻塞写协程池
+func (s *Service) writeAsync(connId uint32, data []byte, lowPriority bool) error {
 	err := s.writerPool.Add(func() struct{} {
-		zipData, err := s.zip.Zip(data)
+		s.pluginContainer.doPreWritePacket(connId, data)
+		zipData, err := s.encodeFor(connId, data)
 		if err != nil {
-			logx.Errorf("zip err %d %+v", connId, err)
+			logx.Errorf("encode err %d %+v", connId, err)
 			return struct{}{}
 		}
-		err = s.sessionManger.Push(connId, zipData)
+		if lowPriority {
+			err = s.sessionManger.PushLowPriority(connId, zipData)
+			if err != nil {
+				logx.Debugf("drop low priority push conn %d %+v", connId, err)
+				return struct{}{}
+			}
+		} else {
+			err = s.sessionManger.Push(connId, zipData)
+		}
 		if err != nil {
 			logx.Errorf("push err conn %d %+v", connId, err)
 			return struct{}{}
@@ -227,6 +433,119 @@ func (s *Service) writeAsync(connId uint32, data []byte) error {
 	return err
 }
 
+// writeBufferAsync buf的引用转交出去，压缩后生成了新切片时直接归还缓冲
+func (s *Service) writeBufferAsync(connId uint32, buf *bytex.Buffer) error {
+	err := s.writerPool.Add(func() struct{} {
+		s.pluginContainer.doPreWritePacket(connId, buf.Bytes())
+		data, err := s.encodeFor(connId, buf.Bytes())
+		if err != nil {
+			buf.Release()
+			logx.Errorf("encode err %d %+v", connId, err)
+			return struct{}{}
+		}
+		if sameBytes(data, buf.Bytes()) {
+			err = s.sessionManger.PushBuffer(connId, buf)
+		} else {
+			buf.Release()
+			err = s.sessionManger.Push(connId, data)
+		}
+		if err != nil {
+			logx.Errorf("push err conn %d %+v", connId, err)
+		}
+		return struct{}{}
+	}, nil, connId)
+	if err != nil {
+		buf.Release()
+	}
+	return err
+}
+
+func sameBytes(a []byte, b []byte) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
+}
+
+// writeErrRsp 拒绝请求时告知客户端，避免客户端一直等到超时
+func (s *Service) writeErrRsp(connId uint32, reqId uint32, code codec.ErrCode, msg string) {
+	rspPacket, err := codec.NewS2CErrRspPacket(reqId, code, msg)
+	if err != nil {
+		logx.Errorf("err rsp packet err %+v", err)
+		return
+	}
+	err = s.writeAsync(connId, rspPacket.Bytes(), false)
+	if err != nil {
+		logx.Infof("push err %d %+v", connId, err)
+	}
+}
+
+// BindUser 绑定用户身份，按会话管理器的登录策略处理重复登录，维护期间拒绝新的绑定
+func (s *Service) BindUser(session *session2.Session, identity any) error {
+	if s.features.InMaintenance() {
+		return MaintenanceErr
+	}
+	kicked, err := s.sessionManger.BindIdentity(session, identity)
+	if err != nil {
+		return err
+	}
+	for _, old := range kicked {
+		s.Kick(old.GetConnId(), codec.KickReasonDuplicateLogin, "login from other device")
+	}
+	s.pluginContainer.doPostUserBind(session, identity)
+	return nil
+}
+
+// Kick 推送踢下线原因后关闭连接，和推送在同一个写协程保证先发后关
+func (s *Service) Kick(connId uint32, reason codec.KickReason, msg string) {
+	kickPacket := codec.NewS2CPushPacket(codec.SysServiceId, codec.SysRouterKick, codec.EncodeKick(reason, msg))
+	err := s.writeAsync(connId, kickPacket.Bytes(), false)
+	if err != nil {
+		logx.Errorf("push kick err %d %+v", connId, err)
+	}
+	err = s.writerPool.Add(func() struct{} {
+		s.sessionManger.RemoveSession(connId)
+		return struct{}{}
+	}, nil, connId)
+	if err != nil {
+		s.sessionManger.RemoveSession(connId)
+	}
+}
+
+func (s *Service) Serializer() codec.ISerializer {
+	return s.serializer
+}
+
+func (s *Service) SessionManager() *session2.Manager {
+	return s.sessionManger
+}
+
+// RouterManager 运行中可以通过Merge、Remove更新路由
+func (s *Service) RouterManager() *router2.Manager {
+	return s.routerManager
+}
+
+// PluginContainer 运行中可以增删、替换插件
+func (s *Service) PluginContainer() *PluginContainer {
+	return s.pluginContainer
+}
+
+// FeatureFlags 运行时关闭路由、切换维护模式
+func (s *Service) FeatureFlags() *FeatureFlags {
+	return s.features
+}
+
+// Routers 已注册的路由，用于调试展示
+func (s *Service) Routers() []router2.Info {
+	infos := s.routerManager.Infos()
+	for i := range infos {
+		infos[i].Serializer, _ = s.serializers.RouteSerializerId(s.svcId, infos[i].RouterId)
+	}
+	return infos
+}
+
+// WriterPoolStats 写协程池每个队列积压的任务数和队列容量
+func (s *Service) WriterPoolStats() (queueLens []int, queueSize int) {
+	return s.writerPool.QueueLens(), s.writerPool.QueueSize()
+}
+
 func (s *Service) RemoveSession(connId uint32) {
 	s.sessionManger.RemoveSession(connId)
 }
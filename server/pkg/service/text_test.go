@@ -0,0 +1,74 @@
+package service
+
+import (
+	"encoding/json"
+	"server/app/test"
+	"server/pkg/codec"
+	"server/pkg/net/ws"
+	router2 "server/pkg/router"
+	zip2 "server/pkg/zip"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestWsTextModeRoundTrip(t *testing.T) {
+	router := router2.NewRouter()
+	var svc *Service
+	router2.RegisterAskRouter[*test.HelloAsk, *test.HelloRsp](router, uint32(test.RouterId_Hello), func(ctx codec.ReqCtx, req *test.HelloAsk) *test.HelloRsp {
+		// 推送也要转成json文本
+		err := svc.Push(ctx.GetSession().GetConnId(), uint32(test.RouterId_Hello), &test.HelloRsp{Msg: "push " + req.Msg})
+		if err != nil {
+			t.Error(err)
+		}
+		return &test.HelloRsp{Msg: "hello " + req.Msg}
+	})
+	// 服务默认gzip压缩，文本连接需要跳过
+	svc = NewService(0, WithRouter(router), WithZip(zip2.GZIP{}))
+	err := svc.StartWsServer("127.0.0.1", 0, websocket.Upgrader{}, ws.WithTextMode(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer svc.Stop()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws://"+svc.WsAddr().String()+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	err = conn.WriteMessage(websocket.TextMessage, []byte(`{"svc":0,"router":0,"reqId":1,"body":{"msg":"hi"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]bool{}
+	_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	for len(got) < 2 {
+		messageType, message, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("read err %v, got %v", err, got)
+		}
+		if messageType != websocket.TextMessage {
+			t.Fatalf("unexpected message type %d", messageType)
+		}
+		var envelope codec.JsonS2CEnvelope
+		err = json.Unmarshal(message, &envelope)
+		if err != nil {
+			t.Fatalf("invalid json %q", message)
+		}
+		var rsp test.HelloRsp
+		err = json.Unmarshal(envelope.Body, &rsp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case envelope.Push && rsp.Msg == "push hi":
+			got["push"] = true
+		case !envelope.Push && envelope.ReqId == 1 && rsp.Msg == "hello hi":
+			got["rsp"] = true
+		default:
+			t.Fatalf("unexpected message %s", message)
+		}
+	}
+}
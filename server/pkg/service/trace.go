@@ -0,0 +1,26 @@
+package service
+
+import (
+	"server/pkg/codec"
+	"server/pkg/trace"
+)
+
+// startSpan 未配置tracer时返回nil span，调用方不需要判断
+func (s *Service) startSpan(reqCtx codec.ReqCtx, reqPacket codec.C2SPacket) (codec.ReqCtx, *trace.Span) {
+	if s.tracer == nil {
+		return reqCtx, nil
+	}
+	ctx := reqCtx.Context
+	if remote, ok := trace.Extract(reqCtx.Metadata()); ok {
+		ctx = trace.ContextWithRemote(ctx, remote)
+	}
+	name := "server.ask"
+	if reqPacket.IsOneWay() {
+		name = "server.tell"
+	}
+	ctx, span := s.tracer.Start(ctx, name, trace.KindServer)
+	span.SetAttr("rpc.service_id", reqPacket.ServiceId())
+	span.SetAttr("rpc.router_id", reqPacket.RouterId())
+	span.SetAttr("net.conn_id", reqCtx.GetSession().GetConnId())
+	return reqCtx.WithContext(ctx), span
+}
@@ -2,4 +2,9 @@ package session
 
 import "errors"
 
-var NotFoundErr = errors.New("session not found")
+var (
+	NotFoundErr       = errors.New("session not found")
+	DuplicateLoginErr = errors.New("duplicate login")
+	// AttrNotComparableErr 索引属性的值不可比较，无法建立索引
+	AttrNotComparableErr = errors.New("indexed attr value not comparable")
+)
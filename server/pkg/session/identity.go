@@ -0,0 +1,54 @@
+package session
+
+type LoginPolicy int
+
+const (
+	// LoginKickOld 新登录顶掉旧连接
+	LoginKickOld LoginPolicy = iota
+	// LoginRejectNew 已登录时拒绝新连接
+	LoginRejectNew
+	// LoginAllowMulti 允许多端同时在线
+	LoginAllowMulti
+)
+
+// BindIdentity 按登录策略把用户身份绑定到会话，返回需要踢下线的旧会话，身份必须可比较
+func (m *Manager) BindIdentity(s *Session, identity any) ([]*Session, error) {
+	if !indexable(identity) {
+		return nil, AttrNotComparableErr
+	}
+	m.identityMu.Lock()
+	defer m.identityMu.Unlock()
+
+	idx, _ := m.indexOf(m.identityKey)
+	olds := make([]*Session, 0)
+	for _, old := range idx.find(identity) {
+		if old != s {
+			olds = append(olds, old)
+		}
+	}
+
+	switch m.loginPolicy {
+	case LoginRejectNew:
+		if len(olds) > 0 {
+			return nil, DuplicateLoginErr
+		}
+	case LoginKickOld:
+		// 先移出索引，旧会话异步关闭期间不会再被查到
+		for _, old := range olds {
+			idx.delete(old, m.identityKey)
+		}
+	case LoginAllowMulti:
+		olds = nil
+	}
+	_ = idx.set(s, m.identityKey, identity)
+	return olds, nil
+}
+
+// Identity 会话绑定的用户身份
+func (m *Manager) Identity(s *Session) (any, bool) {
+	return s.Get(m.identityKey)
+}
+
+func (m *Manager) FindByIdentity(identity any) []*Session {
+	return m.FindByAttr(m.identityKey, identity)
+}
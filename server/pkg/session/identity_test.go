@@ -0,0 +1,72 @@
+package session
+
+import (
+	"errors"
+	"testing"
+)
+
+func bindTwice(t *testing.T, policy LoginPolicy) (*Manager, *Session, *Session, []*Session, error) {
+	m := NewManager(WithLoginPolicy(policy))
+	t.Cleanup(m.Stop)
+	old, cur := m.BindSession(newTestConn()), m.BindSession(newTestConn())
+	kicked, err := m.BindIdentity(old, "u1")
+	if err != nil || len(kicked) != 0 {
+		t.Fatalf("first bind kicked %v err %v", kicked, err)
+	}
+	// 同一个会话重复绑定不会顶掉自己
+	kicked, err = m.BindIdentity(old, "u1")
+	if err != nil || len(kicked) != 0 {
+		t.Fatalf("rebind kicked %v err %v", kicked, err)
+	}
+	kicked, err = m.BindIdentity(cur, "u1")
+	return m, old, cur, kicked, err
+}
+
+func TestLoginKickOld(t *testing.T) {
+	m, old, cur, kicked, err := bindTwice(t, LoginKickOld)
+	if err != nil || len(kicked) != 1 || kicked[0] != old {
+		t.Fatalf("kicked %v err %v", kicked, err)
+	}
+	// 旧会话立即移出身份索引，由调用方负责关闭
+	if found := m.FindByIdentity("u1"); len(found) != 1 || found[0] != cur {
+		t.Fatalf("sessions after kick %v", found)
+	}
+	if _, ok := m.Identity(old); ok {
+		t.Fatal("kicked session should lose its identity")
+	}
+	if _, ok := m.GetSession(old.GetConnId()); !ok {
+		t.Fatal("kicked session should stay until the caller removes it")
+	}
+	m.RemoveSession(old.GetConnId())
+	if found := m.FindByIdentity("u1"); len(found) != 1 || found[0] != cur {
+		t.Fatalf("removing kicked session changed index %v", found)
+	}
+}
+
+func TestLoginRejectNew(t *testing.T) {
+	m, old, cur, kicked, err := bindTwice(t, LoginRejectNew)
+	if !errors.Is(err, DuplicateLoginErr) || kicked != nil {
+		t.Fatalf("kicked %v err %v", kicked, err)
+	}
+	if found := m.FindByIdentity("u1"); len(found) != 1 || found[0] != old {
+		t.Fatalf("sessions after reject %v", found)
+	}
+	if _, ok := m.Identity(cur); ok {
+		t.Fatal("rejected session should not be bound")
+	}
+	// 旧会话结束后可以重新登录
+	m.RemoveSession(old.GetConnId())
+	if _, err = m.BindIdentity(cur, "u1"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoginAllowMulti(t *testing.T) {
+	m, _, _, kicked, err := bindTwice(t, LoginAllowMulti)
+	if err != nil || len(kicked) != 0 {
+		t.Fatalf("kicked %v err %v", kicked, err)
+	}
+	if found := m.FindByIdentity("u1"); len(found) != 2 {
+		t.Fatalf("sessions %v", found)
+	}
+}
@@ -0,0 +1,92 @@
+package session
+
+import (
+	"reflect"
+	"sync"
+)
+
+// attrIndex 属性值到会话的反向索引，值必须可比较，不可比较的值只保存不索引
+type attrIndex struct {
+	mu     sync.Mutex
+	values map[any]map[uint32]*Session
+}
+
+func newAttrIndex() *attrIndex {
+	return &attrIndex{
+		values: map[any]map[uint32]*Session{},
+	}
+}
+
+// indexable 切片、map以及包含它们的结构体不能作为map的key
+func indexable(value any) bool {
+	return value == nil || reflect.ValueOf(value).Comparable()
+}
+
+func (i *attrIndex) set(s *Session, key string, value any) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	old, loaded := s.ctx.Swap(key, value)
+	if loaded {
+		i.remove(old, s.GetConnId())
+	}
+	if !indexable(value) {
+		return AttrNotComparableErr
+	}
+	if s.removed.Load() {
+		return nil
+	}
+	sessions, ok := i.values[value]
+	if !ok {
+		sessions = map[uint32]*Session{}
+		i.values[value] = sessions
+	}
+	sessions[s.GetConnId()] = s
+	return nil
+}
+
+func (i *attrIndex) delete(s *Session, key string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	old, loaded := s.ctx.LoadAndDelete(key)
+	if loaded {
+		i.remove(old, s.GetConnId())
+	}
+}
+
+// drop 会话结束时移出索引
+func (i *attrIndex) drop(s *Session, key string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	v, ok := s.ctx.Load(key)
+	if ok {
+		i.remove(v, s.GetConnId())
+	}
+}
+
+func (i *attrIndex) remove(value any, connId uint32) {
+	if !indexable(value) {
+		return
+	}
+	sessions, ok := i.values[value]
+	if !ok {
+		return
+	}
+	delete(sessions, connId)
+	if len(sessions) == 0 {
+		delete(i.values, value)
+	}
+}
+
+func (i *attrIndex) find(value any) []*Session {
+	if !indexable(value) {
+		return nil
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	sessions := i.values[value]
+	result := make([]*Session, 0, len(sessions))
+	for _, s := range sessions {
+		result = append(result, s)
+	}
+	return result
+}
@@ -0,0 +1,47 @@
+package session
+
+import "fmt"
+
+// Key 带类型的会话属性键，避免到处做类型断言
+type Key[T any] struct {
+	name string
+}
+
+func NewKey[T any](name string) Key[T] {
+	return Key[T]{name: name}
+}
+
+func (k Key[T]) Name() string {
+	return k.name
+}
+
+func (k Key[T]) Get(s *Session) (T, bool) {
+	v, ok := s.Get(k.name)
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	t, ok := v.(T)
+	return t, ok
+}
+
+func (k Key[T]) MustGet(s *Session) T {
+	t, ok := k.Get(s)
+	if !ok {
+		panic(fmt.Sprintf("session %d attr %s not found", s.GetConnId(), k.name))
+	}
+	return t
+}
+
+func (k Key[T]) Set(s *Session, value T) {
+	s.Set(k.name, value)
+}
+
+func (k Key[T]) Remove(s *Session) {
+	s.Remove(k.name)
+}
+
+// FindBy 通过索引属性查找会话，key需要通过WithIndexedKeys声明
+func FindBy[T comparable](m *Manager, key Key[T], value T) []*Session {
+	return m.FindByAttr(key.name, value)
+}
@@ -2,20 +2,27 @@ package session
 
 import (
 	"context"
+	"hutool/bytex"
+	"hutool/timewheel"
 	"server/pkg/net/inet"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type Manager struct {
-	expireTime      time.Duration
-	checkInterval   time.Duration
+	expireTime time.Duration
+	wheel      *timewheel.TimingWheel
+	// 自己创建的时间轮需要在Stop时关闭
+	ownWheel        bool
 	connIdToSession sync.Map
+	count           atomic.Int64
+	indexes         map[string]*attrIndex
+	identityKey     string
+	loginPolicy     LoginPolicy
+	identityMu      sync.Mutex
 	onSessionEnd    func(session *Session)
 	onSessionBind   func(session *Session)
-	ctx             context.Context
-	cancel          context.CancelFunc
-	wg              sync.WaitGroup
 }
 
 func NewManager(opts ...Option) *Manager {
@@ -23,30 +30,55 @@ func NewManager(opts ...Option) *Manager {
 	for _, opt := range opts {
 		opt(cfg)
 	}
-	ctx, cancel := context.WithCancel(context.Background())
 	m := &Manager{
 		expireTime:      cfg.sessionExpireTime,
-		checkInterval:   cfg.sessionCheckInterval,
+		wheel:           cfg.wheel,
+		ownWheel:        cfg.wheel == nil || cfg.checkIntervalSet,
 		onSessionEnd:    cfg.onSessionEnd,
 		onSessionBind:   cfg.onSessionBind,
 		connIdToSession: sync.Map{},
-		ctx:             ctx,
-		cancel:          cancel,
-		wg:              sync.WaitGroup{},
+		indexes:         map[string]*attrIndex{},
+		identityKey:     cfg.identityKey,
+		loginPolicy:     cfg.loginPolicy,
+	}
+	if m.ownWheel {
+		// 检查间隔即过期精度
+		m.wheel = timewheel.NewTimingWheel(timewheel.WithTick(cfg.sessionCheckInterval))
+	}
+	for _, key := range cfg.indexedKeys {
+		m.indexes[key] = newAttrIndex()
+	}
+	if _, ok := m.indexes[m.identityKey]; !ok {
+		m.indexes[m.identityKey] = newAttrIndex()
 	}
-	m.wg.Add(1)
-	go m.checkAlive()
 	return m
 }
 
 func (m *Manager) BindSession(conn inet.IConn) *Session {
-	v, loaded := m.connIdToSession.LoadOrStore(conn.GetConnId(), &Session{
-		bindConn:       conn,
-		ctx:            sync.Map{},
-		lastActiveTime: time.Now(),
-		RWMutex:        sync.RWMutex{},
+	connId := conn.GetConnId()
+	session := &Session{
+		bindConn: conn,
+		ctx:      sync.Map{},
+		manager:  m,
+		RWMutex:  sync.RWMutex{},
+	}
+	session.lifeCtx, session.cancel = context.WithCancel(context.Background())
+	session.lastActiveTime.Store(time.Now().UnixNano())
+	session.expireTimer = m.wheel.AfterFunc(m.expireTime, func() {
+		// 关闭连接可能阻塞在刷写上，不占用时间轮协程
+		go m.RemoveSession(connId)
 	})
-	if !loaded {
+	v, loaded := m.connIdToSession.LoadOrStore(connId, session)
+	if loaded {
+		session.expireTimer.Stop()
+		session.cancel()
+	} else {
+		m.count.Add(1)
+		if attrConn, ok := conn.(inet.IAttrConn); ok {
+			for key, value := range attrConn.Attrs() {
+				v.(*Session).Set(key, value)
+			}
+		}
 		if m.onSessionBind != nil {
 			m.onSessionBind(v.(*Session))
 		}
@@ -65,7 +97,14 @@ func (m *Manager) GetSession(connId uint32) (*Session, bool) {
 func (m *Manager) RemoveSession(connId uint32) {
 	v, ok := m.connIdToSession.LoadAndDelete(connId)
 	if ok {
+		m.count.Add(-1)
 		session := v.(*Session)
+		session.removed.Store(true)
+		session.expireTimer.Stop()
+		session.cancel()
+		for key, idx := range m.indexes {
+			idx.drop(session, key)
+		}
 		if m.onSessionEnd != nil {
 			m.onSessionEnd(session)
 		}
@@ -73,42 +112,68 @@ func (m *Manager) RemoveSession(connId uint32) {
 	}
 }
 
+// FindByAttr 按索引属性查找会话，未声明索引的属性返回空
+func (m *Manager) FindByAttr(key string, value any) []*Session {
+	idx, ok := m.indexOf(key)
+	if !ok {
+		return nil
+	}
+	return idx.find(value)
+}
+
+func (m *Manager) Range(f func(session *Session) bool) {
+	m.connIdToSession.Range(func(k, v any) bool {
+		return f(v.(*Session))
+	})
+}
+
+func (m *Manager) Count() int {
+	return int(m.count.Load())
+}
+
+func (m *Manager) indexOf(key string) (*attrIndex, bool) {
+	if m == nil {
+		return nil, false
+	}
+	idx, ok := m.indexes[key]
+	return idx, ok
+}
+
 func (m *Manager) Stop() {
-	m.cancel()
-	m.wg.Wait()
+	if m.ownWheel {
+		m.wheel.Stop()
+	}
 }
 
 func (m *Manager) KeepAlive(connId uint32) {
 	session, ok := m.GetSession(connId)
 	if ok {
-		session.keepAlive()
+		session.keepAlive(m.expireTime)
 	}
 }
 
-func (m *Manager) checkAlive() {
-	defer m.wg.Done()
-	tk := time.NewTicker(m.checkInterval)
-	defer tk.Stop()
-	for {
-		select {
-		case <-m.ctx.Done():
-			return
-		case <-tk.C:
-			m.connIdToSession.Range(func(k, v any) bool {
-				session, _ := v.(*Session)
-				if session.Expired(m.expireTime) {
-					m.RemoveSession(session.bindConn.GetConnId())
-				}
-				return true
-			})
-		}
+func (m *Manager) Push(connId uint32, data []byte) error {
+	session, ok := m.GetSession(connId)
+	if ok {
+		return session.bindConn.Write(data)
 	}
+	return NotFoundErr
 }
 
-func (m *Manager) Push(connId uint32, data []byte) error {
+// PushBuffer 缓冲的引用转交给连接，会话不存在时直接释放
+func (m *Manager) PushBuffer(connId uint32, buf *bytex.Buffer) error {
 	session, ok := m.GetSession(connId)
 	if ok {
-		return session.bindConn.Write(data)
+		return session.bindConn.WriteBuffer(buf)
+	}
+	buf.Release()
+	return NotFoundErr
+}
+
+func (m *Manager) PushLowPriority(connId uint32, data []byte) error {
+	session, ok := m.GetSession(connId)
+	if ok {
+		return session.bindConn.WriteLowPriority(data)
 	}
 	return NotFoundErr
 }
@@ -0,0 +1,89 @@
+package session
+
+import (
+	"hutool/timewheel"
+	"testing"
+	"time"
+)
+
+// waitEnd 等待会话结束回调，超时返回false
+func waitEnd(ch chan *Session, timeout time.Duration) (*Session, bool) {
+	select {
+	case s := <-ch:
+		return s, true
+	case <-time.After(timeout):
+		return nil, false
+	}
+}
+
+func TestSessionExpire(t *testing.T) {
+	ended := make(chan *Session, 2)
+	m := NewManager(WithSessionExpireTime(50*time.Millisecond), WithSessionCheckInterval(5*time.Millisecond),
+		WithOnSessionEnd(func(s *Session) { ended <- s }))
+	defer m.Stop()
+	idleConn, activeConn := newTestConn(), newTestConn()
+	idle, active := m.BindSession(idleConn), m.BindSession(activeConn)
+
+	// 活跃的会话持续续期
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(10 * time.Millisecond):
+				m.KeepAlive(active.GetConnId())
+			}
+		}
+	}()
+
+	s, ok := waitEnd(ended, time.Second)
+	if !ok || s != idle || !idleConn.closed.Load() {
+		t.Fatalf("idle session not expired %v", s)
+	}
+	if _, ok = m.GetSession(idle.GetConnId()); ok || m.Count() != 1 {
+		t.Fatalf("expired session still registered, count %d", m.Count())
+	}
+	if s, ok = waitEnd(ended, 150*time.Millisecond); ok {
+		t.Fatalf("active session expired %v", s)
+	}
+	if activeConn.closed.Load() {
+		t.Fatal("active conn closed")
+	}
+}
+
+func TestSessionCheckIntervalWithWheel(t *testing.T) {
+	// 精度很粗的外部时间轮，用它驱动时测试期间不会过期
+	wheel := timewheel.NewTimingWheel(timewheel.WithTick(time.Hour))
+	defer wheel.Stop()
+
+	ended := make(chan *Session, 1)
+	m := NewManager(WithTimingWheel(wheel), WithSessionExpireTime(20*time.Millisecond),
+		WithSessionCheckInterval(5*time.Millisecond), WithOnSessionEnd(func(s *Session) { ended <- s }))
+	m.BindSession(newTestConn())
+	if _, ok := waitEnd(ended, time.Second); !ok {
+		t.Fatal("explicit check interval should use its own wheel")
+	}
+	m.Stop()
+
+	shared := NewManager(WithTimingWheel(wheel), WithSessionExpireTime(20*time.Millisecond),
+		WithOnSessionEnd(func(s *Session) { ended <- s }))
+	shared.BindSession(newTestConn())
+	if _, ok := waitEnd(ended, 100*time.Millisecond); ok {
+		t.Fatal("session should be driven by the injected wheel")
+	}
+	shared.Stop()
+
+	// 外部时间轮不归管理器关闭
+	fast := timewheel.NewTimingWheel(timewheel.WithTick(time.Millisecond))
+	defer fast.Stop()
+	NewManager(WithTimingWheel(fast)).Stop()
+	fired := make(chan struct{})
+	fast.AfterFunc(time.Millisecond, func() { close(fired) })
+	select {
+	case <-fired:
+	case <-time.After(time.Second):
+		t.Fatal("injected wheel stopped by manager")
+	}
+}
@@ -1,14 +1,21 @@
 package session
 
 import (
+	"hutool/timewheel"
 	"time"
 )
 
 type Config struct {
 	sessionExpireTime    time.Duration
 	sessionCheckInterval time.Duration
-	onSessionEnd         func(session *Session)
-	onSessionBind        func(session *Session)
+	// 显式设置了检查间隔时使用自己的时间轮
+	checkIntervalSet bool
+	onSessionEnd     func(session *Session)
+	onSessionBind    func(session *Session)
+	indexedKeys      []string
+	identityKey      string
+	loginPolicy      LoginPolicy
+	wheel            *timewheel.TimingWheel
 }
 
 type Option func(*Config)
@@ -19,15 +26,26 @@ func WithSessionExpireTime(expireTime time.Duration) Option {
 	}
 }
 
+// WithSessionCheckInterval 过期检查的精度，设置后不再使用WithTimingWheel传入的时间轮
 func WithSessionCheckInterval(interval time.Duration) Option {
 	return func(c *Config) {
 		c.sessionCheckInterval = interval
+		c.checkIntervalSet = true
 	}
 }
 
+// WithOnSessionEnd 多次设置时按设置顺序依次回调
 func WithOnSessionEnd(onSessionEnd func(session *Session)) Option {
 	return func(c *Config) {
-		c.onSessionEnd = onSessionEnd
+		prev := c.onSessionEnd
+		if prev == nil {
+			c.onSessionEnd = onSessionEnd
+			return
+		}
+		c.onSessionEnd = func(session *Session) {
+			prev(session)
+			onSessionEnd(session)
+		}
 	}
 }
 
@@ -37,11 +55,40 @@ func WithOnSessionBind(onSessionBind func(session *Session)) Option {
 	}
 }
 
+// WithIndexedKeys 为属性建立反向索引，用于Manager.FindByAttr
+func WithIndexedKeys(keys ...string) Option {
+	return func(c *Config) {
+		c.indexedKeys = append(c.indexedKeys, keys...)
+	}
+}
+
+// WithIdentityKey 用户身份存放的属性key，自动建立索引
+func WithIdentityKey(key string) Option {
+	return func(c *Config) {
+		c.identityKey = key
+	}
+}
+
+func WithLoginPolicy(policy LoginPolicy) Option {
+	return func(c *Config) {
+		c.loginPolicy = policy
+	}
+}
+
+// WithTimingWheel 使用外部时间轮驱动会话过期，不设置或显式设置了检查间隔时按检查间隔创建
+func WithTimingWheel(wheel *timewheel.TimingWheel) Option {
+	return func(c *Config) {
+		c.wheel = wheel
+	}
+}
+
 func DefaultConfig() *Config {
 	return &Config{
 		sessionExpireTime:    10 * time.Second,
 		sessionCheckInterval: 5 * time.Second,
 		onSessionEnd:         nil,
 		onSessionBind:        nil,
+		identityKey:          "identity",
+		loginPolicy:          LoginKickOld,
 	}
 }
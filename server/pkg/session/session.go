@@ -1,15 +1,26 @@
 package session
 
 import (
+	"context"
+	"hutool/logx"
+	"hutool/timewheel"
+	"server/pkg/net/batch"
 	"server/pkg/net/inet"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type Session struct {
 	bindConn       inet.IConn
 	ctx            sync.Map
-	lastActiveTime time.Time
+	lastActiveTime atomic.Int64
+	expireTimer    *timewheel.Timer
+	manager        *Manager
+	removed        atomic.Bool
+	// lifeCtx 会话移除时取消，请求上下文从它派生
+	lifeCtx context.Context
+	cancel  context.CancelFunc
 	sync.RWMutex
 }
 
@@ -18,26 +29,80 @@ func (s *Session) Get(key string) (any, bool) {
 	return v, ok
 }
 
+// Set 索引属性的值不可比较时只保存，不能通过FindByAttr查到
 func (s *Session) Set(key string, value any) {
+	if idx, ok := s.manager.indexOf(key); ok {
+		err := idx.set(s, key, value)
+		if err != nil {
+			logx.Errorf("session %d attr %s %T err %+v", s.GetConnId(), key, value, err)
+		}
+		return
+	}
 	s.ctx.Store(key, value)
 }
 
 func (s *Session) Remove(key string) {
+	if idx, ok := s.manager.indexOf(key); ok {
+		idx.delete(s, key)
+		return
+	}
 	s.ctx.Delete(key)
 }
 
+// Context 连接断开或会话移除后取消
+func (s *Session) Context() context.Context {
+	return s.lifeCtx
+}
+
 func (s *Session) GetConnId() uint32 {
 	return s.bindConn.GetConnId()
 }
 
+func (s *Session) RemoteAddr() string {
+	return s.bindConn.RemoteAddr()
+}
+
+func (s *Session) LastActiveTime() time.Time {
+	return time.Unix(0, s.lastActiveTime.Load())
+}
+
+// Attrs 属性的快照，修改不影响会话
+func (s *Session) Attrs() map[string]any {
+	attrs := map[string]any{}
+	s.ctx.Range(func(k, v any) bool {
+		attrs[k.(string)] = v
+		return true
+	})
+	return attrs
+}
+
+// WriterStats 连接不支持统计时返回false
+func (s *Session) WriterStats() (batch.Stats, bool) {
+	conn, ok := s.bindConn.(inet.IStatsConn)
+	if !ok {
+		return batch.Stats{}, false
+	}
+	return conn.WriterStats(), true
+}
+
+// NativeCompressed 连接已经在传输层压缩，不需要再额外压缩
+func (s *Session) NativeCompressed() bool {
+	conn, ok := s.bindConn.(inet.ICompressConn)
+	return ok && conn.Compressed()
+}
+
+// TextMode 浏览器等文本客户端，消息体只能是json
+func (s *Session) TextMode() bool {
+	conn, ok := s.bindConn.(inet.ITextConn)
+	return ok && conn.TextMode()
+}
+
 func (s *Session) Expired(expireDuration time.Duration) bool {
-	s.RLock()
-	defer s.RUnlock()
-	return time.Now().Sub(s.lastActiveTime) > expireDuration
+	return time.Since(time.Unix(0, s.lastActiveTime.Load())) > expireDuration
 }
 
-func (s *Session) keepAlive() {
-	s.Lock()
-	defer s.Unlock()
-	s.lastActiveTime = time.Now()
+// keepAlive 心跳只重置过期定时，不需要加锁扫描
+func (s *Session) keepAlive(expireDuration time.Duration) {
+	s.lastActiveTime.Store(time.Now().UnixNano())
+	s.expireTimer.Reset(expireDuration)
 }
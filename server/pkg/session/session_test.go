@@ -0,0 +1,159 @@
+package session
+
+import (
+	"errors"
+	"hutool/bytex"
+	"server/pkg/net/conn_id"
+	"sync/atomic"
+	"testing"
+)
+
+// testConn 只记录是否被关闭
+type testConn struct {
+	connId uint32
+	closed atomic.Bool
+}
+
+func newTestConn() *testConn {
+	return &testConn{connId: conn_id.NextId()}
+}
+
+func (c *testConn) GetConnId() uint32 {
+	return c.connId
+}
+
+func (c *testConn) RemoteAddr() string {
+	return "test"
+}
+
+func (c *testConn) Write(data []byte) error {
+	return nil
+}
+
+func (c *testConn) WriteLowPriority(data []byte) error {
+	return nil
+}
+
+func (c *testConn) WriteBuffer(buf *bytex.Buffer) error {
+	buf.Release()
+	return nil
+}
+
+func (c *testConn) Close() {
+	c.closed.Store(true)
+}
+
+func TestKey(t *testing.T) {
+	m := NewManager()
+	defer m.Stop()
+	s := m.BindSession(newTestConn())
+	level := NewKey[int]("level")
+	if _, ok := level.Get(s); ok {
+		t.Fatal("unset key found")
+	}
+	level.Set(s, 3)
+	if v, ok := level.Get(s); !ok || v != 3 || level.MustGet(s) != 3 {
+		t.Fatalf("unexpected level %v %v", v, ok)
+	}
+	// 类型不一致时当作不存在
+	s.Set(level.Name(), "3")
+	if _, ok := level.Get(s); ok {
+		t.Fatal("mismatched type should not be found")
+	}
+	level.Remove(s)
+	defer func() {
+		if recover() == nil {
+			t.Fatal("MustGet on missing key should panic")
+		}
+	}()
+	level.MustGet(s)
+}
+
+func TestIndexedKey(t *testing.T) {
+	m := NewManager(WithIndexedKeys("room"))
+	defer m.Stop()
+	room := NewKey[int]("room")
+	c1 := newTestConn()
+	s1, s2 := m.BindSession(c1), m.BindSession(newTestConn())
+	room.Set(s1, 1)
+	room.Set(s2, 1)
+	if found := FindBy(m, room, 1); len(found) != 2 {
+		t.Fatalf("room 1 sessions %d", len(found))
+	}
+
+	// 替换后旧值的索引被移除
+	room.Set(s1, 2)
+	if found := FindBy(m, room, 1); len(found) != 1 || found[0] != s2 {
+		t.Fatalf("room 1 after replace %v", found)
+	}
+	if found := FindBy(m, room, 2); len(found) != 1 || found[0] != s1 {
+		t.Fatalf("room 2 after replace %v", found)
+	}
+	room.Remove(s2)
+	if found := FindBy(m, room, 1); len(found) != 0 {
+		t.Fatalf("room 1 after remove %v", found)
+	}
+
+	// 会话结束时移出索引，之后的设置不再进入索引
+	m.RemoveSession(s1.GetConnId())
+	if found := FindBy(m, room, 2); len(found) != 0 || !c1.closed.Load() {
+		t.Fatalf("room 2 after session end %v closed %v", found, c1.closed.Load())
+	}
+	room.Set(s1, 3)
+	if found := FindBy(m, room, 3); len(found) != 0 {
+		t.Fatalf("removed session indexed %v", found)
+	}
+	// 未声明索引的属性查不到
+	if found := m.FindByAttr("other", 1); found != nil {
+		t.Fatalf("unindexed attr found %v", found)
+	}
+}
+
+func TestIndexedKeyNotComparable(t *testing.T) {
+	m := NewManager(WithIndexedKeys("tags"))
+	defer m.Stop()
+	s := m.BindSession(newTestConn())
+	s.Set("tags", []string{"a"})
+	if v, ok := s.Get("tags"); !ok || len(v.([]string)) != 1 {
+		t.Fatalf("not comparable value should still be stored %v", v)
+	}
+	if found := m.FindByAttr("tags", []string{"a"}); len(found) != 0 {
+		t.Fatalf("not comparable value found %v", found)
+	}
+	s.Set("tags", "a")
+	if found := m.FindByAttr("tags", "a"); len(found) != 1 {
+		t.Fatalf("comparable value after replace %v", found)
+	}
+	s.Remove("tags")
+
+	type identity struct {
+		roles []string
+	}
+	_, err := m.BindIdentity(s, identity{roles: []string{"admin"}})
+	if !errors.Is(err, AttrNotComparableErr) {
+		t.Fatalf("bind not comparable identity err %v", err)
+	}
+}
+
+func TestFindByIdentity(t *testing.T) {
+	m := NewManager()
+	defer m.Stop()
+	s := m.BindSession(newTestConn())
+	if _, err := m.BindIdentity(s, uint32(7)); err != nil {
+		t.Fatal(err)
+	}
+	if found := m.FindByIdentity(uint32(7)); len(found) != 1 || found[0] != s {
+		t.Fatalf("unexpected sessions %v", found)
+	}
+	if identity, ok := m.Identity(s); !ok || identity != uint32(7) {
+		t.Fatalf("unexpected identity %v", identity)
+	}
+	// 身份按值和类型匹配
+	if found := m.FindByIdentity(7); len(found) != 0 {
+		t.Fatalf("int identity should not match uint32 %v", found)
+	}
+	m.RemoveSession(s.GetConnId())
+	if found := m.FindByIdentity(uint32(7)); len(found) != 0 {
+		t.Fatalf("identity after session end %v", found)
+	}
+}
@@ -0,0 +1,5 @@
+package trace
+
+import "errors"
+
+var TraceparentErr = errors.New("invalid traceparent")
@@ -0,0 +1,56 @@
+package trace
+
+import (
+	"hutool/logx"
+	"sync"
+)
+
+// Exporter 在结束span的协程中同步调用，耗时的导出需要自己异步批量处理
+type Exporter interface {
+	Export(span *Span)
+}
+
+type NopExporter struct {
+}
+
+func (n NopExporter) Export(span *Span) {
+}
+
+// LogExporter 调试时输出到日志
+type LogExporter struct {
+}
+
+func (l LogExporter) Export(span *Span) {
+	logx.Infof("span %s trace %s span %s parent %s kind %d cost %v status %d %s attrs %v",
+		span.Name, span.Context.TraceId, span.Context.SpanId, span.Parent, span.Kind, span.Duration(), span.Status, span.StatusMessage, span.Attributes)
+}
+
+// MemoryExporter 保存所有导出的span，用于测试
+type MemoryExporter struct {
+	mu    sync.Mutex
+	spans []*Span
+}
+
+func NewMemoryExporter() *MemoryExporter {
+	return &MemoryExporter{}
+}
+
+func (m *MemoryExporter) Export(span *Span) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.spans = append(m.spans, span)
+}
+
+func (m *MemoryExporter) Spans() []*Span {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	spans := make([]*Span, len(m.spans))
+	copy(spans, m.spans)
+	return spans
+}
+
+func (m *MemoryExporter) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.spans = nil
+}
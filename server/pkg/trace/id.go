@@ -0,0 +1,50 @@
+package trace
+
+import (
+	"encoding/hex"
+	"math/rand/v2"
+)
+
+// TraceId 和SpanId与W3C Trace Context及OpenTelemetry的格式一致
+type TraceId [16]byte
+
+type SpanId [8]byte
+
+func (t TraceId) IsValid() bool {
+	return t != TraceId{}
+}
+
+func (t TraceId) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+func (s SpanId) IsValid() bool {
+	return s != SpanId{}
+}
+
+func (s SpanId) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+func newTraceId() TraceId {
+	var t TraceId
+	for !t.IsValid() {
+		putUint64(t[:8], rand.Uint64())
+		putUint64(t[8:], rand.Uint64())
+	}
+	return t
+}
+
+func newSpanId() SpanId {
+	var s SpanId
+	for !s.IsValid() {
+		putUint64(s[:], rand.Uint64())
+	}
+	return s
+}
+
+func putUint64(b []byte, v uint64) {
+	for i := range 8 {
+		b[i] = byte(v >> (56 - 8*i))
+	}
+}
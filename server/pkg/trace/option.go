@@ -0,0 +1,28 @@
+package trace
+
+type Config struct {
+	exporter    Exporter
+	sampleRatio float64
+}
+
+type Option func(*Config)
+
+func WithExporter(exporter Exporter) Option {
+	return func(c *Config) {
+		c.exporter = exporter
+	}
+}
+
+// WithSampleRatio 根span的采样比例，子span跟随上游的采样结果
+func WithSampleRatio(ratio float64) Option {
+	return func(c *Config) {
+		c.sampleRatio = ratio
+	}
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		exporter:    NopExporter{},
+		sampleRatio: 1,
+	}
+}
@@ -0,0 +1,97 @@
+package trace
+
+import (
+	"context"
+	"encoding/hex"
+)
+
+// TraceparentKey W3C Trace Context的传播头，放在请求元数据中
+const TraceparentKey = "traceparent"
+
+const (
+	traceparentVersion = "00"
+	traceparentLen     = 55
+	flagSampled        = 0x01
+)
+
+// SpanContext 跨进程传播的部分
+type SpanContext struct {
+	TraceId TraceId
+	SpanId  SpanId
+	Sampled bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceId.IsValid() && sc.SpanId.IsValid()
+}
+
+// Traceparent 格式 00-{traceId}-{spanId}-{flags}
+func (sc SpanContext) Traceparent() string {
+	var flags byte
+	if sc.Sampled {
+		flags = flagSampled
+	}
+	b := make([]byte, 0, traceparentLen)
+	b = append(b, traceparentVersion...)
+	b = append(b, '-')
+	b = hex.AppendEncode(b, sc.TraceId[:])
+	b = append(b, '-')
+	b = hex.AppendEncode(b, sc.SpanId[:])
+	b = append(b, '-')
+	b = hex.AppendEncode(b, []byte{flags})
+	return string(b)
+}
+
+// ParseTraceparent 只接受00版本，全0的id视为无效
+func ParseTraceparent(s string) (SpanContext, error) {
+	var sc SpanContext
+	if len(s) != traceparentLen || s[:2] != traceparentVersion || s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return sc, TraceparentErr
+	}
+	var flags [1]byte
+	if _, err := hex.Decode(sc.TraceId[:], []byte(s[3:35])); err != nil {
+		return sc, TraceparentErr
+	}
+	if _, err := hex.Decode(sc.SpanId[:], []byte(s[36:52])); err != nil {
+		return sc, TraceparentErr
+	}
+	if _, err := hex.Decode(flags[:], []byte(s[53:55])); err != nil {
+		return sc, TraceparentErr
+	}
+	if !sc.IsValid() {
+		return sc, TraceparentErr
+	}
+	sc.Sampled = flags[0]&flagSampled != 0
+	return sc, nil
+}
+
+// Inject 把上下文中的span写入载体，载体可以直接使用codec.Metadata
+func Inject(ctx context.Context, carrier map[string]string) {
+	span := SpanFromContext(ctx)
+	if span == nil || carrier == nil {
+		return
+	}
+	carrier[TraceparentKey] = span.SpanContext().Traceparent()
+}
+
+// Extract 从载体中解析上游的span，没有或格式错误时返回false
+func Extract(carrier map[string]string) (SpanContext, bool) {
+	v, ok := carrier[TraceparentKey]
+	if !ok {
+		return SpanContext{}, false
+	}
+	sc, err := ParseTraceparent(v)
+	return sc, err == nil
+}
+
+type remoteKey struct{}
+
+// ContextWithRemote 记录上游进程的span，之后开始的span作为它的子span
+func ContextWithRemote(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+func remoteFromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(remoteKey{}).(SpanContext)
+	return sc, ok
+}
@@ -0,0 +1,123 @@
+package trace
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type SpanKind uint8
+
+const (
+	KindInternal SpanKind = iota
+	KindServer
+	KindClient
+)
+
+type StatusCode uint8
+
+const (
+	StatusUnset StatusCode = iota
+	StatusOk
+	StatusError
+)
+
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// Span 结束后交给导出器，nil的Span所有方法都是空操作，未开启追踪时不用判断
+type Span struct {
+	tracer *Tracer
+	mu     sync.Mutex
+	ended  bool
+
+	Name          string
+	Kind          SpanKind
+	Context       SpanContext
+	Parent        SpanId
+	RemoteParent  bool
+	StartTime     time.Time
+	EndTime       time.Time
+	Attributes    []Attribute
+	Status        StatusCode
+	StatusMessage string
+}
+
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.Context
+}
+
+func (s *Span) SetAttr(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.Attributes = append(s.Attributes, Attribute{Key: key, Value: value})
+	}
+}
+
+// SetError 记录错误并把状态设为Error，err为nil时忽略
+func (s *Span) SetError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ended {
+		s.Status = StatusError
+		s.StatusMessage = err.Error()
+	}
+}
+
+// End 只有第一次调用有效
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.EndTime = time.Now()
+	if s.Status == StatusUnset {
+		s.Status = StatusOk
+	}
+	s.mu.Unlock()
+	if s.Context.Sampled {
+		s.tracer.export(s)
+	}
+}
+
+func (s *Span) Duration() time.Duration {
+	return s.EndTime.Sub(s.StartTime)
+}
+
+type spanKey struct{}
+
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext 没有span时返回nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// StartSpan 用上下文中span所属的tracer开始子span，没有span时不追踪，用于内部调用
+func StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	return parent.tracer.Start(ctx, name, KindInternal)
+}
@@ -0,0 +1,87 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestTraceparentRoundTrip(t *testing.T) {
+	sc := SpanContext{TraceId: newTraceId(), SpanId: newSpanId(), Sampled: true}
+	parsed, err := ParseTraceparent(sc.Traceparent())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed != sc {
+		t.Fatalf("round trip mismatch %+v %+v", parsed, sc)
+	}
+	for _, bad := range []string{
+		"",
+		"01-" + sc.TraceId.String() + "-" + sc.SpanId.String() + "-01",
+		"00-00000000000000000000000000000000-" + sc.SpanId.String() + "-01",
+		"00-" + sc.TraceId.String() + "-zz" + sc.SpanId.String()[2:] + "-01",
+	} {
+		if _, err := ParseTraceparent(bad); err == nil {
+			t.Fatalf("accepted bad traceparent %q", bad)
+		}
+	}
+}
+
+func TestSpanPropagation(t *testing.T) {
+	exporter := NewMemoryExporter()
+	client := NewTracer(WithExporter(exporter))
+	server := NewTracer(WithExporter(exporter))
+
+	ctx, clientSpan := client.Start(context.Background(), "client.ask", KindClient)
+	carrier := map[string]string{}
+	Inject(ctx, carrier)
+
+	remote, ok := Extract(carrier)
+	if !ok {
+		t.Fatal("traceparent not injected")
+	}
+	serverCtx, serverSpan := server.Start(ContextWithRemote(context.Background(), remote), "server.ask", KindServer)
+	_, inner := StartSpan(serverCtx, "db.query")
+	inner.SetError(errors.New("boom"))
+	inner.End()
+	serverSpan.End()
+	clientSpan.End()
+	clientSpan.End()
+
+	spans := exporter.Spans()
+	if len(spans) != 3 {
+		t.Fatalf("expect 3 spans got %d", len(spans))
+	}
+	if serverSpan.Context.TraceId != clientSpan.Context.TraceId || serverSpan.Parent != clientSpan.Context.SpanId || !serverSpan.RemoteParent {
+		t.Fatalf("server span not linked to client span")
+	}
+	if inner.Parent != serverSpan.Context.SpanId || inner.Status != StatusError {
+		t.Fatalf("inner span %+v", inner)
+	}
+}
+
+func TestNilSpan(t *testing.T) {
+	var tracer *Tracer
+	ctx, span := tracer.Start(context.Background(), "noop", KindInternal)
+	span.SetAttr("k", 1)
+	span.SetError(errors.New("ignored"))
+	span.End()
+	if _, child := StartSpan(ctx, "child"); child != nil {
+		t.Fatal("child span without tracer")
+	}
+}
+
+func TestSampling(t *testing.T) {
+	exporter := NewMemoryExporter()
+	tracer := NewTracer(WithExporter(exporter), WithSampleRatio(0))
+	ctx, root := tracer.Start(context.Background(), "root", KindServer)
+	_, child := StartSpan(ctx, "child")
+	child.End()
+	root.End()
+	if len(exporter.Spans()) != 0 {
+		t.Fatal("unsampled spans exported")
+	}
+	if !root.SpanContext().IsValid() {
+		t.Fatal("unsampled span should still propagate ids")
+	}
+}
@@ -0,0 +1,62 @@
+package trace
+
+import (
+	"context"
+	"hutool/logx"
+	"math/rand/v2"
+	"time"
+)
+
+type Tracer struct {
+	exporter    Exporter
+	sampleRatio float64
+}
+
+func NewTracer(opts ...Option) *Tracer {
+	cfg := DefaultConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return &Tracer{
+		exporter:    cfg.exporter,
+		sampleRatio: cfg.sampleRatio,
+	}
+}
+
+// Start 父span优先取本进程上下文中的span，其次是上游传过来的span，都没有时开始新的trace
+// 未采样的span仍然会生成id并向下游传播，只是不导出
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	span := &Span{
+		tracer:    t,
+		Name:      name,
+		Kind:      kind,
+		StartTime: time.Now(),
+	}
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.Context.TraceId = parent.Context.TraceId
+		span.Context.Sampled = parent.Context.Sampled
+		span.Parent = parent.Context.SpanId
+	} else if remote, ok := remoteFromContext(ctx); ok && remote.IsValid() {
+		span.Context.TraceId = remote.TraceId
+		span.Context.Sampled = remote.Sampled
+		span.Parent = remote.SpanId
+		span.RemoteParent = true
+	} else {
+		span.Context.TraceId = newTraceId()
+		span.Context.Sampled = t.sampleRatio >= 1 || rand.Float64() < t.sampleRatio
+	}
+	span.Context.SpanId = newSpanId()
+	return ContextWithSpan(ctx, span), span
+}
+
+func (t *Tracer) export(span *Span) {
+	defer func() {
+		if r := recover(); r != nil {
+			logx.Errorf("trace export panic %+v", r)
+		}
+	}()
+	t.exporter.Export(span)
+}
@@ -4,3 +4,31 @@ type IZip interface {
 	Zip(data []byte) ([]byte, error)
 	Unzip(data []byte) ([]byte, error)
 }
+
+// 握手协商使用的压缩算法id
+const (
+	IdNone   uint8 = 0
+	IdGzip   uint8 = 1
+	IdCustom uint8 = 255
+)
+
+// IdOf 自定义压缩返回IdCustom，不参与协商
+func IdOf(zip IZip) uint8 {
+	switch zip.(type) {
+	case nil, None, *None:
+		return IdNone
+	case GZIP, *GZIP:
+		return IdGzip
+	default:
+		return IdCustom
+	}
+}
+
+// MaskOf 客户端握手时声明支持的压缩算法
+func MaskOf(zip IZip) uint8 {
+	id := IdOf(zip)
+	if id == IdNone || id == IdCustom {
+		return 0
+	}
+	return 1 << id
+}